@@ -5,6 +5,7 @@ import (
 	"flag"
 
 	"github.com/spf13/cobra"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
@@ -52,13 +53,18 @@ func run(ctx context.Context) error {
 	if err != nil {
 		klog.Fatalf("Failed to create client: %v", err)
 	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		klog.Fatalf("Failed to create dynamic client: %v", err)
+	}
 
-	openappHelper := utils.NewOpenAPPHelper(ctx, k8sClient, openappClient)
+	openappHelper := utils.NewOpenAPPHelper(ctx, k8sClient, openappClient, dynamicClient)
 	klog.Infof("Wait resource cache sync...")
 	if ok := cache.WaitForCacheSync(ctx.Done(),
 		openappHelper.ConfigMapInformer.HasSynced,
 		openappHelper.AppInstanceInformer.HasSynced,
-		openappHelper.PublicServiceInstanceInformer.HasSynced); !ok {
+		openappHelper.PublicServiceInstanceInformer.HasSynced,
+		openappHelper.RegistryInformer.HasSynced); !ok {
 		klog.Fatal("Failed to wait for cache sync")
 	}
 
@@ -5,6 +5,7 @@ import (
 	"flag"
 
 	"github.com/spf13/cobra"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/openapp-dev/openapp/pkg/controller/appinstance"
 	"github.com/openapp-dev/openapp/pkg/controller/apptemplate"
+	"github.com/openapp-dev/openapp/pkg/controller/derivedresource"
 	"github.com/openapp-dev/openapp/pkg/controller/publicserviceinstance"
 	"github.com/openapp-dev/openapp/pkg/controller/publicservicetemplate"
 	"github.com/openapp-dev/openapp/pkg/controller/registry"
@@ -52,6 +54,7 @@ var controllerNewFuncList = []types.NewControllerFunc{
 	publicserviceinstance.NewPublicServiceInstanceStatusController,
 	publicserviceinstance.NewPublicServiceInstanceServiceController,
 	registry.NewRegistryController,
+	derivedresource.NewDerivedResourceGCController,
 }
 
 func run(ctx context.Context) error {
@@ -69,7 +72,11 @@ func run(ctx context.Context) error {
 	if err != nil {
 		klog.Fatalf("Failed to create client: %v", err)
 	}
-	openappHelper := utils.NewOpenAPPHelper(ctx, k8sClient, openappClient)
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		klog.Fatalf("Failed to create dynamic client: %v", err)
+	}
+	openappHelper := utils.NewOpenAPPHelper(ctx, k8sClient, openappClient, dynamicClient)
 
 	ctls := []types.ControllerInterface{}
 	for _, controllerFunc := range controllerNewFuncList {
@@ -82,7 +89,8 @@ func run(ctx context.Context) error {
 		openappHelper.AppInstanceInformer.HasSynced,
 		openappHelper.PublicServiceInstanceInformer.HasSynced,
 		openappHelper.ServiceInformer.HasSynced,
-		openappHelper.StatefulSetInformer.HasSynced); !ok {
+		openappHelper.StatefulSetInformer.HasSynced,
+		openappHelper.RegistryInformer.HasSynced); !ok {
 		klog.Fatal("Failed to wait for cache sync")
 	}
 
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
+	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
+	"github.com/openapp-dev/openapp/pkg/compose"
+	"github.com/openapp-dev/openapp/pkg/utils"
+)
+
+const defaultComposeTemplateVersion = "0.1.0"
+
+type importComposeOptions struct {
+	name        string
+	outputDir   string
+	exposeType  string
+	keepCompose bool
+}
+
+func newTemplateImportComposeCommand() *cobra.Command {
+	o := &importComposeOptions{}
+	cmd := &cobra.Command{
+		Use:   "import-compose <compose-file>",
+		Short: "Convert a docker-compose file into an app template",
+		Long: `Convert a docker-compose file into an app template directory. The services run
+as the containers of a StatefulSet, the environments are ConfigMaps, the volumes
+are PVCs and the published ports are a Service. The variables of the compose
+file are the inputs of the template.
+
+With --keep-compose the compose file is kept in the template instead of the
+manifests, and it's converted whenever the instances are rendered.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.run(cmd, args[0])
+		},
+	}
+	cmd.Flags().StringVar(&o.name, "name", "", "Name of the template, defaults to the directory name of the compose file")
+	cmd.Flags().StringVarP(&o.outputDir, "output", "o", "", "Template directory to create, defaults to the template name")
+	cmd.Flags().StringVar(&o.exposeType, "expose-type", string(commonv1alpha1.ExposeLayer7),
+		"Expose type of the template, Layer4 or Layer7")
+	cmd.Flags().BoolVar(&o.keepCompose, "keep-compose", false, "Keep the compose file in the template and convert it when rendering")
+	return cmd
+}
+
+func (o *importComposeOptions) run(cmd *cobra.Command, composeFile string) error {
+	content, err := os.ReadFile(composeFile)
+	if err != nil {
+		return err
+	}
+	project, err := compose.Load(content)
+	if err != nil {
+		return err
+	}
+	result, err := compose.Convert(project)
+	if err != nil {
+		return err
+	}
+	for _, w := range result.Warnings {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %s\n", w)
+	}
+
+	if o.name == "" {
+		absPath, err := filepath.Abs(composeFile)
+		if err != nil {
+			return err
+		}
+		o.name = strings.ToLower(path.Base(path.Dir(absPath)))
+	}
+	if errs := validation.IsDNS1123Subdomain(o.name); len(errs) != 0 {
+		return fmt.Errorf("invalid template name %q: %s, set it with --name", o.name, strings.Join(errs, ", "))
+	}
+	exposeType := commonv1alpha1.ExposeType(o.exposeType)
+	if exposeType != commonv1alpha1.ExposeLayer4 && exposeType != commonv1alpha1.ExposeLayer7 {
+		return fmt.Errorf("invalid expose type %q, must be %s or %s", o.exposeType,
+			commonv1alpha1.ExposeLayer4, commonv1alpha1.ExposeLayer7)
+	}
+	if o.outputDir == "" {
+		o.outputDir = o.name
+	}
+	if _, err := os.Stat(path.Join(o.outputDir, utils.TemplateFileName)); err == nil {
+		return fmt.Errorf("template already exists in %s", o.outputDir)
+	}
+
+	files := map[string][]byte{}
+	spec := appv1alpha1.AppTemplateSpec{
+		Title:       o.name,
+		Description: fmt.Sprintf("Imported from %s", path.Base(composeFile)),
+		Inputs:      result.Inputs,
+		InputSchema: result.InputSchema,
+		ExposeType:  exposeType,
+		Version:     defaultComposeTemplateVersion,
+	}
+	if o.keepCompose {
+		spec.Type = appv1alpha1.AppTemplateCompose
+		files[utils.DefaultComposeFileName] = content
+	} else {
+		for _, m := range result.Manifests {
+			files[path.Join(utils.TemplateManifestsDirName, m.Name)] = m.Content
+		}
+	}
+	template, err := marshalAppTemplate(o.name, spec)
+	if err != nil {
+		return err
+	}
+	files[utils.TemplateFileName] = template
+
+	for name, d := range files {
+		p := path.Join(o.outputDir, name)
+		if err := os.MkdirAll(path.Dir(p), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(p, d, 0644); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Template %s is created in %s\n", o.name, o.outputDir)
+	return nil
+}
+
+// marshalAppTemplate marshals the template without the empty metadata fields,
+// e.g. the creation timestamp.
+func marshalAppTemplate(name string, spec appv1alpha1.AppTemplateSpec) ([]byte, error) {
+	return yaml.Marshal(struct {
+		metav1.TypeMeta `json:",inline"`
+		Metadata        map[string]string           `json:"metadata"`
+		Spec            appv1alpha1.AppTemplateSpec `json:"spec"`
+	}{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: appv1alpha1.SchemeGroupVersion.String(),
+			Kind:       "AppTemplate",
+		},
+		Metadata: map[string]string{"name": name},
+		Spec:     spec,
+	})
+}
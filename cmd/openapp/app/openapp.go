@@ -0,0 +1,25 @@
+package app
+
+import (
+	"flag"
+
+	"github.com/spf13/cobra"
+	cliflag "k8s.io/component-base/cli/flag"
+	"k8s.io/klog"
+)
+
+func NewOpenAPPCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:  "openapp",
+		Long: `openapp used to develop the openapp templates without a cluster`,
+	}
+
+	fss := cliflag.NamedFlagSets{}
+	logFlagSet := fss.FlagSet("log")
+	klog.InitFlags(flag.CommandLine)
+	logFlagSet.AddGoFlagSet(flag.CommandLine)
+	cmd.PersistentFlags().AddFlagSet(logFlagSet)
+
+	cmd.AddCommand(newTemplateCommand())
+	return cmd
+}
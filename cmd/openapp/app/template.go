@@ -0,0 +1,92 @@
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openapp-dev/openapp/pkg/lint"
+)
+
+func newTemplateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "template",
+		Short: "Lint, render and import the templates of a registry",
+	}
+	cmd.AddCommand(newTemplateLintCommand(), newTemplateRenderCommand(), newTemplateImportComposeCommand())
+	return cmd
+}
+
+func newTemplateLintCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "lint <template-dir|registry-dir>...",
+		Short: "Validate the templates and render their manifests with the sample inputs",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			failed := 0
+			for _, arg := range args {
+				dirs, err := lint.FindTemplateDirs(arg)
+				if err != nil {
+					return err
+				}
+				for _, dir := range dirs {
+					problems := lint.Lint(dir)
+					for _, p := range problems {
+						fmt.Fprintln(cmd.OutOrStdout(), p)
+					}
+					if len(problems) != 0 {
+						failed++
+					}
+				}
+			}
+			if failed != 0 {
+				return fmt.Errorf("%d templates failed linting", failed)
+			}
+			return nil
+		},
+	}
+}
+
+func newTemplateRenderCommand() *cobra.Command {
+	var instanceName, inputsFile string
+	cmd := &cobra.Command{
+		Use:   "render <template-dir>",
+		Short: "Render the manifests of the template",
+		Long: `Render the manifests of the template as the instance would get, the sample
+inputs of the template are used unless an inputs file is given.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			t, problems := lint.LoadTemplate(args[0])
+			if t == nil {
+				return fmt.Errorf("failed to load template: %v", problems)
+			}
+			inputs := ""
+			if inputsFile != "" {
+				d, err := os.ReadFile(inputsFile)
+				if err != nil {
+					return err
+				}
+				inputs = string(d)
+			}
+			if instanceName == "" {
+				instanceName = t.Name
+			}
+			values, err := t.Values(instanceName, inputs)
+			if err != nil {
+				return fmt.Errorf("invalid inputs: %v", err)
+			}
+			manifests, err := t.Render(values)
+			if err != nil {
+				return err
+			}
+			for _, m := range manifests {
+				fmt.Fprintf(cmd.OutOrStdout(), "---\n# Source: %s\n%s\n", m.File, m.Content)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&instanceName, "instance-name", "", "Name of the instance, defaults to the template name")
+	cmd.Flags().StringVarP(&inputsFile, "inputs", "i", "", "YAML file of the instance inputs")
+	return cmd
+}
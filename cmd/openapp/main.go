@@ -0,0 +1,15 @@
+package main
+
+import (
+	"os"
+
+	"k8s.io/component-base/cli"
+
+	"github.com/openapp-dev/openapp/cmd/openapp/app"
+)
+
+func main() {
+	cmd := app.NewOpenAPPCommand()
+	code := cli.Run(cmd)
+	os.Exit(code)
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"context"
+	"flag"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	cliflag "k8s.io/component-base/cli/flag"
+	"k8s.io/klog"
+
+	openappclient "github.com/openapp-dev/openapp/pkg/generated/clientset/versioned"
+	"github.com/openapp-dev/openapp/pkg/utils"
+	"github.com/openapp-dev/openapp/pkg/webhook"
+)
+
+func NewWebhookCommand(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:  "openapp-webhook",
+		Long: `openapp-webhook used to validate and default the openapp resources`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := run(ctx); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	fss := cliflag.NamedFlagSets{}
+	logFlagSet := fss.FlagSet("log")
+	klog.InitFlags(flag.CommandLine)
+	logFlagSet.AddGoFlagSet(flag.CommandLine)
+	cmd.Flags().AddFlagSet(logFlagSet)
+
+	return cmd
+}
+
+func run(ctx context.Context) error {
+	version := utils.GetOpenAPPVersion()
+	klog.Infof("Start openapp-webhook, version: %s, commit: %s", version.GitVersion, version.GitCommit)
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		klog.Fatalf("Failed to get in-cluster config: %v", err)
+	}
+	k8sClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		klog.Fatalf("Failed to create k8s client: %v", err)
+	}
+	openappClient, err := openappclient.NewForConfig(config)
+	if err != nil {
+		klog.Fatalf("Failed to create client: %v", err)
+	}
+
+	webhookServer := webhook.NewWebhookServer(k8sClient, openappClient)
+	go func() {
+		if err := webhookServer.Run(ctx, ":9443"); err != nil {
+			klog.Fatalf("Run openapp webhook failed: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	return nil
+}
@@ -0,0 +1,8 @@
+// Package crds embeds the CRDs generated by controller-gen, so the resources
+// can be validated against their schema without a cluster.
+package crds
+
+import "embed"
+
+//go:embed *.yaml
+var FS embed.FS
@@ -29,38 +29,38 @@ echo "Generating with deepcopy-gen"
 deepcopy-gen \
   --output-file-base zz_generated.deepcopy \
   --go-header-file "${boilerplate}" \
-  --input-dirs github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1,github.com/openapp-dev/openapp/pkg/apis/service/v1alpha1
+  --input-dirs github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1,github.com/openapp-dev/openapp/pkg/apis/service/v1alpha1,github.com/openapp-dev/openapp/pkg/apis/registry/v1alpha1,github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1
 
 echo "Generating with register-gen"
 register-gen \
   --output-file-base zz_generated.register \
   --go-header-file "${boilerplate}" \
-  --input-dirs github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1,github.com/openapp-dev/openapp/pkg/apis/service/v1alpha1
+  --input-dirs github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1,github.com/openapp-dev/openapp/pkg/apis/service/v1alpha1,github.com/openapp-dev/openapp/pkg/apis/registry/v1alpha1
 
 echo "Generating with conversion-gen"
 conversion-gen \
   -O zz_generated.conversion \
   --go-header-file "${boilerplate}" \
-  --input-dirs github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1,github.com/openapp-dev/openapp/pkg/apis/service/v1alpha1
+  --input-dirs github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1,github.com/openapp-dev/openapp/pkg/apis/service/v1alpha1,github.com/openapp-dev/openapp/pkg/apis/registry/v1alpha1
 
 echo "Generating with client-gen"
 client-gen \
   --input-base "" \
   --go-header-file "${boilerplate}" \
-  --input github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1,github.com/openapp-dev/openapp/pkg/apis/service/v1alpha1 \
+  --input github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1,github.com/openapp-dev/openapp/pkg/apis/service/v1alpha1,github.com/openapp-dev/openapp/pkg/apis/registry/v1alpha1 \
   --output-package github.com/openapp-dev/openapp/pkg/generated/clientset \
   --clientset-name versioned
 
 echo "Generating with lister-gen"
 lister-gen \
   --go-header-file "${boilerplate}" \
-  --input-dirs github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1,github.com/openapp-dev/openapp/pkg/apis/service/v1alpha1 \
+  --input-dirs github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1,github.com/openapp-dev/openapp/pkg/apis/service/v1alpha1,github.com/openapp-dev/openapp/pkg/apis/registry/v1alpha1 \
   --output-package github.com/openapp-dev/openapp/pkg/generated/listers
 
 echo "Generating with informer-gen"
 informer-gen \
   --go-header-file "${boilerplate}" \
-  --input-dirs github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1,github.com/openapp-dev/openapp/pkg/apis/service/v1alpha1 \
+  --input-dirs github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1,github.com/openapp-dev/openapp/pkg/apis/service/v1alpha1,github.com/openapp-dev/openapp/pkg/apis/registry/v1alpha1 \
   --versioned-clientset-package github.com/openapp-dev/openapp/pkg/generated/clientset/versioned \
   --listers-package github.com/openapp-dev/openapp/pkg/generated/listers \
   --output-package github.com/openapp-dev/openapp/pkg/generated/informers
@@ -6,3 +6,4 @@ set -o pipefail
 
 controller-gen crd paths=./pkg/apis/app/... output:crd:dir=./config/crds
 controller-gen crd paths=./pkg/apis/service/... output:crd:dir=./config/crds
+controller-gen crd paths=./pkg/apis/registry/... output:crd:dir=./config/crds
@@ -12,6 +12,7 @@ import (
 // +kubebuilder:resource:categories={openapp-dev}
 // +kubebuilder:metadata:labels=openapp.dev/crd-install=true
 // +kubebuilder:printcolumn:JSONPath=`.spec.appTemplate`,name=`APP-TEMPLATE`,type=string
+// +kubebuilder:printcolumn:JSONPath=`.status.templateVersion`,name=`VERSION`,type=string
 // +kubebuilder:printcolumn:JSONPath=`.status.appReady`,name=`APP-READY`,type=string
 // +kubebuilder:printcolumn:JSONPath=`.spec.publicServiceClass`,name=`PUBLIC-SERVICE`,type=string
 // +kubebuilder:printcolumn:JSONPath=`.status.externalServiceURL`,name=`PUBLIC-URL`,type=string
@@ -31,7 +32,51 @@ type AppInstance struct {
 type AppInstanceSpec struct {
 	PublicServiceClass string `json:"publicServiceClass,omitempty"`
 	AppTemplate        string `json:"appTemplate"`
-	Inputs             string `json:"inputs,omitempty"`
+	// TemplateVersion pins the version of the template, it's set to the latest
+	// version when the instance is created and only changed by upgrade or rollback.
+	// +optional
+	TemplateVersion string `json:"templateVersion,omitempty"`
+	Inputs          string `json:"inputs,omitempty"`
+	// RevisionHistoryLimit is the number of revisions to retain, defaults to 10.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+	// RollbackToRevision rolls the instance back to the inputs and template
+	// version of the revision, it's cleared once the rollback is done.
+	// +optional
+	RollbackToRevision int64 `json:"rollbackToRevision,omitempty"`
+	// Patches are applied to the rendered objects in order before they are
+	// applied, so the instance can tweak the template without forking it.
+	// +optional
+	Patches []InstancePatch `json:"patches,omitempty"`
+}
+
+// InstancePatch is a strategic merge patch or a JSON6902 patch of the rendered
+// objects like the patches of kustomize.
+type InstancePatch struct {
+	// Patch is the patch in YAML or JSON. A JSON6902 patch is a list of
+	// operations and requires the target. A strategic merge patch is an object
+	// which patches the object of the same kind and name if the target is not
+	// set.
+	Patch string `json:"patch"`
+	// Target selects the objects to patch.
+	// +optional
+	Target *PatchTarget `json:"target,omitempty"`
+}
+
+// PatchTarget selects the rendered objects, the empty fields match any object.
+type PatchTarget struct {
+	// +optional
+	Group string `json:"group,omitempty"`
+	// +optional
+	Version string `json:"version,omitempty"`
+	// +optional
+	Kind string `json:"kind,omitempty"`
+	// +optional
+	Name string `json:"name,omitempty"`
+	// LabelSelector selects the objects by the labels, e.g. "app=web".
+	// +optional
+	LabelSelector string `json:"labelSelector,omitempty"`
 }
 
 type AppInstanceStatus struct {
@@ -43,8 +88,40 @@ type AppInstanceStatus struct {
 	LocalServiceURL string `json:"localServiceURL,omitempty"`
 	// +optional
 	DerivedResources []commonv1alpha1.DerivedResource `json:"derivedResources,omitempty"`
+	// TemplateVersion is the version of the template the resources rendered from.
+	// +optional
+	TemplateVersion string `json:"templateVersion,omitempty"`
+	// CurrentRevision is the revision of the applied inputs and template version.
+	// +optional
+	CurrentRevision int64 `json:"currentRevision,omitempty"`
 	// +optional
 	Message string `json:"message,omitempty"`
+	// Rollouts shows the rollout progress of the derived statefulsets.
+	// +optional
+	Rollouts []StatefulSetRolloutStatus `json:"rollouts,omitempty"`
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+type StatefulSetRolloutStatus struct {
+	Name string `json:"name"`
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+	// +optional
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+	// Pods with ordinal less than the partition will not be updated.
+	// +optional
+	Partition int32 `json:"partition,omitempty"`
+	// +optional
+	CurrentRevision string `json:"currentRevision,omitempty"`
+	// +optional
+	UpdateRevision string `json:"updateRevision,omitempty"`
+	// +optional
+	Completed bool `json:"completed,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
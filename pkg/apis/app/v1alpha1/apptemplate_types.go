@@ -6,6 +6,21 @@ import (
 	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
 )
 
+// AppTemplateType is how the resources of the app template are rendered.
+type AppTemplateType string
+
+const (
+	// AppTemplateManifests renders the manifests in the manifests directory of
+	// the template.
+	AppTemplateManifests AppTemplateType = "manifests"
+	// AppTemplateHelm renders the Helm chart of the template with the inputs as
+	// the values.
+	AppTemplateHelm AppTemplateType = "helm"
+	// AppTemplateCompose converts the compose file of the template into the
+	// manifests and renders them with the inputs.
+	AppTemplateCompose AppTemplateType = "compose"
+)
+
 // +genclient
 // +genclient:nonNamespaced
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -13,6 +28,8 @@ import (
 // +kubebuilder:metadata:labels=openapp.dev/crd-install=true
 // +kubebuilder:printcolumn:JSONPath=`.spec.url`,name=`APP-TEMPLATE-URL`,type=string
 // +kubebuilder:printcolumn:JSONPath=`.spec.exposeType`,name=`EXPOSE-TYPE`,type=string
+// +kubebuilder:printcolumn:JSONPath=`.spec.version`,name=`VERSION`,type=string
+// +kubebuilder:printcolumn:JSONPath=`.spec.registry`,name=`REGISTRY`,type=string
 
 type AppTemplate struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -22,13 +39,54 @@ type AppTemplate struct {
 }
 
 type AppTemplateSpec struct {
-	Title       string                    `json:"title"`
-	Description string                    `json:"description"`
-	Author      string                    `json:"author"`
-	Icon        string                    `json:"icon"`
-	URL         string                    `json:"url"`
-	Inputs      string                    `json:"inputs"`
-	ExposeType  commonv1alpha1.ExposeType `json:"exposeType"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Author      string `json:"author"`
+	Icon        string `json:"icon"`
+	URL         string `json:"url"`
+	Inputs      string `json:"inputs"`
+	// InputSchema validates the inputs of the instances and fills the defaults.
+	// +optional
+	InputSchema *commonv1alpha1.InputSchema `json:"inputSchema,omitempty"`
+	ExposeType  commonv1alpha1.ExposeType   `json:"exposeType"`
+	// Version is the semantic version of the template checked out in the registry.
+	// +optional
+	Version string `json:"version,omitempty"`
+	// Versions lists all the versions kept in the template cache.
+	// +optional
+	Versions []string `json:"versions,omitempty"`
+	// Registry is the name of the registry the template comes from.
+	// +optional
+	Registry string `json:"registry,omitempty"`
+	// Type is the type of the template, default is manifests.
+	// +kubebuilder:validation:Enum=manifests;helm;compose
+	// +optional
+	Type AppTemplateType `json:"type,omitempty"`
+	// Chart is the Helm chart of the helm template.
+	// +optional
+	Chart *HelmChart `json:"chart,omitempty"`
+	// Compose is the compose file of the compose template relative to the
+	// template directory, default is docker-compose.yaml.
+	// +optional
+	Compose string `json:"compose,omitempty"`
+}
+
+// HelmChart is either a chart in the template directory or a chart of a chart
+// repository.
+type HelmChart struct {
+	// Path is the chart directory or archive relative to the template directory.
+	// +optional
+	Path string `json:"path,omitempty"`
+	// Repository is the URL of the chart repository.
+	// +optional
+	Repository string `json:"repository,omitempty"`
+	// Name is the name of the chart in the repository.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Version is the version of the chart in the repository, it's required so
+	// the instances are not changed by the new releases of the chart.
+	// +optional
+	Version string `json:"version,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
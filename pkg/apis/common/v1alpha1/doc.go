@@ -0,0 +1,3 @@
+// Package v1alpha1 contains the types shared by the OpenAPP APIs.
+// +k8s:deepcopy-gen=package
+package v1alpha1
@@ -0,0 +1,45 @@
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type InputType string
+
+const (
+	InputTypeString  InputType = "string"
+	InputTypeInteger InputType = "integer"
+	InputTypeNumber  InputType = "number"
+	InputTypeBoolean InputType = "boolean"
+	InputTypeObject  InputType = "object"
+	InputTypeArray   InputType = "array"
+)
+
+// InputSchema declares the inputs accepted by a template, it's a subset of
+// JSON-Schema which only describes the top level properties.
+type InputSchema struct {
+	// +optional
+	Properties map[string]InputProperty `json:"properties,omitempty"`
+	// +optional
+	Required []string `json:"required,omitempty"`
+}
+
+type InputProperty struct {
+	// +kubebuilder:validation:Enum=string;integer;number;boolean;object;array
+	Type InputType `json:"type"`
+	// +optional
+	Title string `json:"title,omitempty"`
+	// +optional
+	Description string `json:"description,omitempty"`
+	// Default is used if the input is not set in the instance.
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	Default *runtime.RawExtension `json:"default,omitempty"`
+	// Enum lists the allowed values, they are compared in string format.
+	// +optional
+	Enum []string `json:"enum,omitempty"`
+	// Secret marks the input as sensitive, UI should not show it in plain text.
+	// +optional
+	Secret bool `json:"secret,omitempty"`
+}
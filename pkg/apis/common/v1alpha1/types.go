@@ -4,6 +4,9 @@ type DerivedResource struct {
 	APIVersion string `json:"apiVersion"`
 	Kind       string `json:"kind"`
 	Name       string `json:"name"`
+	// Namespace is set if the resource is not in the namespace of the instance.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
 }
 
 type ExposeType string
@@ -12,3 +15,49 @@ const (
 	ExposeLayer4 ExposeType = "Layer4"
 	ExposeLayer7 ExposeType = "Layer7"
 )
+
+// Condition types of AppInstance and PublicServiceInstance.
+const (
+	ConditionTemplateResolved = "TemplateResolved"
+	ConditionInputsValid      = "InputsValid"
+	ConditionResourcesApplied = "ResourcesApplied"
+	ConditionWorkloadReady    = "WorkloadReady"
+	ConditionExposed          = "Exposed"
+	ConditionRolledBack       = "RolledBack"
+)
+
+// Condition reasons of AppInstance and PublicServiceInstance.
+const (
+	ReasonTemplateFound        = "TemplateFound"
+	ReasonTemplateNotSpecified = "TemplateNotSpecified"
+	ReasonTemplateNotFound     = "TemplateNotFound"
+	ReasonInputsAccepted       = "InputsAccepted"
+	ReasonInvalidInputs        = "InvalidInputs"
+	ReasonApplied              = "Applied"
+	ReasonRenderFailed         = "RenderFailed"
+	ReasonPatchFailed          = "PatchFailed"
+	ReasonApplyFailed          = "ApplyFailed"
+	ReasonApplyConflict        = "ApplyConflict"
+	ReasonReplicasReady        = "ReplicasReady"
+	ReasonReplicasNotReady     = "ReplicasNotReady"
+	ReasonRolloutInProgress    = "RolloutInProgress"
+	ReasonServiceExposed       = "ServiceExposed"
+	ReasonServiceNotFound      = "ServiceNotFound"
+	ReasonPublicURLPending     = "PublicURLPending"
+	ReasonRevisionRestored     = "RevisionRestored"
+	ReasonRollbackFailed       = "RollbackFailed"
+)
+
+// Event reasons of AppInstance, PublicServiceInstance and their derived
+// resources, the failures are recorded with the condition reasons.
+const (
+	EventReasonRendered           = "Rendered"
+	EventReasonDeleted            = "Deleted"
+	EventReasonRolloutStarted     = "RolloutStarted"
+	EventReasonRolloutCompleted   = "RolloutCompleted"
+	EventReasonRolledBack         = "RolledBack"
+	EventReasonPublicServiceInUse = "PublicServiceInUse"
+	EventReasonTemplateRemoved    = "TemplateRemoved"
+	EventReasonURLChanged         = "URLChanged"
+	EventReasonOrphanDeleted      = "OrphanDeleted"
+)
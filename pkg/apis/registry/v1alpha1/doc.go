@@ -0,0 +1,5 @@
+// Package v1alpha1 is the v1alpha1 version of the API.
+// +k8s:deepcopy-gen=package,register
+// +k8s:openapi-gen=true
+// +groupName=registry.openapp.dev
+package v1alpha1
@@ -0,0 +1,180 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ConditionSynced is the condition type of whether the last sync succeeded.
+	ConditionSynced = "Synced"
+
+	// ConditionVerified is the condition type of whether the content of the
+	// last sync is signed by the trusted keys.
+	ConditionVerified = "Verified"
+
+	ReasonSyncSucceeded      = "SyncSucceeded"
+	ReasonSyncFailed         = "SyncFailed"
+	ReasonSignatureVerified  = "SignatureVerified"
+	ReasonVerificationFailed = "VerificationFailed"
+)
+
+// RegistrySourceType is where the templates of the registry are fetched from.
+type RegistrySourceType string
+
+const (
+	// RegistrySourceGit clones the git repository of the url.
+	RegistrySourceGit RegistrySourceType = "git"
+	// RegistrySourceLocal copies the local directory of the path, the directory
+	// is watched and synced once it's changed.
+	RegistrySourceLocal RegistrySourceType = "local"
+	// RegistrySourceArchive downloads and unpacks the .tar.gz archive of the url.
+	RegistrySourceArchive RegistrySourceType = "archive"
+	// RegistrySourceOCI pulls the template bundle artifact of the OCI repository.
+	RegistrySourceOCI RegistrySourceType = "oci"
+)
+
+// RegistryVerificationMethod is how the content of the registry is verified.
+type RegistryVerificationMethod string
+
+const (
+	// RegistryVerificationCommit verifies the OpenPGP signature of the checked
+	// out commit, or of the tag if the ref is a signed annotated tag. It's only
+	// supported by the git registries.
+	RegistryVerificationCommit RegistryVerificationMethod = "commit"
+	// RegistryVerificationManifest verifies the OpenPGP signature of the
+	// manifest file at the registry root, and all the files of the registry
+	// against the digests listed in the manifest.
+	RegistryVerificationManifest RegistryVerificationMethod = "manifest"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:scope="Cluster",categories={openapp-dev}
+// +kubebuilder:subresource:status
+// +kubebuilder:metadata:labels=openapp.dev/crd-install=true
+// +kubebuilder:printcolumn:JSONPath=`.spec.type`,name=`TYPE`,type=string
+// +kubebuilder:printcolumn:JSONPath=`.spec.url`,name=`URL`,type=string
+// +kubebuilder:printcolumn:JSONPath=`.spec.ref`,name=`REF`,type=string
+// +kubebuilder:printcolumn:JSONPath=`.status.lastSyncedCommit`,name=`COMMIT`,type=string
+// +kubebuilder:printcolumn:JSONPath=`.status.verification.verified`,name=`VERIFIED`,type=boolean
+// +kubebuilder:printcolumn:JSONPath=`.status.lastSyncTime`,name=`LAST-SYNC`,type=date
+
+type Registry struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +required
+	Spec RegistrySpec `json:"spec"`
+
+	// +optional
+	Status RegistryStatus `json:"status"`
+}
+
+type RegistrySpec struct {
+	// Type is the source type of the registry, default is git.
+	// +kubebuilder:validation:Enum=git;local;archive;oci
+	// +optional
+	Type RegistrySourceType `json:"type,omitempty"`
+	// URL is the git repository, the archive or the OCI repository of the
+	// registry. The OCI repository is in the form of `oci://<host>/<repo>`, or
+	// `http://<host>/<repo>` for the registries without TLS.
+	// +optional
+	URL string `json:"url,omitempty"`
+	// Ref is the branch, tag or commit SHA to check out, the default branch of
+	// the repository is used if it's empty. For the OCI registry, it's the tag
+	// or the digest of the artifact, default is `latest`.
+	// +optional
+	Ref string `json:"ref,omitempty"`
+	// Path is the directory of the local registry, it must be mounted into the
+	// controller. For the OCI registry, it's an OCI image layout directory used
+	// instead of the remote repository.
+	// +optional
+	Path string `json:"path,omitempty"`
+	// Checksum is the expected digest of the archive in the form of
+	// `sha256:<hex>`, the archive is refused if the digest doesn't match.
+	// +optional
+	Checksum string `json:"checksum,omitempty"`
+	// AuthSecretName is the secret in the openapp-system namespace containing
+	// `ssh-privatekey`(with `known_hosts`), `token` or `username`/`password`,
+	// and an optional `ca.crt`.
+	// +optional
+	AuthSecretName string `json:"authSecretName,omitempty"`
+	// InsecureSkipTLSVerify disables the TLS verification of the registry.
+	// +optional
+	InsecureSkipTLSVerify bool `json:"insecureSkipTLSVerify,omitempty"`
+	// SyncInterval is the interval to poll the registry, default is 30m.
+	// +optional
+	SyncInterval *metav1.Duration `json:"syncInterval,omitempty"`
+	// WebhookSecretName is the secret in the openapp-system namespace with the
+	// `webhook-secret` key, the push webhooks of the git registry validated by
+	// the secret trigger an immediate sync. The webhook is refused if it's not set.
+	// +optional
+	WebhookSecretName string `json:"webhookSecretName,omitempty"`
+	// Priority decides which registry the template is taken from if multiple
+	// registries have templates with the same name, the higher one wins.
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+	// Verification refuses the content of the registry which isn't signed by
+	// the trusted keys, the content isn't verified if it's not set.
+	// +optional
+	Verification *RegistryVerification `json:"verification,omitempty"`
+}
+
+type RegistryVerification struct {
+	// +kubebuilder:validation:Enum=commit;manifest
+	// +required
+	Method RegistryVerificationMethod `json:"method"`
+	// PublicKeys is the ASCII armored OpenPGP public keys trusted to sign the
+	// registry content.
+	// +required
+	PublicKeys string `json:"publicKeys"`
+}
+
+type RegistryVerificationStatus struct {
+	// Verified is true if the content of the last sync is verified.
+	Verified bool `json:"verified"`
+	// +optional
+	Method RegistryVerificationMethod `json:"method,omitempty"`
+	// Signer is the identity and key ID of the signature.
+	// +optional
+	Signer string `json:"signer,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+type RegistryStatus struct {
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastSyncedCommit is the commit checked out by the last successful sync,
+	// it's the content digest for the local and archive registries.
+	// +optional
+	LastSyncedCommit string `json:"lastSyncedCommit,omitempty"`
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// +optional
+	LastSyncDuration *metav1.Duration `json:"lastSyncDuration,omitempty"`
+	// LastError is the error of the last sync, it's empty if the sync succeeded.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+	// +optional
+	AppTemplateCount int32 `json:"appTemplateCount,omitempty"`
+	// +optional
+	PublicServiceTemplateCount int32 `json:"publicServiceTemplateCount,omitempty"`
+	// Verification is the verification result of the last sync, it's empty if
+	// the verification is not enabled.
+	// +optional
+	Verification *RegistryVerificationStatus `json:"verification,omitempty"`
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type RegistryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Registry `json:"items"`
+}
@@ -28,7 +28,11 @@ type PublicServiceInstance struct {
 
 type PublicServiceInstanceSpec struct {
 	PublicServiceTemplate string `json:"publicServiceTemplate"`
-	Inputs                string `json:"inputs,omitempty"`
+	// TemplateVersion pins the version of the template, it's set to the latest
+	// version when the instance is created and only changed by upgrade.
+	// +optional
+	TemplateVersion string `json:"templateVersion,omitempty"`
+	Inputs          string `json:"inputs,omitempty"`
 }
 
 type PublicServiceInstanceStatus struct {
@@ -39,8 +43,15 @@ type PublicServiceInstanceStatus struct {
 	LocalServiceURL string `json:"localServiceURL,omitempty"`
 	// +optional
 	DerivedResources []commonv1alpha1.DerivedResource `json:"derivedResources,omitempty"`
+	// TemplateVersion is the version of the template the resources rendered from.
+	// +optional
+	TemplateVersion string `json:"templateVersion,omitempty"`
 	// +optional
 	Message string `json:"message,omitempty"`
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
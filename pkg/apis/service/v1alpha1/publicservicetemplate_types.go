@@ -29,8 +29,20 @@ type PublicServiceTemplateSpec struct {
 	Icon        string `json:"icon"`
 	URL         string `json:"url"`
 	Inputs      string `json:"inputs"`
+	// InputSchema validates the inputs of the instances and fills the defaults.
+	// +optional
+	InputSchema *commonv1alpha1.InputSchema `json:"inputSchema,omitempty"`
 	// +required
 	ExposeTypes []commonv1alpha1.ExposeType `json:"exposeTypes"`
+	// Version is the semantic version of the template checked out in the registry.
+	// +optional
+	Version string `json:"version,omitempty"`
+	// Versions lists all the versions kept in the template cache.
+	// +optional
+	Versions []string `json:"versions,omitempty"`
+	// Registry is the name of the registry the template comes from.
+	// +optional
+	Registry string `json:"registry,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -12,6 +12,7 @@ package v1alpha1
 
 import (
 	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -100,6 +101,13 @@ func (in *PublicServiceInstanceStatus) DeepCopyInto(out *PublicServiceInstanceSt
 		*out = make([]commonv1alpha1.DerivedResource, len(*in))
 		copy(*out, *in)
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -176,11 +184,21 @@ func (in *PublicServiceTemplateList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PublicServiceTemplateSpec) DeepCopyInto(out *PublicServiceTemplateSpec) {
 	*out = *in
+	if in.InputSchema != nil {
+		in, out := &in.InputSchema, &out.InputSchema
+		*out = new(commonv1alpha1.InputSchema)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.ExposeTypes != nil {
 		in, out := &in.ExposeTypes, &out.ExposeTypes
 		*out = make([]commonv1alpha1.ExposeType, len(*in))
 		copy(*out, *in)
 	}
+	if in.Versions != nil {
+		in, out := &in.Versions, &out.Versions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
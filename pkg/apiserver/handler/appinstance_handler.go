@@ -101,6 +101,21 @@ func CreateOrUpdateAppInstanceHandler(ctx *gin.Context) {
 	}
 	appIns.Name = ctx.Param("instanceName")
 	appIns.Namespace = utils.InstanceNamespace
+
+	appTemp, err := utils.GetAppTemplate(openappHelper.AppTemplateLister.Get, appIns.Spec.AppTemplate)
+	if err != nil {
+		klog.Errorf("Failed to get app template: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if schema, ok := templateInputSchema(appIns.Spec.AppTemplate, appIns.Spec.TemplateVersion,
+		utils.AppTemplateBasePath, appTemp.Spec.Version, appTemp.Spec.InputSchema); ok {
+		if errs := validateInstanceInputs(appIns.Spec.Inputs, schema); len(errs) != 0 {
+			klog.Warningf("Invalid inputs of app instance(%s)", appIns.Name)
+			utils.ReturnFormattedData(ctx, http.StatusBadRequest, "Invalid inputs", errs)
+			return
+		}
+	}
 	_, err = openappHelper.OpenAPPClient.AppV1alpha1().AppInstances(utils.InstanceNamespace).
 		Create(context.Background(), &appIns, metav1.CreateOptions{})
 	if err != nil {
@@ -168,3 +183,174 @@ func AppInstanceLoggingHandler(ctx *gin.Context) {
 		utils.ReturnFormattedData(ctx, http.StatusOK, "Get app instance logs successfully", string(logs))
 	}
 }
+
+func AppInstanceEventsHandler(ctx *gin.Context) {
+	klog.V(4).Infof("Start to list app instance events...")
+	openappHelper, err := getOpenAPPHelper(ctx)
+	if err != nil {
+		klog.Errorf("Failed to get openapp lister: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	insName := ctx.Param("instanceName")
+	events, err := utils.ListInstanceEvents(openappHelper.K8sClient, "AppInstance", insName, "app="+insName)
+	if err != nil {
+		klog.Errorf("Failed to list app instance events: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	utils.ReturnFormattedData(ctx, http.StatusOK, "List app instance events successfully", events)
+}
+
+func UpgradeAppInstanceHandler(ctx *gin.Context) {
+	klog.V(4).Infof("Start to upgrade app instance...")
+	openappHelper, err := getOpenAPPHelper(ctx)
+	if err != nil {
+		klog.Errorf("Failed to get openapp lister: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	req, err := getUpgradeRequest(ctx)
+	if err != nil {
+		klog.Errorf("Failed to unmarshal request body: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	insName := ctx.Param("instanceName")
+	appIns, err := openappHelper.OpenAPPClient.AppV1alpha1().AppInstances(utils.InstanceNamespace).
+		Get(context.Background(), insName, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("Failed to get app instance: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+	appTemp, err := utils.GetAppTemplate(openappHelper.AppTemplateLister.Get, appIns.Spec.AppTemplate)
+	if err != nil {
+		klog.Errorf("Failed to get app template: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	version, err := utils.ResolveUpgradeVersion(appIns.Spec.TemplateVersion, req.Version,
+		appTemp.Spec.Version, appTemp.Spec.Versions)
+	if err != nil {
+		klog.Errorf("Failed to upgrade app instance(%s): %v", insName, err)
+		utils.ReturnFormattedData(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	// The inputs are kept on upgrade, they must be accepted by the target version
+	if schema, ok := templateInputSchema(appIns.Spec.AppTemplate, version, utils.AppTemplateBasePath,
+		appTemp.Spec.Version, appTemp.Spec.InputSchema); ok {
+		if errs := validateInstanceInputs(appIns.Spec.Inputs, schema); len(errs) != 0 {
+			klog.Warningf("Invalid inputs of app instance(%s) for version %s", insName, version)
+			utils.ReturnFormattedData(ctx, http.StatusBadRequest, "Invalid inputs", errs)
+			return
+		}
+	}
+	appIns.Spec.TemplateVersion = version
+	_, err = openappHelper.OpenAPPClient.AppV1alpha1().AppInstances(utils.InstanceNamespace).
+		Update(context.Background(), appIns, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Errorf("Failed to upgrade app instance: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	utils.ReturnFormattedData(ctx, http.StatusOK, "Upgrade app instance successfully", appIns)
+}
+
+func ListAppInstanceRevisionsHandler(ctx *gin.Context) {
+	klog.V(4).Infof("Start to list app instance revisions...")
+	openappHelper, err := getOpenAPPHelper(ctx)
+	if err != nil {
+		klog.Errorf("Failed to get openapp lister: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	insName := ctx.Param("instanceName")
+	revisions, err := utils.ListAppInstanceRevisions(openappHelper.K8sClient, insName)
+	if err != nil {
+		klog.Errorf("Failed to list app instance revisions: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+	ret := []*utils.InstanceRevision{}
+	for i := range revisions {
+		revision, err := utils.DecodeInstanceRevision(&revisions[i])
+		if err != nil {
+			continue
+		}
+		ret = append(ret, revision)
+	}
+
+	utils.ReturnFormattedData(ctx, http.StatusOK, "List app instance revisions successfully", ret)
+}
+
+type rollbackRequest struct {
+	Revision int64 `json:"revision"`
+}
+
+func RollbackAppInstanceHandler(ctx *gin.Context) {
+	klog.V(4).Infof("Start to rollback app instance...")
+	openappHelper, err := getOpenAPPHelper(ctx)
+	if err != nil {
+		klog.Errorf("Failed to get openapp lister: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	var req rollbackRequest
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		klog.Errorf("Failed to read request body: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+	if err := json.Unmarshal(body, &req); err != nil || req.Revision <= 0 {
+		klog.Errorf("Invalid rollback request: %s", string(body))
+		utils.ReturnFormattedData(ctx, http.StatusBadRequest, "Revision is required", nil)
+		return
+	}
+
+	insName := ctx.Param("instanceName")
+	revisions, err := utils.ListAppInstanceRevisions(openappHelper.K8sClient, insName)
+	if err != nil {
+		klog.Errorf("Failed to list app instance revisions: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+	found := false
+	for _, revision := range revisions {
+		if revision.Revision == req.Revision {
+			found = true
+			break
+		}
+	}
+	if !found {
+		utils.ReturnFormattedData(ctx, http.StatusNotFound, "Revision is not found", nil)
+		return
+	}
+
+	appIns, err := openappHelper.OpenAPPClient.AppV1alpha1().AppInstances(utils.InstanceNamespace).
+		Get(context.Background(), insName, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("Failed to get app instance: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+	appIns.Spec.RollbackToRevision = req.Revision
+	_, err = openappHelper.OpenAPPClient.AppV1alpha1().AppInstances(utils.InstanceNamespace).
+		Update(context.Background(), appIns, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Errorf("Failed to rollback app instance: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	utils.ReturnFormattedData(ctx, http.StatusOK, "Rollback app instance successfully", nil)
+}
@@ -7,6 +7,7 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/klog"
 
+	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
 	"github.com/openapp-dev/openapp/pkg/utils"
 )
 
@@ -26,7 +27,14 @@ func ListAllAppTemplatesHandler(ctx *gin.Context) {
 		return
 	}
 
-	utils.ReturnFormattedData(ctx, http.StatusOK, "List app templates successfully", appTemps)
+	// The deprecated templates are only kept for the instances using them
+	available := []*appv1alpha1.AppTemplate{}
+	for _, temp := range appTemps {
+		if !utils.IsTemplateDeprecated(temp) {
+			available = append(available, temp)
+		}
+	}
+	utils.ReturnFormattedData(ctx, http.StatusOK, "List app templates successfully", available)
 }
 
 func GetAppTemplateHandler(ctx *gin.Context) {
@@ -38,8 +46,8 @@ func GetAppTemplateHandler(ctx *gin.Context) {
 		return
 	}
 
-	tempName := ctx.Param("templateName")
-	appTemp, err := openappHelper.AppTemplateLister.Get(tempName)
+	// The shadowed template is referenced as `registry/template`
+	appTemp, err := utils.GetAppTemplate(openappHelper.AppTemplateLister.Get, ctx.Param("templateName"))
 	if err != nil {
 		klog.Errorf("Failed to get app template: %v", err)
 		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, "Failed to get app template", nil)
@@ -48,3 +56,23 @@ func GetAppTemplateHandler(ctx *gin.Context) {
 
 	utils.ReturnFormattedData(ctx, http.StatusOK, "Get app template successfully", appTemp)
 }
+
+func GetAppTemplateInputSchemaHandler(ctx *gin.Context) {
+	klog.V(4).Infof("Start to get app template input schema...")
+	openappHelper, err := getOpenAPPHelper(ctx)
+	if err != nil {
+		klog.Errorf("Failed to get openapp lister: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, "Failed to get openapp lister", nil)
+		return
+	}
+
+	// The shadowed template is referenced as `registry/template`
+	appTemp, err := utils.GetAppTemplate(openappHelper.AppTemplateLister.Get, ctx.Param("templateName"))
+	if err != nil {
+		klog.Errorf("Failed to get app template: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, "Failed to get app template", nil)
+		return
+	}
+
+	utils.ReturnFormattedData(ctx, http.StatusOK, "Get app template input schema successfully", appTemp.Spec.InputSchema)
+}
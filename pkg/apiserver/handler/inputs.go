@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/klog"
+
+	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
+	"github.com/openapp-dev/openapp/pkg/utils"
+)
+
+type inputError struct {
+	Field  string `json:"field"`
+	Type   string `json:"type"`
+	Detail string `json:"detail"`
+}
+
+// validateInstanceInputs returns the field-level errors of the inputs, so the UI
+// can show them next to the form fields.
+func validateInstanceInputs(inputs string, schema *commonv1alpha1.InputSchema) []inputError {
+	_, errs := utils.ResolveInputs(inputs, schema)
+	return toInputErrors(errs)
+}
+
+// templateInputSchema returns the input schema of the version of the template,
+// the schemas of the older versions are loaded from the registry cache. It's
+// false if the schema can't be loaded, then the controller will validate the
+// inputs like the webhook does.
+func templateInputSchema(templateRef, version, tempBasePath, latestVersion string,
+	latestSchema *commonv1alpha1.InputSchema) (*commonv1alpha1.InputSchema, bool) {
+	if version == "" || version == latestVersion {
+		return latestSchema, true
+	}
+	registry, tempName := utils.ParseTemplateReference(templateRef)
+	schema, err := utils.LoadTemplateInputSchema(registry, tempName, version, tempBasePath)
+	if err != nil {
+		klog.Warningf("Failed to load input schema of template(%s) %s, skip validating inputs: %v",
+			templateRef, version, err)
+		return nil, false
+	}
+	return schema, true
+}
+
+func toInputErrors(errs field.ErrorList) []inputError {
+	ret := []inputError{}
+	for _, err := range errs {
+		ret = append(ret, inputError{
+			Field:  err.Field,
+			Type:   string(err.Type),
+			Detail: err.ErrorBody(),
+		})
+	}
+	return ret
+}
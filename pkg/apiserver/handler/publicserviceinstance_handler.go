@@ -102,6 +102,22 @@ func CreateOrUpdatePublicServiceInstanceHandler(ctx *gin.Context) {
 
 	ins.Name = ctx.Param("instanceName")
 	ins.Namespace = utils.InstanceNamespace
+
+	temp, err := utils.GetPublicServiceTemplate(openappHelper.PublicServiceTemplateLister.Get,
+		ins.Spec.PublicServiceTemplate)
+	if err != nil {
+		klog.Errorf("Failed to get public service template: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusBadRequest, "Failed to get public service template", nil)
+		return
+	}
+	if schema, ok := templateInputSchema(ins.Spec.PublicServiceTemplate, ins.Spec.TemplateVersion,
+		utils.PublicServiceTemplateBasePath, temp.Spec.Version, temp.Spec.InputSchema); ok {
+		if errs := validateInstanceInputs(ins.Spec.Inputs, schema); len(errs) != 0 {
+			klog.Warningf("Invalid inputs of public service instance(%s)", ins.Name)
+			utils.ReturnFormattedData(ctx, http.StatusBadRequest, "Invalid inputs", errs)
+			return
+		}
+	}
 	_, err = openappHelper.OpenAPPClient.ServiceV1alpha1().PublicServiceInstances(utils.InstanceNamespace).
 		Create(context.Background(), &ins, metav1.CreateOptions{})
 	if err != nil {
@@ -170,3 +186,83 @@ func PublicServiceInstanceLoggingHandler(ctx *gin.Context) {
 		utils.ReturnFormattedData(ctx, http.StatusOK, "Get public service instance logs successfully", string(logs))
 	}
 }
+
+func PublicServiceInstanceEventsHandler(ctx *gin.Context) {
+	klog.V(4).Infof("Start to list public service instance events...")
+	openappHelper, err := getOpenAPPHelper(ctx)
+	if err != nil {
+		klog.Errorf("Failed to get openapp lister: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	insName := ctx.Param("instanceName")
+	events, err := utils.ListInstanceEvents(openappHelper.K8sClient, "PublicServiceInstance", insName, "publicservice="+insName)
+	if err != nil {
+		klog.Errorf("Failed to list public service instance events: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	utils.ReturnFormattedData(ctx, http.StatusOK, "List public service instance events successfully", events)
+}
+
+func UpgradePublicServiceInstanceHandler(ctx *gin.Context) {
+	klog.V(4).Infof("Start to upgrade public service instance...")
+	openappHelper, err := getOpenAPPHelper(ctx)
+	if err != nil {
+		klog.Errorf("Failed to get openapp lister: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, "Failed to get openapp lister", nil)
+		return
+	}
+
+	req, err := getUpgradeRequest(ctx)
+	if err != nil {
+		klog.Errorf("Failed to unmarshal request body: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusBadRequest, "Failed to unmarshal request body", nil)
+		return
+	}
+
+	insName := ctx.Param("instanceName")
+	ins, err := openappHelper.OpenAPPClient.ServiceV1alpha1().PublicServiceInstances(utils.InstanceNamespace).
+		Get(context.Background(), insName, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("Failed to get public service instance: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, "Failed to get public service instance", nil)
+		return
+	}
+	temp, err := utils.GetPublicServiceTemplate(openappHelper.PublicServiceTemplateLister.Get,
+		ins.Spec.PublicServiceTemplate)
+	if err != nil {
+		klog.Errorf("Failed to get public service template: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, "Failed to get public service template", nil)
+		return
+	}
+
+	version, err := utils.ResolveUpgradeVersion(ins.Spec.TemplateVersion, req.Version,
+		temp.Spec.Version, temp.Spec.Versions)
+	if err != nil {
+		klog.Errorf("Failed to upgrade public service instance(%s): %v", insName, err)
+		utils.ReturnFormattedData(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	// The inputs are kept on upgrade, they must be accepted by the target version
+	if schema, ok := templateInputSchema(ins.Spec.PublicServiceTemplate, version, utils.PublicServiceTemplateBasePath,
+		temp.Spec.Version, temp.Spec.InputSchema); ok {
+		if errs := validateInstanceInputs(ins.Spec.Inputs, schema); len(errs) != 0 {
+			klog.Warningf("Invalid inputs of public service instance(%s) for version %s", insName, version)
+			utils.ReturnFormattedData(ctx, http.StatusBadRequest, "Invalid inputs", errs)
+			return
+		}
+	}
+	ins.Spec.TemplateVersion = version
+	_, err = openappHelper.OpenAPPClient.ServiceV1alpha1().PublicServiceInstances(utils.InstanceNamespace).
+		Update(context.Background(), ins, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Errorf("Failed to upgrade public service instance: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, "Failed to upgrade public service instance", nil)
+		return
+	}
+
+	utils.ReturnFormattedData(ctx, http.StatusOK, "Upgrade public service instance successfully", ins)
+}
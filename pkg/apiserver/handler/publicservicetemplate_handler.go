@@ -7,6 +7,7 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/klog"
 
+	servicev1alpha1 "github.com/openapp-dev/openapp/pkg/apis/service/v1alpha1"
 	"github.com/openapp-dev/openapp/pkg/utils"
 )
 
@@ -26,7 +27,14 @@ func ListAllPublicServiceTemplatesHandler(ctx *gin.Context) {
 		return
 	}
 
-	utils.ReturnFormattedData(ctx, http.StatusOK, "List publicservice templates successfully", publicServiceTemps)
+	// The deprecated templates are only kept for the instances using them
+	available := []*servicev1alpha1.PublicServiceTemplate{}
+	for _, temp := range publicServiceTemps {
+		if !utils.IsTemplateDeprecated(temp) {
+			available = append(available, temp)
+		}
+	}
+	utils.ReturnFormattedData(ctx, http.StatusOK, "List publicservice templates successfully", available)
 }
 
 func GetPublicServiceTemplateHandler(ctx *gin.Context) {
@@ -38,8 +46,8 @@ func GetPublicServiceTemplateHandler(ctx *gin.Context) {
 		return
 	}
 
-	tempName := ctx.Param("templateName")
-	publicServiceTemp, err := openappHelper.PublicServiceTemplateLister.Get(tempName)
+	// The shadowed template is referenced as `registry/template`
+	publicServiceTemp, err := utils.GetPublicServiceTemplate(openappHelper.PublicServiceTemplateLister.Get, ctx.Param("templateName"))
 	if err != nil {
 		klog.Errorf("Failed to get publicservice template: %v", err)
 		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, "Failed to get publicservice template", nil)
@@ -48,3 +56,24 @@ func GetPublicServiceTemplateHandler(ctx *gin.Context) {
 
 	utils.ReturnFormattedData(ctx, http.StatusOK, "Get publicservice template successfully", publicServiceTemp)
 }
+
+func GetPublicServiceTemplateInputSchemaHandler(ctx *gin.Context) {
+	klog.V(4).Infof("Start to get publicservice template input schema...")
+	openappHelper, err := getOpenAPPHelper(ctx)
+	if err != nil {
+		klog.Errorf("Failed to get openapp lister: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, "Failed to get openapp lister", nil)
+		return
+	}
+
+	// The shadowed template is referenced as `registry/template`
+	publicServiceTemp, err := utils.GetPublicServiceTemplate(openappHelper.PublicServiceTemplateLister.Get, ctx.Param("templateName"))
+	if err != nil {
+		klog.Errorf("Failed to get publicservice template: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, "Failed to get publicservice template", nil)
+		return
+	}
+
+	utils.ReturnFormattedData(ctx, http.StatusOK, "Get publicservice template input schema successfully",
+		publicServiceTemp.Spec.InputSchema)
+}
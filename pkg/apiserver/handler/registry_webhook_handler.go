@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	pkgtypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+
+	registryv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/registry/v1alpha1"
+	"github.com/openapp-dev/openapp/pkg/utils"
+)
+
+const (
+	registryWebhookSecretKey = "webhook-secret"
+	// registryWebhookMaxBodySize limits the payload read before it's validated
+	registryWebhookMaxBodySize = 10 << 20
+)
+
+// pushEvent is the common fields of the push events of GitHub, Gitea and GitLab.
+type pushEvent struct {
+	Ref        string `json:"ref"`
+	Repository struct {
+		CloneURL   string `json:"clone_url"`
+		SSHURL     string `json:"ssh_url"`
+		HTMLURL    string `json:"html_url"`
+		GitHTTPURL string `json:"git_http_url"`
+		GitSSHURL  string `json:"git_ssh_url"`
+		Homepage   string `json:"homepage"`
+	} `json:"repository"`
+	Project struct {
+		GitHTTPURL string `json:"git_http_url"`
+		GitSSHURL  string `json:"git_ssh_url"`
+		WebURL     string `json:"web_url"`
+	} `json:"project"`
+}
+
+func (e *pushEvent) repoURLs() []string {
+	ret := []string{}
+	for _, u := range []string{e.Repository.CloneURL, e.Repository.SSHURL, e.Repository.HTMLURL,
+		e.Repository.GitHTTPURL, e.Repository.GitSSHURL, e.Repository.Homepage,
+		e.Project.GitHTTPURL, e.Project.GitSSHURL, e.Project.WebURL} {
+		if u != "" {
+			ret = append(ret, u)
+		}
+	}
+	return ret
+}
+
+// RegistryWebhookHandler receives the push webhooks of the git registries, the
+// registries of the pushed repository are synced immediately if the webhook is
+// validated by their webhook secret.
+func RegistryWebhookHandler(ctx *gin.Context) {
+	klog.V(4).Infof("Start to handle registry webhook...")
+	openappHelper, err := getOpenAPPHelper(ctx)
+	if err != nil {
+		klog.Errorf("Failed to get openapp lister: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, "Failed to get openapp lister", nil)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, registryWebhookMaxBodySize))
+	if err != nil {
+		klog.Errorf("Failed to read request body: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusBadRequest, "Failed to read request body", nil)
+		return
+	}
+	if !isPushEvent(ctx.Request.Header) {
+		utils.ReturnFormattedData(ctx, http.StatusOK, "Event is ignored", nil)
+		return
+	}
+	event := &pushEvent{}
+	if err := json.Unmarshal(body, event); err != nil {
+		klog.Errorf("Failed to unmarshal push event: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusBadRequest, "Invalid push event", nil)
+		return
+	}
+
+	registries, err := openappHelper.RegistryLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("Failed to list registries: %v", err)
+		utils.ReturnFormattedData(ctx, http.StatusInternalServerError, "Failed to list registries", nil)
+		return
+	}
+	matched, triggered := 0, []string{}
+	for _, registry := range registries {
+		if !isPushedRegistry(registry, event) {
+			continue
+		}
+		matched++
+		secret, err := openappHelper.K8sClient.CoreV1().Secrets(utils.SystemNamespace).
+			Get(context.Background(), registry.Spec.WebhookSecretName, metav1.GetOptions{})
+		if err != nil {
+			klog.Errorf("Failed to get webhook secret of registry(%s): %v", registry.Name, err)
+			continue
+		}
+		if err := validateWebhook(ctx.Request.Header, body, secret.Data[registryWebhookSecretKey]); err != nil {
+			klog.Warningf("Refused webhook of registry(%s): %v", registry.Name, err)
+			continue
+		}
+
+		patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`,
+			utils.RegistrySyncRequestedAnnotationKey, time.Now().UTC().Format(time.RFC3339Nano))
+		_, err = openappHelper.OpenAPPClient.RegistryV1alpha1().Registries().Patch(context.Background(),
+			registry.Name, pkgtypes.MergePatchType, []byte(patch), metav1.PatchOptions{})
+		if err != nil {
+			klog.Errorf("Failed to request sync of registry(%s): %v", registry.Name, err)
+			utils.ReturnFormattedData(ctx, http.StatusInternalServerError, "Failed to request registry sync", nil)
+			return
+		}
+		klog.Infof("Registry(%s) sync is requested by %s", registry.Name, event.Ref)
+		triggered = append(triggered, registry.Name)
+	}
+
+	// The unmatched repository is refused as the invalid signature, so the
+	// repositories of the registries can't be probed by the webhook.
+	if len(triggered) == 0 {
+		if matched == 0 {
+			klog.Warningf("Refused webhook, no registry matches the repository of %s", event.Ref)
+		}
+		utils.ReturnFormattedData(ctx, http.StatusUnauthorized, "Failed to validate webhook", nil)
+		return
+	}
+	utils.ReturnFormattedData(ctx, http.StatusOK, "Registry sync requested", triggered)
+}
+
+func isPushEvent(header http.Header) bool {
+	switch {
+	case header.Get("X-GitHub-Event") != "":
+		return header.Get("X-GitHub-Event") == "push"
+	case header.Get("X-Gitea-Event") != "":
+		return header.Get("X-Gitea-Event") == "push"
+	case header.Get("X-Gitlab-Event") != "":
+		return header.Get("X-Gitlab-Event") == "Push Hook" || header.Get("X-Gitlab-Event") == "Tag Push Hook"
+	}
+	return false
+}
+
+// isPushedRegistry reports whether the registry has the webhook enabled and
+// checks out the pushed repository and ref.
+func isPushedRegistry(registry *registryv1alpha1.Registry, event *pushEvent) bool {
+	if registry.Spec.WebhookSecretName == "" ||
+		(registry.Spec.Type != "" && registry.Spec.Type != registryv1alpha1.RegistrySourceGit) {
+		return false
+	}
+	// The registry following the default branch is synced by any push
+	if registry.Spec.Ref != "" && event.Ref != "refs/heads/"+registry.Spec.Ref &&
+		event.Ref != "refs/tags/"+registry.Spec.Ref {
+		return false
+	}
+	registryURL := normalizeRepoURL(registry.Spec.URL)
+	for _, u := range event.repoURLs() {
+		if normalizeRepoURL(u) == registryURL {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeRepoURL returns the host and path of the repository url, so the
+// http, ssh and web urls of the same repository are equal.
+func normalizeRepoURL(repoURL string) string {
+	repoURL = strings.TrimSpace(repoURL)
+	host, repoPath := "", ""
+	if u, err := url.Parse(repoURL); err == nil && u.Host != "" {
+		host, repoPath = u.Hostname(), u.Path
+	} else {
+		// The scp-like ssh url, e.g. git@github.com:openapp-dev/openapp-registry.git
+		hostPart, p, found := strings.Cut(repoURL, ":")
+		if !found {
+			return repoURL
+		}
+		if i := strings.LastIndex(hostPart, "@"); i >= 0 {
+			hostPart = hostPart[i+1:]
+		}
+		host, repoPath = hostPart, p
+	}
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	return strings.ToLower(host + "/" + repoPath)
+}
+
+// validateWebhook validates the HMAC-SHA256 signature of GitHub and Gitea, or
+// the secret token of GitLab.
+func validateWebhook(header http.Header, body, secret []byte) error {
+	if len(secret) == 0 {
+		return fmt.Errorf("webhook secret is empty")
+	}
+	if token := header.Get("X-Gitlab-Token"); token != "" {
+		if !hmac.Equal([]byte(token), secret) {
+			return fmt.Errorf("token mismatch")
+		}
+		return nil
+	}
+
+	signature := strings.TrimPrefix(header.Get("X-Hub-Signature-256"), "sha256=")
+	if signature == "" {
+		signature = header.Get("X-Gitea-Signature")
+	}
+	if signature == "" {
+		return fmt.Errorf("signature is missing")
+	}
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %v", err)
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/gin-gonic/gin"
+)
+
+type upgradeRequest struct {
+	// Version is the target template version, the latest one is used if empty.
+	Version string `json:"version"`
+}
+
+func getUpgradeRequest(ctx *gin.Context) (*upgradeRequest, error) {
+	req := &upgradeRequest{}
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) == 0 {
+		return req, nil
+	}
+	if err := json.Unmarshal(body, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
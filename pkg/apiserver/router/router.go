@@ -23,6 +23,8 @@ func NewOpenAPPServerRouter(k8sClient kubernetes.Interface,
 	openappClient versioned.Interface,
 	openappHelper *utils.OpenAPPHelper) *gin.Engine {
 	router := gin.New()
+	// The template reference `registry/template` is escaped in the path
+	router.UseRawPath = true
 
 	config := cors.DefaultConfig()
 	config.AllowOrigins = []string{"*"}
@@ -35,6 +37,8 @@ func NewOpenAPPServerRouter(k8sClient kubernetes.Interface,
 	// version/login API don't need authorization, put it in the first place
 	initVersionRouter(router, corsHandler)
 	initLoginRouter(router, corsHandler)
+	// The registry webhooks are validated by the webhook secrets
+	initRegistryWebhookRouter(router, corsHandler)
 
 	// middleware
 	router.Use(utils.JWTAuth(openappHelper.ConfigMapLister))
@@ -50,12 +54,17 @@ func initAPPRouter(router *gin.Engine, corsHandler gin.HandlerFunc) {
 	appGroup := router.Group("/api/v1/apps")
 	appGroup.GET("/templates", handler.ListAllAppTemplatesHandler)
 	appGroup.GET("/templates/:templateName", handler.GetAppTemplateHandler)
+	appGroup.GET("/templates/:templateName/schema", handler.GetAppTemplateInputSchemaHandler)
 
 	appGroup.GET("/instances", handler.ListAllAppInstancesHandler)
 	appGroup.GET("/instances/:instanceName", handler.GetAppInstanceHandler)
 	appGroup.POST("/instances/:instanceName", handler.CreateOrUpdateAppInstanceHandler)
 	appGroup.DELETE("/instances/:instanceName", handler.DeleteAppInstanceHandler)
+	appGroup.POST("/instances/:instanceName/upgrade", handler.UpgradeAppInstanceHandler)
+	appGroup.GET("/instances/:instanceName/revisions", handler.ListAppInstanceRevisionsHandler)
+	appGroup.POST("/instances/:instanceName/rollback", handler.RollbackAppInstanceHandler)
 	appGroup.GET("/instances/:instanceName/log", handler.AppInstanceLoggingHandler)
+	appGroup.GET("/instances/:instanceName/events", handler.AppInstanceEventsHandler)
 	appGroup.Use(corsHandler)
 }
 
@@ -63,12 +72,15 @@ func initPublicServiceRouter(router *gin.Engine, corsHandler gin.HandlerFunc) {
 	publicServiceGroup := router.Group("/api/v1/publicservices")
 	publicServiceGroup.GET("/templates", handler.ListAllPublicServiceTemplatesHandler)
 	publicServiceGroup.GET("/templates/:templateName", handler.GetPublicServiceTemplateHandler)
+	publicServiceGroup.GET("/templates/:templateName/schema", handler.GetPublicServiceTemplateInputSchemaHandler)
 
 	publicServiceGroup.GET("/instances", handler.ListAllPublicServiceInstancesHandler)
 	publicServiceGroup.GET("/instances/:instanceName", handler.GetPublicServiceInstanceHandler)
 	publicServiceGroup.POST("/instances/:instanceName", handler.CreateOrUpdatePublicServiceInstanceHandler)
 	publicServiceGroup.DELETE("/instances/:instanceName", handler.DeletePublicServiceInstanceHandler)
+	publicServiceGroup.POST("/instances/:instanceName/upgrade", handler.UpgradePublicServiceInstanceHandler)
 	publicServiceGroup.GET("/instances/:instanceName/log", handler.PublicServiceInstanceLoggingHandler)
+	publicServiceGroup.GET("/instances/:instanceName/events", handler.PublicServiceInstanceEventsHandler)
 	publicServiceGroup.Use(corsHandler)
 }
 
@@ -90,3 +102,9 @@ func initLoginRouter(router *gin.Engine, corsHandler gin.HandlerFunc) {
 	loginGroup.POST("", handler.LoginHandler)
 	loginGroup.Use(corsHandler)
 }
+
+func initRegistryWebhookRouter(router *gin.Engine, corsHandler gin.HandlerFunc) {
+	registryGroup := router.Group("/api/v1/registries")
+	registryGroup.POST("/webhook", handler.RegistryWebhookHandler)
+	registryGroup.Use(corsHandler)
+}
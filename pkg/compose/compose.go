@@ -0,0 +1,292 @@
+package compose
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// Project is the subset of the compose file which can be converted into the
+// manifests of a template.
+type Project struct {
+	Services map[string]*Service   `json:"services"`
+	Volumes  map[string]*VolumeDef `json:"volumes,omitempty"`
+}
+
+type Service struct {
+	Image       string       `json:"image,omitempty"`
+	Build       interface{}  `json:"build,omitempty"`
+	Entrypoint  ShellCommand `json:"entrypoint,omitempty"`
+	Command     ShellCommand `json:"command,omitempty"`
+	Environment Environment  `json:"environment,omitempty"`
+	Ports       []Port       `json:"ports,omitempty"`
+	Expose      []Port       `json:"expose,omitempty"`
+	Volumes     []Volume     `json:"volumes,omitempty"`
+	WorkingDir  string       `json:"working_dir,omitempty"`
+	User        string       `json:"user,omitempty"`
+
+	// unknownKeys are the keys of the service which are not converted
+	unknownKeys []string
+}
+
+type VolumeDef struct {
+	External bool `json:"external,omitempty"`
+}
+
+// ShellCommand is a command in either the list form or the string form, the
+// latter is split like the shell does.
+type ShellCommand []string
+
+func (c *ShellCommand) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		words, err := splitCommand(s)
+		*c = words
+		return err
+	}
+	var l []string
+	if err := json.Unmarshal(data, &l); err != nil {
+		return fmt.Errorf("must be a string or a list of strings")
+	}
+	*c = l
+	return nil
+}
+
+// Environment is the environment variables in either the map form or the list
+// form of "NAME=VALUE". The value is nil if it's taken from the environment of
+// the host, e.g. "NAME" in the list form.
+type Environment map[string]*string
+
+func (e *Environment) UnmarshalJSON(data []byte) error {
+	*e = Environment{}
+	var l []string
+	if err := json.Unmarshal(data, &l); err == nil {
+		for _, item := range l {
+			name, value, ok := strings.Cut(item, "=")
+			if !ok {
+				(*e)[name] = nil
+				continue
+			}
+			(*e)[name] = &value
+		}
+		return nil
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("must be a map or a list of NAME=VALUE")
+	}
+	for name, value := range m {
+		if value == nil {
+			(*e)[name] = nil
+			continue
+		}
+		s := fmt.Sprint(value)
+		(*e)[name] = &s
+	}
+	return nil
+}
+
+// Port is a port in the short form of "[[HOST:]PUBLISHED:]TARGET[/PROTOCOL]"
+// or the long form. The host IP is ignored.
+type Port struct {
+	Target    string `json:"target"`
+	Published string `json:"published,omitempty"`
+	Protocol  string `json:"protocol,omitempty"`
+}
+
+func (p *Port) UnmarshalJSON(data []byte) error {
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err == nil {
+		p.Target = n.String()
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		// The ports are resolved before they are split, e.g. "${PORT:-8080}:80"
+		resolved, err := resolveDefaults(s)
+		if err != nil {
+			return err
+		}
+		resolved, p.Protocol, _ = strings.Cut(resolved, "/")
+		fields := strings.Split(resolved, ":")
+		p.Target = fields[len(fields)-1]
+		if len(fields) > 1 {
+			p.Published = fields[len(fields)-2]
+		}
+		return nil
+	}
+	long := struct {
+		Target    json.Number `json:"target"`
+		Published interface{} `json:"published"`
+		Protocol  string      `json:"protocol"`
+	}{}
+	if err := json.Unmarshal(data, &long); err != nil {
+		return fmt.Errorf("must be a port number, a string or a map of target, published and protocol")
+	}
+	p.Target, p.Protocol = long.Target.String(), long.Protocol
+	if long.Published != nil {
+		p.Published = fmt.Sprint(long.Published)
+	}
+	return nil
+}
+
+// Volume is a volume in the short form of "[SOURCE:]TARGET[:MODE]" or the long
+// form, the type of the short form is bind if the source is a path, otherwise
+// it's volume, which is anonymous if there is no source.
+type Volume struct {
+	Type     string `json:"type"`
+	Source   string `json:"source,omitempty"`
+	Target   string `json:"target"`
+	ReadOnly bool   `json:"read_only,omitempty"`
+}
+
+const (
+	volumeTypeVolume = "volume"
+	volumeTypeBind   = "bind"
+	volumeTypeTmpfs  = "tmpfs"
+)
+
+func (v *Volume) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		type volume Volume
+		return json.Unmarshal(data, (*volume)(v))
+	}
+	// The volumes are resolved before they are split, e.g. "${DATA:-./data}:/data"
+	s, err := resolveDefaults(s)
+	if err != nil {
+		return err
+	}
+	fields := strings.Split(s, ":")
+	switch len(fields) {
+	case 1:
+		v.Target = fields[0]
+	case 2:
+		if strings.HasPrefix(fields[1], "/") {
+			v.Source, v.Target = fields[0], fields[1]
+		} else {
+			v.Target, v.ReadOnly = fields[0], fields[1] == "ro"
+		}
+	default:
+		v.Source, v.Target = fields[0], fields[1]
+		v.ReadOnly = strings.Contains(","+fields[2]+",", ",ro,")
+	}
+	v.Type = volumeType(v.Source)
+	return nil
+}
+
+// volumeType returns the type of the volume by the source like compose does.
+func volumeType(source string) string {
+	if strings.HasPrefix(source, ".") || strings.HasPrefix(source, "/") || strings.HasPrefix(source, "~") {
+		return volumeTypeBind
+	}
+	return volumeTypeVolume
+}
+
+// knownServiceKeys are the keys of the service that are converted or don't
+// matter once the services run in the same pod.
+var knownServiceKeys = map[string]bool{
+	"image": true, "build": true, "entrypoint": true, "command": true, "environment": true,
+	"ports": true, "expose": true, "volumes": true, "working_dir": true, "user": true,
+	"container_name": true, "restart": true, "depends_on": true, "networks": true,
+	"labels": true, "logging": true, "stdin_open": true, "tty": true,
+}
+
+// Load parses the compose file, the unsupported fields of the services are
+// reported by Convert.
+func Load(content []byte) (*Project, error) {
+	p := &Project{}
+	if err := yaml.Unmarshal(content, p); err != nil {
+		return nil, fmt.Errorf("invalid compose file: %v", err)
+	}
+	if len(p.Services) == 0 {
+		return nil, fmt.Errorf("no service found in the compose file")
+	}
+
+	raw := struct {
+		Services map[string]map[string]interface{} `json:"services"`
+	}{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("invalid compose file: %v", err)
+	}
+	for name, svc := range p.Services {
+		if svc == nil {
+			return nil, fmt.Errorf("service %s is empty", name)
+		}
+		for key := range raw.Services[name] {
+			if !knownServiceKeys[key] {
+				svc.unknownKeys = append(svc.unknownKeys, key)
+			}
+		}
+		sort.Strings(svc.unknownKeys)
+	}
+	return p, nil
+}
+
+// serviceNames returns the names of the services in order.
+func (p *Project) serviceNames() []string {
+	ret := []string{}
+	for name := range p.Services {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// splitCommand splits the command into words like the shell does, the quotes
+// and backslashes are removed.
+func splitCommand(s string) ([]string, error) {
+	ret := []string{}
+	word := strings.Builder{}
+	inWord := false
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != '\'' && c == '\\' && i+1 < len(s):
+			i++
+			word.WriteByte(s[i])
+			inWord = true
+		case quote != 0:
+			word.WriteByte(c)
+		case c == '\'' || c == '"':
+			quote = c
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				ret = append(ret, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unclosed quote in command %q", s)
+	}
+	if inWord {
+		ret = append(ret, word.String())
+	}
+	return ret, nil
+}
+
+// parsePort resolves the port number with the default values of the variables.
+func parsePort(s string) (int, error) {
+	resolved, err := resolveDefaults(s)
+	if err != nil {
+		return 0, err
+	}
+	port, err := strconv.Atoi(resolved)
+	if err != nil || port <= 0 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return port, nil
+}
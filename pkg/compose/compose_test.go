@@ -0,0 +1,121 @@
+package compose
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testComposeFile = `
+services:
+  db:
+    image: postgres:16
+    environment:
+      POSTGRES_USER: ${DB_USER:-app}
+      POSTGRES_PASSWORD: ${DB_PASSWORD:?password is required}
+    volumes:
+      - db-data:/var/lib/postgresql/data
+    healthcheck:
+      test: ["CMD", "pg_isready"]
+  web:
+    image: example/web:${VERSION-latest}
+    command: serve --listen ":3000"
+    ports:
+      - "${WEB_PORT:-8080}:3000"
+    environment:
+      - DATABASE_URL=postgres://${DB_USER}:${DB_PASSWORD}@db/app
+      - GREETING=100% {{ raw }} $$HOME
+    volumes:
+      - ./uploads:/app/uploads:ro
+volumes:
+  db-data:
+`
+
+func TestParseInterpolation(t *testing.T) {
+	parts, err := parseInterpolation("a$$b-$NAME-${X:-d}-${Y?msg}$")
+	assert.NoError(t, err)
+	d := "d"
+	assert.Equal(t, []part{
+		{literal: "a$b-"},
+		{variable: &Variable{Name: "NAME"}},
+		{literal: "-"},
+		{variable: &Variable{Name: "X", Default: &d}},
+		{literal: "-"},
+		{variable: &Variable{Name: "Y", Required: true, Message: "msg"}},
+		{literal: "$"},
+	}, parts)
+
+	_, err = parseInterpolation("${X")
+	assert.Error(t, err)
+	_, err = parseInterpolation("${X:+alt}")
+	assert.Error(t, err)
+}
+
+func TestConvert(t *testing.T) {
+	p, err := Load([]byte(testComposeFile))
+	assert.NoError(t, err)
+	result, err := Convert(p)
+	assert.NoError(t, err)
+
+	manifests := map[string]string{}
+	for _, m := range result.Manifests {
+		manifests[m.Name] = string(m.Content)
+	}
+	assert.Contains(t, manifests["configmap.yaml"], `POSTGRES_PASSWORD: {{ .inputs.DB_PASSWORD | default "" | quote }}`)
+	assert.Contains(t, manifests["configmap.yaml"],
+		`DATABASE_URL: {{ printf "postgres://%v:%v@db/app" (.inputs.DB_USER | default "") (.inputs.DB_PASSWORD | default "") | quote }}`)
+	assert.Contains(t, manifests["configmap.yaml"], `GREETING: {{ "100% {{ raw }} $HOME" | quote }}`)
+	assert.Contains(t, manifests["persistentvolumeclaim.yaml"], "name: {{ .openapp.instance_name }}-db-data")
+	assert.Contains(t, manifests["persistentvolumeclaim.yaml"], "name: {{ .openapp.instance_name }}-uploads")
+	assert.Contains(t, manifests["statefulset.yaml"], `image: {{ printf "example/web:%v" (.inputs.VERSION | default "latest") | quote }}`)
+	assert.Contains(t, manifests["statefulset.yaml"], "- --listen\n        - :3000")
+	assert.Contains(t, manifests["statefulset.yaml"], "readOnly: true")
+	assert.Contains(t, manifests["service.yaml"], "port: 8080")
+	assert.Contains(t, manifests["service.yaml"], "targetPort: 3000")
+
+	assert.Equal(t, []string{"DB_PASSWORD"}, result.InputSchema.Required)
+	assert.True(t, result.InputSchema.Properties["DB_PASSWORD"].Secret)
+	assert.Equal(t, `"app"`, string(result.InputSchema.Properties["DB_USER"].Default.Raw))
+	assert.Equal(t, `"latest"`, string(result.InputSchema.Properties["VERSION"].Default.Raw))
+	assert.NotContains(t, result.InputSchema.Properties, "WEB_PORT")
+	assert.Contains(t, result.Inputs, "DB_USER: app")
+	assert.Contains(t, strings.Join(result.Warnings, "\n"), "services.db.healthcheck is not supported")
+	assert.Contains(t, strings.Join(result.Warnings, "\n"), "bind mount ./uploads")
+}
+
+func TestConvertErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		compose  string
+		expected string
+	}{
+		{
+			name:     "no service",
+			compose:  "volumes:\n  data:\n",
+			expected: "no service found",
+		},
+		{
+			name:     "build",
+			compose:  "services:\n  web:\n    build: .\n",
+			expected: "build is not supported",
+		},
+		{
+			name:     "conflicted ports",
+			compose:  "services:\n  a:\n    image: a\n    ports: [80]\n  b:\n    image: b\n    expose: [80]\n",
+			expected: "port 80/TCP is also used by service a",
+		},
+		{
+			name:     "port range",
+			compose:  "services:\n  a:\n    image: a\n    ports: [\"8000-8010:8000-8010\"]\n",
+			expected: "invalid port",
+		},
+	}
+	for _, c := range cases {
+		p, err := Load([]byte(c.compose))
+		if err == nil {
+			_, err = Convert(p)
+		}
+		assert.ErrorContains(t, err, c.expected, c.name)
+	}
+}
@@ -0,0 +1,592 @@
+package compose
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
+)
+
+const (
+	// DefaultVolumeSize is the storage requested by the PVCs converted from the
+	// named volumes and the bind mounts.
+	DefaultVolumeSize = "1Gi"
+
+	instanceNameExpr = "{{ .openapp.instance_name }}"
+	// The service is exposed by the public service class if it's set
+	serviceTypeExpr  = "{{ if .openapp.service_class }}LoadBalancer{{ else }}NodePort{{ end }}"
+	selectorLabelKey = "app"
+)
+
+// secretVariablePattern matches the names of the variables which are marked as
+// secret inputs, e.g. DB_PASSWORD and API_TOKEN.
+var secretVariablePattern = regexp.MustCompile(`(?i)(^|_)(PASSWORD|PASSWD|PASS|SECRET|TOKEN|KEY|APIKEY)($|_)`)
+
+// Manifest is a manifest file of the converted template.
+type Manifest struct {
+	Name    string
+	Content []byte
+}
+
+// Result is the template converted from the compose file.
+type Result struct {
+	// Manifests are the template manifests, which are rendered with the
+	// instance values like the manifests of the registry templates.
+	Manifests []Manifest
+	// InputSchema declares an input for each variable of the compose file.
+	InputSchema *commonv1alpha1.InputSchema
+	// Inputs is the sample inputs with the default values of the variables.
+	Inputs string
+	// Warnings are the parts of the compose file which are ignored or
+	// converted differently from how compose runs them.
+	Warnings []string
+}
+
+type converter struct {
+	project *Project
+	// tokens are the placeholders of the template expressions in the objects,
+	// they are replaced once the objects are marshaled.
+	tokens        map[string]string
+	exprs         map[string]string
+	variables     map[string]*Variable
+	variableNames []string
+	warnings      []string
+
+	podVolumes  []interface{}
+	volumeNames map[string]string
+	pvcs        []interface{}
+}
+
+// Convert converts the compose file into the manifests of a template. All the
+// services run as the containers of a StatefulSet, so they still reach each
+// other by the service names, which are resolved to the localhost:
+//
+//   - the environment of each service is a ConfigMap;
+//   - the named volumes and the bind mounts are PVCs, the anonymous volumes
+//     and tmpfs are emptyDirs;
+//   - the published ports are the ports of a Service, which is exposed by the
+//     public service class of the instance.
+//
+// The variables are inputs of the template with the same names, the required
+// variables are the required inputs. The variables of the ports, volumes and
+// user are resolved with the default values since they are not inputs.
+func Convert(p *Project) (*Result, error) {
+	c := &converter{
+		project:     p,
+		tokens:      map[string]string{},
+		exprs:       map[string]string{},
+		variables:   map[string]*Variable{},
+		volumeNames: map[string]string{},
+	}
+
+	containers := []interface{}{}
+	configMaps := []interface{}{}
+	servicePorts := []interface{}{}
+	containerPorts := map[string]string{}
+	published := map[string]bool{}
+	hostnames := []string{}
+	for _, name := range p.serviceNames() {
+		svc := p.Services[name]
+		containerName := sanitizeName(name)
+		if errs := validation.IsDNS1123Label(containerName); len(errs) != 0 {
+			return nil, fmt.Errorf("invalid service name %s: %s", name, strings.Join(errs, ", "))
+		}
+		for _, key := range svc.unknownKeys {
+			c.warn("services.%s.%s is not supported and ignored", name, key)
+		}
+
+		if svc.Image == "" {
+			if svc.Build != nil {
+				return nil, fmt.Errorf("service %s: build is not supported, an image is required", name)
+			}
+			return nil, fmt.Errorf("service %s: image is required", name)
+		}
+		image, err := c.value(svc.Image)
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %v", name, err)
+		}
+		container := map[string]interface{}{"name": containerName, "image": image}
+		if len(svc.Entrypoint) != 0 {
+			if container["command"], err = c.values(svc.Entrypoint); err != nil {
+				return nil, fmt.Errorf("service %s: %v", name, err)
+			}
+		}
+		if len(svc.Command) != 0 {
+			if container["args"], err = c.values(svc.Command); err != nil {
+				return nil, fmt.Errorf("service %s: %v", name, err)
+			}
+		}
+		if svc.WorkingDir != "" {
+			if container["workingDir"], err = c.value(svc.WorkingDir); err != nil {
+				return nil, fmt.Errorf("service %s: %v", name, err)
+			}
+		}
+		if svc.User != "" {
+			securityContext, err := convertUser(svc.User)
+			if err != nil {
+				c.warn("services.%s.user is ignored: %v", name, err)
+			} else {
+				container["securityContext"] = securityContext
+			}
+		}
+
+		if len(svc.Environment) != 0 {
+			data := map[string]interface{}{}
+			for _, envName := range sortedEnvNames(svc.Environment) {
+				if errs := validation.IsEnvVarName(envName); len(errs) != 0 {
+					return nil, fmt.Errorf("service %s: invalid environment %s: %s",
+						name, envName, strings.Join(errs, ", "))
+				}
+				// The environment without value is taken from the variable of the same name
+				raw := "${" + envName + "}"
+				if v := svc.Environment[envName]; v != nil {
+					raw = *v
+				}
+				if data[envName], err = c.value(raw); err != nil {
+					return nil, fmt.Errorf("service %s: %v", name, err)
+				}
+			}
+			configMapName := c.name(containerName)
+			configMaps = append(configMaps, map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]interface{}{"name": configMapName},
+				"data":       data,
+			})
+			container["envFrom"] = []interface{}{
+				map[string]interface{}{"configMapRef": map[string]interface{}{"name": configMapName}},
+			}
+		}
+
+		ports := []interface{}{}
+		for i, port := range append(append([]Port{}, svc.Ports...), svc.Expose...) {
+			target, err := parsePort(port.Target)
+			if err != nil {
+				return nil, fmt.Errorf("service %s: %v", name, err)
+			}
+			protocol := strings.ToUpper(port.Protocol)
+			if protocol == "" {
+				protocol = "TCP"
+			}
+			if protocol != "TCP" && protocol != "UDP" && protocol != "SCTP" {
+				return nil, fmt.Errorf("service %s: unsupported protocol %s", name, port.Protocol)
+			}
+			key := fmt.Sprintf("%d/%s", target, protocol)
+			other, ok := containerPorts[key]
+			if ok && other != name {
+				return nil, fmt.Errorf("service %s: port %s is also used by service %s, the services share the network",
+					name, key, other)
+			}
+			if !ok {
+				containerPorts[key] = name
+				ports = append(ports, map[string]interface{}{"containerPort": target, "protocol": protocol})
+			}
+			// The exposed ports are only reachable by the other services
+			if i >= len(svc.Ports) {
+				continue
+			}
+			servicePort := target
+			if port.Published != "" {
+				if servicePort, err = parsePort(port.Published); err != nil {
+					return nil, fmt.Errorf("service %s: %v", name, err)
+				}
+			}
+			publishedKey := fmt.Sprintf("%d/%s", servicePort, protocol)
+			if published[publishedKey] {
+				return nil, fmt.Errorf("service %s: port %s is published more than once", name, publishedKey)
+			}
+			published[publishedKey] = true
+			servicePorts = append(servicePorts, map[string]interface{}{
+				"name":       fmt.Sprintf("%s-%d", strings.ToLower(protocol), servicePort),
+				"port":       servicePort,
+				"targetPort": target,
+				"protocol":   protocol,
+			})
+		}
+		if len(ports) != 0 {
+			container["ports"] = ports
+		}
+
+		mounts := []interface{}{}
+		for _, v := range svc.Volumes {
+			mount, err := c.volumeMount(name, v)
+			if err != nil {
+				return nil, fmt.Errorf("service %s: %v", name, err)
+			}
+			mounts = append(mounts, mount)
+		}
+		if len(mounts) != 0 {
+			container["volumeMounts"] = mounts
+		}
+
+		containers = append(containers, container)
+		hostnames = append(hostnames, name)
+	}
+
+	instanceName := c.token(instanceNameExpr)
+	labels := map[string]interface{}{selectorLabelKey: instanceName}
+	podSpec := map[string]interface{}{
+		"hostAliases": []interface{}{map[string]interface{}{"ip": "127.0.0.1", "hostnames": hostnames}},
+		"containers":  containers,
+	}
+	if len(c.podVolumes) != 0 {
+		podSpec["volumes"] = c.podVolumes
+	}
+	statefulSet := map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "StatefulSet",
+		"metadata":   map[string]interface{}{"name": instanceName},
+		"spec": map[string]interface{}{
+			"replicas":    1,
+			"serviceName": instanceName,
+			"selector":    map[string]interface{}{"matchLabels": labels},
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": labels},
+				"spec":     podSpec,
+			},
+		},
+	}
+
+	ret := &Result{}
+	for _, m := range []struct {
+		name string
+		objs []interface{}
+	}{
+		{name: "configmap.yaml", objs: configMaps},
+		{name: "persistentvolumeclaim.yaml", objs: c.pvcs},
+		{name: "statefulset.yaml", objs: []interface{}{statefulSet}},
+	} {
+		if len(m.objs) == 0 {
+			continue
+		}
+		content, err := c.marshal(m.objs)
+		if err != nil {
+			return nil, err
+		}
+		ret.Manifests = append(ret.Manifests, Manifest{Name: m.name, Content: content})
+	}
+	if len(servicePorts) != 0 {
+		content, err := c.marshal([]interface{}{map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata":   map[string]interface{}{"name": instanceName},
+			"spec": map[string]interface{}{
+				"type":     c.token(serviceTypeExpr),
+				"selector": labels,
+				"ports":    servicePorts,
+			},
+		}})
+		if err != nil {
+			return nil, err
+		}
+		ret.Manifests = append(ret.Manifests, Manifest{Name: "service.yaml", Content: content})
+	} else {
+		c.warn("no port is published, the app is not exposed")
+	}
+
+	var err error
+	ret.InputSchema, ret.Inputs, err = c.inputs()
+	if err != nil {
+		return nil, err
+	}
+	ret.Warnings = c.warnings
+	return ret, nil
+}
+
+// volumeMount converts the volume of the service into a volume of the pod,
+// the volumes of the same source are shared by the services.
+func (c *converter) volumeMount(service string, v Volume) (map[string]interface{}, error) {
+	source, err := resolveDefaults(v.Source)
+	if err != nil {
+		return nil, err
+	}
+	target, err := resolveDefaults(v.Target)
+	if err != nil {
+		return nil, err
+	}
+	if !path.IsAbs(target) {
+		return nil, fmt.Errorf("volume target %q must be an absolute path", v.Target)
+	}
+	if v.Type == "" {
+		v.Type = volumeType(source)
+	}
+	mount := map[string]interface{}{"mountPath": target}
+	if v.ReadOnly {
+		mount["readOnly"] = true
+	}
+
+	key := v.Type + ":" + source
+	if source == "" {
+		// The anonymous volumes are never shared
+		key = v.Type + ":" + service + ":" + target
+	}
+	if name, ok := c.volumeNames[key]; ok {
+		mount["name"] = name
+		return mount, nil
+	}
+
+	name := c.volumeName(source, target)
+	var podVolume map[string]interface{}
+	switch {
+	case v.Type == volumeTypeTmpfs:
+		podVolume = map[string]interface{}{"name": name, "emptyDir": map[string]interface{}{"medium": "Memory"}}
+	case v.Type == volumeTypeVolume && source == "":
+		podVolume = map[string]interface{}{"name": name, "emptyDir": map[string]interface{}{}}
+	case v.Type == volumeTypeVolume || v.Type == volumeTypeBind:
+		if v.Type == volumeTypeBind {
+			c.warn("bind mount %s of service %s is converted to a PVC, its content is not copied", source, service)
+		} else if def := c.project.Volumes[source]; def != nil && def.External {
+			c.warn("external volume %s is converted to a PVC", source)
+		}
+		claimName := c.name(name)
+		c.pvcs = append(c.pvcs, map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "PersistentVolumeClaim",
+			"metadata":   map[string]interface{}{"name": claimName},
+			"spec": map[string]interface{}{
+				"accessModes": []interface{}{"ReadWriteOnce"},
+				"resources": map[string]interface{}{
+					"requests": map[string]interface{}{"storage": DefaultVolumeSize},
+				},
+			},
+		})
+		podVolume = map[string]interface{}{
+			"name":                  name,
+			"persistentVolumeClaim": map[string]interface{}{"claimName": claimName},
+		}
+	default:
+		return nil, fmt.Errorf("unsupported volume type %s", v.Type)
+	}
+	c.podVolumes = append(c.podVolumes, podVolume)
+	c.volumeNames[key] = name
+	mount["name"] = name
+	return mount, nil
+}
+
+// volumeName returns a unique name of the pod volume after the source, or the
+// target if the volume is anonymous.
+func (c *converter) volumeName(source, target string) string {
+	base := sanitizeName(path.Base(source))
+	if source == "" || base == "" {
+		base = sanitizeName(path.Base(target))
+	}
+	if base == "" {
+		base = "volume"
+	}
+	used := map[string]bool{}
+	for _, name := range c.volumeNames {
+		used[name] = true
+	}
+	name := base
+	for i := 2; used[name]; i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	return name
+}
+
+// value converts the string of the compose file into the template expression
+// of the variables, the string is kept as is if there is no variable.
+func (c *converter) value(s string) (string, error) {
+	parts, err := parseInterpolation(s)
+	if err != nil {
+		return "", err
+	}
+	literal := strings.Builder{}
+	format := strings.Builder{}
+	args := []string{}
+	for _, p := range parts {
+		if p.variable == nil {
+			literal.WriteString(p.literal)
+			format.WriteString(strings.ReplaceAll(p.literal, "%", "%%"))
+			continue
+		}
+		c.addVariable(p.variable)
+		format.WriteString("%v")
+		args = append(args, inputPipeline(p.variable))
+	}
+
+	switch {
+	case len(args) == 0 && !strings.Contains(literal.String(), "{{"):
+		return literal.String(), nil
+	case len(args) == 0:
+		// The literal braces are not template actions
+		return c.token(fmt.Sprintf("{{ %s | quote }}", strconv.Quote(literal.String()))), nil
+	case len(parts) == 1:
+		return c.token(fmt.Sprintf("{{ %s | quote }}", args[0])), nil
+	}
+	return c.token(fmt.Sprintf("{{ printf %s (%s) | quote }}", strconv.Quote(format.String()),
+		strings.Join(args, ") ("))), nil
+}
+
+// inputPipeline returns the template pipeline of the variable, the missing
+// input is rendered with the default value of the variable or empty like
+// compose does.
+func inputPipeline(v *Variable) string {
+	defaultValue := ""
+	if v.Default != nil {
+		defaultValue = *v.Default
+	}
+	return fmt.Sprintf(".inputs.%s | default %s", v.Name, strconv.Quote(defaultValue))
+}
+
+func (c *converter) values(l []string) ([]interface{}, error) {
+	ret := []interface{}{}
+	for _, s := range l {
+		v, err := c.value(s)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, v)
+	}
+	return ret, nil
+}
+
+// name returns the name of the object prefixed with the instance name.
+func (c *converter) name(suffix string) string {
+	return c.token(instanceNameExpr) + "-" + suffix
+}
+
+// token returns the placeholder of the template expression, it's a plain YAML
+// scalar so it's not quoted when the objects are marshaled.
+func (c *converter) token(expr string) string {
+	if token, ok := c.exprs[expr]; ok {
+		return token
+	}
+	token := fmt.Sprintf("__openapp_expr_%d__", len(c.exprs))
+	c.exprs[expr] = token
+	c.tokens[token] = expr
+	return token
+}
+
+func (c *converter) marshal(objs []interface{}) ([]byte, error) {
+	docs := []string{}
+	for _, obj := range objs {
+		d, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, string(d))
+	}
+	content := strings.Join(docs, "---\n")
+	for token, expr := range c.tokens {
+		content = strings.ReplaceAll(content, token, expr)
+	}
+	return []byte(content), nil
+}
+
+func (c *converter) addVariable(v *Variable) {
+	existing, ok := c.variables[v.Name]
+	if !ok {
+		copied := *v
+		c.variables[v.Name] = &copied
+		c.variableNames = append(c.variableNames, v.Name)
+		return
+	}
+	if v.Required && !existing.Required {
+		existing.Required, existing.Message = true, v.Message
+	}
+	if v.Default == nil {
+		return
+	}
+	if existing.Default == nil {
+		existing.Default = v.Default
+	} else if *existing.Default != *v.Default {
+		c.warn("variable %s has different defaults %q and %q, the former is used",
+			v.Name, *existing.Default, *v.Default)
+	}
+}
+
+// inputs returns the input schema of the variables and the sample inputs. The
+// variables without default are empty unless they are required, which is how
+// compose treats the unset variables.
+func (c *converter) inputs() (*commonv1alpha1.InputSchema, string, error) {
+	if len(c.variables) == 0 {
+		return nil, "", nil
+	}
+	schema := &commonv1alpha1.InputSchema{Properties: map[string]commonv1alpha1.InputProperty{}}
+	inputs := map[string]interface{}{}
+	for _, name := range c.variableNames {
+		v := c.variables[name]
+		property := commonv1alpha1.InputProperty{
+			Type:        commonv1alpha1.InputTypeString,
+			Title:       name,
+			Description: v.Message,
+			Secret:      secretVariablePattern.MatchString(name),
+		}
+		defaultValue := ""
+		if v.Required {
+			schema.Required = append(schema.Required, name)
+		} else {
+			if v.Default != nil {
+				defaultValue = *v.Default
+			}
+			raw, err := json.Marshal(defaultValue)
+			if err != nil {
+				return nil, "", err
+			}
+			property.Default = &runtime.RawExtension{Raw: raw}
+		}
+		schema.Properties[name] = property
+		inputs[name] = defaultValue
+	}
+	d, err := yaml.Marshal(inputs)
+	if err != nil {
+		return nil, "", err
+	}
+	return schema, string(d), nil
+}
+
+func (c *converter) warn(format string, a ...interface{}) {
+	c.warnings = append(c.warnings, fmt.Sprintf(format, a...))
+}
+
+// convertUser converts the numeric "UID[:GID]" into the security context.
+func convertUser(user string) (map[string]interface{}, error) {
+	resolved, err := resolveDefaults(user)
+	if err != nil {
+		return nil, err
+	}
+	uid, gid, hasGroup := strings.Cut(resolved, ":")
+	ret := map[string]interface{}{}
+	id, err := strconv.ParseInt(uid, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("user %q is not numeric", user)
+	}
+	ret["runAsUser"] = id
+	if hasGroup {
+		if id, err = strconv.ParseInt(gid, 10, 64); err != nil {
+			return nil, fmt.Errorf("group of user %q is not numeric", user)
+		}
+		ret["runAsGroup"] = id
+	}
+	return ret, nil
+}
+
+// sanitizeName converts the name into a DNS label, e.g. "my_db" to "my-db".
+func sanitizeName(name string) string {
+	ret := []byte(strings.ToLower(name))
+	for i, c := range ret {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			ret[i] = '-'
+		}
+	}
+	return strings.Trim(string(ret), "-")
+}
+
+func sortedEnvNames(env Environment) []string {
+	ret := []string{}
+	for name := range env {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
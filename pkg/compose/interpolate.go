@@ -0,0 +1,123 @@
+package compose
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Variable is a variable referenced by the compose file in the forms of $NAME,
+// ${NAME}, ${NAME:-default}, ${NAME-default}, ${NAME:?message} or
+// ${NAME?message}.
+type Variable struct {
+	Name string
+	// Default is nil if the variable has no default value.
+	Default *string
+	// Required is set if the variable must be set, Message tells why.
+	Required bool
+	Message  string
+}
+
+// part is either a literal string or a variable of an interpolated string.
+type part struct {
+	literal  string
+	variable *Variable
+}
+
+// parseInterpolation splits the string into the literal parts and the variable
+// parts, "$$" is the escaped "$".
+func parseInterpolation(s string) ([]part, error) {
+	ret := []part{}
+	literal := strings.Builder{}
+	flush := func() {
+		if literal.Len() != 0 {
+			ret = append(ret, part{literal: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			literal.WriteByte(s[i])
+			continue
+		}
+		switch next := s[i+1]; {
+		case next == '$':
+			literal.WriteByte('$')
+			i++
+		case next == '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed variable in %q", s)
+			}
+			v, err := parseBracedVariable(s[i+2 : i+end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid variable in %q: %v", s, err)
+			}
+			flush()
+			ret = append(ret, part{variable: v})
+			i += end
+		case isNameStart(next):
+			end := i + 2
+			for end < len(s) && isNameChar(s[end]) {
+				end++
+			}
+			flush()
+			ret = append(ret, part{variable: &Variable{Name: s[i+1 : end]}})
+			i = end - 1
+		default:
+			literal.WriteByte('$')
+		}
+	}
+	flush()
+	return ret, nil
+}
+
+func parseBracedVariable(s string) (*Variable, error) {
+	end := 0
+	for end < len(s) && (isNameChar(s[end]) && (end != 0 || isNameStart(s[end]))) {
+		end++
+	}
+	if end == 0 {
+		return nil, fmt.Errorf("variable name is required")
+	}
+	v := &Variable{Name: s[:end]}
+	op := s[end:]
+	switch {
+	case op == "":
+	case strings.HasPrefix(op, ":-"), strings.HasPrefix(op, "-"):
+		d := strings.TrimPrefix(strings.TrimPrefix(op, ":"), "-")
+		v.Default = &d
+	case strings.HasPrefix(op, ":?"), strings.HasPrefix(op, "?"):
+		v.Required = true
+		v.Message = strings.TrimPrefix(strings.TrimPrefix(op, ":"), "?")
+	default:
+		return nil, fmt.Errorf("unsupported expression %q of variable %s", op, v.Name)
+	}
+	return v, nil
+}
+
+// resolveDefaults interpolates the string with the default values of the
+// variables, it's used for the fields that can't be changed by the inputs.
+func resolveDefaults(s string) (string, error) {
+	parts, err := parseInterpolation(s)
+	if err != nil {
+		return "", err
+	}
+	ret := strings.Builder{}
+	for _, p := range parts {
+		if p.variable == nil {
+			ret.WriteString(p.literal)
+		} else if p.variable.Default != nil {
+			ret.WriteString(*p.variable.Default)
+		}
+	}
+	return ret.String(), nil
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
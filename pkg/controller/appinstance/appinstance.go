@@ -2,15 +2,20 @@ package appinstance
 
 import (
 	"context"
-	"path"
+	"fmt"
 	"reflect"
 	"strconv"
 
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	pkgtypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
 
 	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
@@ -23,6 +28,10 @@ import (
 type AppInstanceController struct {
 	k8sClient     kubernetes.Interface
 	openappClient versioned.Interface
+	dynamicClient dynamic.Interface
+	restMapper    meta.RESTMapper
+	eventRecorder record.EventRecorder
+	lookup        utils.InstanceLookupFunc
 	workqueue     *utils.WorkQueue
 }
 
@@ -31,6 +40,11 @@ func NewAppInstanceController(openappHelper *utils.OpenAPPHelper) types.Controll
 	ac.workqueue = utils.NewWorkQueue(ac.Reconcile)
 	ac.openappClient = openappHelper.OpenAPPClient
 	ac.k8sClient = openappHelper.K8sClient
+	ac.dynamicClient = openappHelper.DynamicClient
+	ac.restMapper = openappHelper.RESTMapper
+	ac.eventRecorder = openappHelper.EventRecorder
+	ac.lookup = utils.NewInstanceLookupFunc(openappHelper.AppInstanceLister,
+		openappHelper.PublicServiceInstanceLister)
 
 	_, _ = openappHelper.AppInstanceInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
@@ -80,6 +94,10 @@ func (ac *AppInstanceController) Start() {
 	go ac.workqueue.Run()
 }
 
+func (ac *AppInstanceController) getAppTemplate(name string) (*appv1alpha1.AppTemplate, error) {
+	return ac.openappClient.AppV1alpha1().AppTemplates().Get(context.Background(), name, metav1.GetOptions{})
+}
+
 func (ac *AppInstanceController) Reconcile(resourceKey pkgtypes.NamespacedName) error {
 	klog.Infof("Reconciling app instance(%s)...", resourceKey)
 	appIns, err := ac.openappClient.AppV1alpha1().AppInstances(resourceKey.Namespace).
@@ -95,22 +113,160 @@ func (ac *AppInstanceController) Reconcile(resourceKey pkgtypes.NamespacedName)
 	if !appIns.DeletionTimestamp.IsZero() {
 		return ac.deleteAppInstanceResources(appIns)
 	}
+	if appIns.Spec.RollbackToRevision != 0 {
+		return ac.rollbackAppInstance(appIns)
+	}
 
 	appTemplate := appIns.Spec.AppTemplate
 	if appTemplate == "" {
 		klog.Errorf("AppTemplate is empty in AppInstance(%s/%s)", appIns.Namespace, appIns.Name)
+		ac.updateAppInstanceCondition(appIns, commonv1alpha1.ConditionTemplateResolved, false,
+			commonv1alpha1.ReasonTemplateNotSpecified, "AppTemplate is not specified")
 		return nil
 	}
-	derivedResoruce := []commonv1alpha1.DerivedResource{}
-	manifests := utils.FindAppTemplateResources(appTemplate)
-	// The last element is statefulset, we should recreate it
+	// Pin the instance to the latest version, so the registry pull won't change it
+	if appIns.Spec.TemplateVersion == "" {
+		appTemp, err := utils.GetAppTemplate(ac.getAppTemplate, appTemplate)
+		if err != nil && !apierrors.IsNotFound(err) {
+			klog.Errorf("Failed to get app template(%s): %v", appTemplate, err)
+			return err
+		}
+		if err == nil && appTemp.Spec.Version != "" {
+			appIns.Spec.TemplateVersion = appTemp.Spec.Version
+			_, err = ac.openappClient.AppV1alpha1().AppInstances(appIns.Namespace).
+				Update(context.Background(), appIns, metav1.UpdateOptions{})
+			if err != nil {
+				klog.Errorf("Failed to pin app instance template version: %v", err)
+				return err
+			}
+			return nil
+		}
+	}
+	templateVersion := appIns.Spec.TemplateVersion
+	registry, tempName := utils.ParseTemplateReference(appTemplate)
+	templateDir := utils.FindTemplateDir(registry, tempName, templateVersion, utils.AppTemplateBasePath)
+	var helmChart *appv1alpha1.HelmChart
+	composeFile := ""
+	manifests := []string{}
+	if templateDir != "" {
+		if helmChart, err = utils.LoadTemplateChart(templateDir); err != nil {
+			return err
+		}
+		if composeFile, err = utils.LoadTemplateCompose(templateDir); err != nil {
+			return err
+		}
+		if helmChart == nil && composeFile == "" {
+			manifests = utils.GetTemplateManifests(templateDir)
+		}
+	}
+	if helmChart == nil && composeFile == "" && len(manifests) == 0 {
+		ac.updateAppInstanceCondition(appIns, commonv1alpha1.ConditionTemplateResolved, false,
+			commonv1alpha1.ReasonTemplateNotFound, fmt.Sprintf("AppTemplate(%s) %s is not found in registries",
+				appTemplate, templateVersion))
+		return fmt.Errorf("app template(%s) %s not found", appTemplate, templateVersion)
+	}
+	utils.SetInstanceCondition(&appIns.Status.Conditions, appIns.Generation,
+		commonv1alpha1.ConditionTemplateResolved, true, commonv1alpha1.ReasonTemplateFound, "")
+
+	inputSchema, err := utils.LoadTemplateInputSchema(registry, tempName, templateVersion,
+		utils.AppTemplateBasePath)
+	if err != nil {
+		return err
+	}
+	values, err := utils.ConstructAppInstanceValues(appIns, inputSchema)
+	if err != nil {
+		ac.updateAppInstanceCondition(appIns, commonv1alpha1.ConditionInputsValid, false,
+			commonv1alpha1.ReasonInvalidInputs, err.Error())
+		return nil
+	}
+	utils.SetInstanceCondition(&appIns.Status.Conditions, appIns.Generation,
+		commonv1alpha1.ConditionInputsValid, true, commonv1alpha1.ReasonInputsAccepted, "")
+
+	rendered := []utils.RenderedManifest{}
+	if helmChart != nil {
+		rendered, err = utils.RenderHelmChart(templateDir, helmChart, values)
+		if err != nil {
+			klog.Errorf("Failed to render helm chart: %v", err)
+			ac.updateAppInstanceCondition(appIns, commonv1alpha1.ConditionResourcesApplied, false,
+				commonv1alpha1.ReasonRenderFailed, fmt.Sprintf("Failed to render helm chart: %v", err))
+			return nil
+		}
+	}
+	if composeFile != "" {
+		rendered, err = utils.RenderComposeFile(composeFile, values, ac.lookup)
+		if err != nil {
+			klog.Errorf("Failed to render compose file: %v", err)
+			ac.updateAppInstanceCondition(appIns, commonv1alpha1.ConditionResourcesApplied, false,
+				commonv1alpha1.ReasonRenderFailed, fmt.Sprintf("Failed to render compose file: %v", err))
+			return nil
+		}
+	}
 	for _, manifest := range manifests {
-		if err := ac.handleAppInstanceDerivedResourceCreation(appIns, manifest, &derivedResoruce); err != nil {
+		manifestContent, err := utils.ConstructTemplateWithValues(manifest, values, ac.lookup)
+		if err != nil {
+			klog.Errorf("Failed to construct manifest with values: %v", err)
+			ac.updateAppInstanceCondition(appIns, commonv1alpha1.ConditionResourcesApplied, false,
+				commonv1alpha1.ReasonRenderFailed, fmt.Sprintf("Failed to render %s: %v", utils.TemplateManifestName(manifest), err))
+			return nil
+		}
+		rendered = append(rendered, utils.RenderedManifest{Name: utils.TemplateManifestName(manifest), Content: manifestContent})
+	}
+
+	manifestContents := [][]byte{}
+	objs := []*unstructured.Unstructured{}
+	for _, m := range rendered {
+		manifestObjs, err := utils.DecodeManifests(m.Content)
+		if err != nil {
+			klog.Errorf("Failed to decode manifest: %v", err)
+			ac.updateAppInstanceCondition(appIns, commonv1alpha1.ConditionResourcesApplied, false,
+				commonv1alpha1.ReasonRenderFailed, fmt.Sprintf("Failed to decode %s: %v", m.Name, err))
+			return nil
+		}
+		manifestContents = append(manifestContents, m.Content)
+		objs = append(objs, manifestObjs...)
+	}
+	if err := utils.ApplyInstancePatches(objs, appIns.Spec.Patches); err != nil {
+		klog.Errorf("Failed to apply patches: %v", err)
+		ac.updateAppInstanceCondition(appIns, commonv1alpha1.ConditionResourcesApplied, false,
+			commonv1alpha1.ReasonPatchFailed, fmt.Sprintf("Failed to apply patches: %v", err))
+		return nil
+	}
+	ac.eventRecorder.Eventf(appIns, corev1.EventTypeNormal, commonv1alpha1.EventReasonRendered,
+		"Rendered %d manifests from app template %s", len(manifestContents), appTemplate)
+
+	derivedResoruce := []commonv1alpha1.DerivedResource{}
+	// Apply the objects after the ones they depend on, e.g. the configs before the workloads
+	utils.SortManifestObjects(objs)
+	for _, obj := range objs {
+		if err := ac.handleAppInstanceDerivedResourceCreation(appIns, obj, &derivedResoruce); err != nil {
+			reason, message := commonv1alpha1.ReasonApplyFailed, err.Error()
+			if msg, ok := utils.DerivedResourceConflictMessage(err); ok {
+				reason, message = commonv1alpha1.ReasonApplyConflict, msg
+			}
+			ac.updateAppInstanceCondition(appIns, commonv1alpha1.ConditionResourcesApplied, false,
+				reason, message)
 			return err
 		}
 	}
 
+	if err := utils.CleanStaleDerivedResources(ac.dynamicClient, ac.restMapper,
+		appIns.Status.DerivedResources, derivedResoruce, appIns.Namespace); err != nil {
+		return err
+	}
+
+	revision, err := ac.recordAppInstanceRevision(appIns, manifestContents)
+	if err != nil {
+		return err
+	}
+
 	appIns.Status.DerivedResources = derivedResoruce
+	appIns.Status.TemplateVersion = templateVersion
+	appIns.Status.CurrentRevision = revision
+	appIns.Status.Message = ""
+	appliedMessage := fmt.Sprintf("%d resources applied", len(derivedResoruce))
+	utils.SetInstanceCondition(&appIns.Status.Conditions, appIns.Generation,
+		commonv1alpha1.ConditionResourcesApplied, true, commonv1alpha1.ReasonApplied, appliedMessage)
+	ac.eventRecorder.Event(appIns, corev1.EventTypeNormal, commonv1alpha1.ReasonApplied, appliedMessage)
 	appIns, err = ac.openappClient.AppV1alpha1().AppInstances(appIns.Namespace).
 		UpdateStatus(context.Background(), appIns, metav1.UpdateOptions{})
 	if err != nil {
@@ -130,64 +286,48 @@ func (ac *AppInstanceController) Reconcile(resourceKey pkgtypes.NamespacedName)
 }
 
 func (ac *AppInstanceController) handleAppInstanceDerivedResourceCreation(appIns *appv1alpha1.AppInstance,
-	manifest string,
+	obj *unstructured.Unstructured,
 	derivedResoruce *[]commonv1alpha1.DerivedResource) error {
-	values, err := utils.ConstructAppInstanceValues(appIns)
-	if err != nil {
-		return err
+	labels := map[string]string{
+		utils.ServiceExposeClassLabelKey: appIns.Spec.PublicServiceClass,
+		utils.AppInstanceLabelKey:        appIns.Name,
+		utils.InstanceGenerationLabelKey: strconv.Itoa(int(appIns.Generation)),
 	}
-	manifestContent, err := utils.ConstructTemplateWithValues(manifest, values)
+	owner := metav1.NewControllerRef(appIns, appv1alpha1.SchemeGroupVersion.WithKind("AppInstance"))
+	return utils.ApplyDerivedResource(ac.dynamicClient, ac.restMapper,
+		obj, derivedResoruce, labels, owner)
+}
+
+// updateAppInstanceCondition records the failed condition on the instance, the
+// message is also shown in the status message and a warning event.
+func (ac *AppInstanceController) updateAppInstanceCondition(appIns *appv1alpha1.AppInstance,
+	conditionType string,
+	ok bool,
+	reason, message string) {
+	ac.eventRecorder.Event(appIns, corev1.EventTypeWarning, reason, message)
+	appInsCopy := appIns.DeepCopy()
+	utils.SetInstanceCondition(&appInsCopy.Status.Conditions, appInsCopy.Generation,
+		conditionType, ok, reason, message)
+	appInsCopy.Status.Message = message
+	_, err := ac.openappClient.AppV1alpha1().AppInstances(appInsCopy.Namespace).
+		UpdateStatus(context.Background(), appInsCopy, metav1.UpdateOptions{})
 	if err != nil {
-		klog.Errorf("Failed to construct manifest with values: %v", err)
-		return err
-	}
-	file := path.Base(manifest)
-	switch file {
-	case utils.TemplateManifestStatefulSetFile:
-		err = ac.statefulsetHandler(manifestContent, derivedResoruce, appIns)
-		if err != nil {
-			klog.Errorf("Failed to handle statefulset: %v", err)
-			return err
-		}
-	// TBD: except statefulset, there is a general way to update the resource
-	case utils.TemplateManifestServiceFile:
-		err = ac.serviceHandler(manifestContent, derivedResoruce, appIns)
-		if err != nil {
-			klog.Errorf("Failed to handle service: %v", err)
-			return err
-		}
-	case utils.TemplateManifestConfigMapFile:
-		err = ac.configmapHandler(manifestContent, derivedResoruce, appIns.Name)
-		if err != nil {
-			klog.Errorf("Failed to handle configmap: %v", err)
-			return err
-		}
+		klog.Errorf("Failed to update app instance condition: %v", err)
 	}
-	return nil
 }
 
 func (ac *AppInstanceController) deleteAppInstanceResources(appInstance *appv1alpha1.AppInstance) error {
 	klog.Infof("Deleting app instance(%s/%s) resources...", appInstance.Namespace, appInstance.Name)
 	for _, d := range appInstance.Status.DerivedResources {
-		switch d.Kind {
-		case utils.InstanceDerivedResourceServiceKind:
-			if err := utils.CleanInstanceDerivedServiceResource(ac.k8sClient,
-				d.Name, appInstance.Namespace); err != nil {
-				return err
-			}
-		case utils.InstanceDerivedResourceConfigMapKind:
-			if err := utils.CleanInstanceDerivedConfigMapResource(ac.k8sClient,
-				d.Name, appInstance.Namespace); err != nil {
-				return err
-			}
-		case utils.InstanceDerivedResourceStatefulSetKind:
-			if err := utils.CleanInstanceDerivedStatefulSetResource(ac.k8sClient,
-				d.Name, appInstance.Namespace); err != nil {
-				return err
-			}
+		if err := utils.CleanInstanceDerivedResource(ac.dynamicClient, ac.restMapper,
+			d, appInstance.Namespace); err != nil {
+			return err
 		}
 	}
 
+	ac.eventRecorder.Eventf(appInstance, corev1.EventTypeNormal, commonv1alpha1.EventReasonDeleted,
+		"Deleted %d derived resources", len(appInstance.Status.DerivedResources))
+
 	appInstance.Finalizers = nil
 	_, err := ac.openappClient.AppV1alpha1().AppInstances(appInstance.Namespace).
 		Update(context.Background(), appInstance, metav1.UpdateOptions{})
@@ -198,41 +338,3 @@ func (ac *AppInstanceController) deleteAppInstanceResources(appInstance *appv1al
 
 	return nil
 }
-
-func (ac *AppInstanceController) serviceHandler(manifestContent []byte,
-	derivedResoruce *[]commonv1alpha1.DerivedResource,
-	appIns *appv1alpha1.AppInstance) error {
-	labels := map[string]string{
-		utils.ServiceExposeClassLabelKey: appIns.Spec.PublicServiceClass,
-		utils.AppInstanceLabelKey:        appIns.Name,
-	}
-	return utils.CreateOrUpdateService(ac.k8sClient,
-		manifestContent,
-		derivedResoruce,
-		labels)
-}
-
-func (ac *AppInstanceController) configmapHandler(manifestContent []byte,
-	derivedResoruce *[]commonv1alpha1.DerivedResource,
-	instanceName string) error {
-	labels := map[string]string{
-		utils.AppInstanceLabelKey: instanceName,
-	}
-	return utils.CreateOrUpdateConfigmap(ac.k8sClient,
-		manifestContent,
-		derivedResoruce,
-		labels)
-}
-
-func (ac *AppInstanceController) statefulsetHandler(manifestContent []byte,
-	derivedResoruce *[]commonv1alpha1.DerivedResource,
-	appIns *appv1alpha1.AppInstance) error {
-	labels := map[string]string{
-		utils.AppInstanceLabelKey:        appIns.Name,
-		utils.InstanceGenerationLabelKey: strconv.Itoa(int(appIns.Generation)),
-	}
-	return utils.CreateOrUpdateStatefulset(ac.k8sClient,
-		manifestContent,
-		derivedResoruce,
-		labels)
-}
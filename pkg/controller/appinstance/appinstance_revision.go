@@ -0,0 +1,179 @@
+package appinstance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog"
+
+	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
+	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
+	"github.com/openapp-dev/openapp/pkg/utils"
+)
+
+// recordAppInstanceRevision stores the applied inputs, template version,
+// patches and the hash of the rendered manifests as a controller revision, the revision is reused if the same content
+// was applied before and the oldest revisions beyond the history limit are
+// deleted.
+func (ac *AppInstanceController) recordAppInstanceRevision(appIns *appv1alpha1.AppInstance,
+	manifestContents [][]byte) (int64, error) {
+	data := &utils.InstanceRevisionData{
+		Inputs:          appIns.Spec.Inputs,
+		TemplateVersion: appIns.Spec.TemplateVersion,
+		ManifestsHash:   utils.HashManifests(manifestContents),
+		Patches:         appIns.Spec.Patches,
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		klog.Errorf("Failed to marshal revision: %v", err)
+		return 0, err
+	}
+	revisions, err := utils.ListAppInstanceRevisions(ac.k8sClient, appIns.Name)
+	if err != nil {
+		return 0, err
+	}
+	nextRevision := int64(1)
+	if len(revisions) != 0 {
+		nextRevision = revisions[0].Revision + 1
+	}
+
+	revisionClient := ac.k8sClient.AppsV1().ControllerRevisions(appIns.Namespace)
+	name := utils.NewInstanceRevisionName(appIns.Name, data)
+	for i := range revisions {
+		if revisions[i].Name != name {
+			continue
+		}
+		// The data of the revision is immutable, the reused revision keeps the
+		// manifests hash of the first time it was applied
+		if i == 0 {
+			return revisions[0].Revision, nil
+		}
+		// The content is applied again, move the revision to the latest one
+		revision := revisions[i].DeepCopy()
+		revision.Revision = nextRevision
+		if _, err := revisionClient.Update(context.Background(), revision, metav1.UpdateOptions{}); err != nil {
+			klog.Errorf("Failed to update revision(%s): %v", revision.Name, err)
+			return 0, err
+		}
+		return nextRevision, ac.truncateAppInstanceRevisions(appIns)
+	}
+
+	revision := &appsv1.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: appIns.Namespace,
+			Labels: map[string]string{
+				utils.AppInstanceLabelKey: appIns.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(appIns, appv1alpha1.SchemeGroupVersion.WithKind("AppInstance")),
+			},
+		},
+		Data:     runtime.RawExtension{Raw: raw},
+		Revision: nextRevision,
+	}
+	if _, err := revisionClient.Create(context.Background(), revision, metav1.CreateOptions{}); err != nil {
+		klog.Errorf("Failed to create revision(%s): %v", revision.Name, err)
+		return 0, err
+	}
+	return nextRevision, ac.truncateAppInstanceRevisions(appIns)
+}
+
+func (ac *AppInstanceController) truncateAppInstanceRevisions(appIns *appv1alpha1.AppInstance) error {
+	limit := int32(utils.DefaultRevisionHistoryLimit)
+	if appIns.Spec.RevisionHistoryLimit != nil {
+		limit = *appIns.Spec.RevisionHistoryLimit
+	}
+	revisions, err := utils.ListAppInstanceRevisions(ac.k8sClient, appIns.Name)
+	if err != nil {
+		return err
+	}
+	for i := int(limit); i < len(revisions); i++ {
+		err := ac.k8sClient.AppsV1().ControllerRevisions(appIns.Namespace).
+			Delete(context.Background(), revisions[i].Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			klog.Errorf("Failed to delete revision(%s): %v", revisions[i].Name, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// rollbackAppInstance restores the inputs, template version and patches of the
+// revision, the instance will be reconciled again after the spec is updated.
+// The rollback is skipped if the revision or its template version is gone, it
+// won't succeed by retrying.
+func (ac *AppInstanceController) rollbackAppInstance(appIns *appv1alpha1.AppInstance) error {
+	targetRevision := appIns.Spec.RollbackToRevision
+	appIns.Spec.RollbackToRevision = 0
+
+	revisions, err := utils.ListAppInstanceRevisions(ac.k8sClient, appIns.Name)
+	if err != nil {
+		return err
+	}
+	var target *utils.InstanceRevision
+	for i := range revisions {
+		if revisions[i].Revision != targetRevision {
+			continue
+		}
+		target, err = utils.DecodeInstanceRevision(&revisions[i])
+		if err != nil {
+			return err
+		}
+		break
+	}
+
+	failedMessage := ""
+	switch {
+	case target == nil:
+		failedMessage = fmt.Sprintf("Revision %d is not found, skip rollback", targetRevision)
+	case !templateVersionAvailable(appIns.Spec.AppTemplate, target.TemplateVersion):
+		failedMessage = fmt.Sprintf("AppTemplate(%s) %s of revision %d is no longer available, skip rollback",
+			appIns.Spec.AppTemplate, target.TemplateVersion, targetRevision)
+	default:
+		appIns.Spec.Inputs = target.Inputs
+		appIns.Spec.TemplateVersion = target.TemplateVersion
+		appIns.Spec.Patches = target.Patches
+	}
+
+	updated, err := ac.openappClient.AppV1alpha1().AppInstances(appIns.Namespace).
+		Update(context.Background(), appIns, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Errorf("Failed to rollback app instance: %v", err)
+		return fmt.Errorf("failed to rollback app instance(%s) to revision %d: %v", appIns.Name, targetRevision, err)
+	}
+	appIns.ObjectMeta = updated.ObjectMeta
+	if failedMessage != "" {
+		ac.updateAppInstanceCondition(appIns, commonv1alpha1.ConditionRolledBack, false,
+			commonv1alpha1.ReasonRollbackFailed, failedMessage)
+		return nil
+	}
+
+	message := fmt.Sprintf("Rolled back to revision %d", targetRevision)
+	ac.eventRecorder.Event(appIns, corev1.EventTypeNormal, commonv1alpha1.EventReasonRolledBack, message)
+	utils.SetInstanceCondition(&appIns.Status.Conditions, appIns.Generation,
+		commonv1alpha1.ConditionRolledBack, true, commonv1alpha1.ReasonRevisionRestored, message)
+	_, err = ac.openappClient.AppV1alpha1().AppInstances(appIns.Namespace).
+		UpdateStatus(context.Background(), appIns, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Errorf("Failed to update app instance status: %v", err)
+		return err
+	}
+	return nil
+}
+
+// templateVersionAvailable reports whether the template version is cached, the
+// empty version follows the latest one.
+func templateVersionAvailable(appTemplate, templateVersion string) bool {
+	if templateVersion == "" {
+		return true
+	}
+	registry, tempName := utils.ParseTemplateReference(appTemplate)
+	return utils.FindTemplateDir(registry, tempName, templateVersion, utils.AppTemplateBasePath) != ""
+}
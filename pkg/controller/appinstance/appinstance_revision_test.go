@@ -0,0 +1,88 @@
+package appinstance
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
+	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
+	openappfake "github.com/openapp-dev/openapp/pkg/generated/clientset/versioned/fake"
+	"github.com/openapp-dev/openapp/pkg/utils"
+)
+
+func TestRecordAppInstanceRevision(t *testing.T) {
+	limit := int32(2)
+	appIns := &appv1alpha1.AppInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: utils.InstanceNamespace},
+		Spec: appv1alpha1.AppInstanceSpec{
+			AppTemplate:          "demo",
+			Inputs:               "port: 80",
+			RevisionHistoryLimit: &limit,
+		},
+	}
+	ac := &AppInstanceController{
+		k8sClient:     fake.NewSimpleClientset(),
+		openappClient: openappfake.NewSimpleClientset(appIns),
+		eventRecorder: record.NewFakeRecorder(10),
+	}
+
+	revision, err := ac.recordAppInstanceRevision(appIns, [][]byte{[]byte("a")})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), revision)
+
+	// Same content reuses the latest revision even if it's rendered differently
+	revision, err = ac.recordAppInstanceRevision(appIns, [][]byte{[]byte("random")})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), revision)
+
+	appIns.Spec.Inputs = "port: 8080"
+	patches := []appv1alpha1.InstancePatch{{Patch: "kind: Service\nmetadata:\n  name: demo\n"}}
+	appIns.Spec.Patches = patches
+	revision, err = ac.recordAppInstanceRevision(appIns, [][]byte{[]byte("b")})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), revision)
+
+	appIns.Spec.Inputs = "port: 9090"
+	appIns.Spec.Patches = nil
+	revision, err = ac.recordAppInstanceRevision(appIns, [][]byte{[]byte("c")})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), revision)
+
+	revisions, err := utils.ListAppInstanceRevisions(ac.k8sClient, appIns.Name)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 2)
+	latest, err := utils.DecodeInstanceRevision(&revisions[0])
+	assert.NoError(t, err)
+	assert.Equal(t, utils.HashManifests([][]byte{[]byte("c")}), latest.ManifestsHash)
+
+	appIns.Spec.RollbackToRevision = 2
+	assert.NoError(t, ac.rollbackAppInstance(appIns))
+	assert.Equal(t, "port: 8080", appIns.Spec.Inputs)
+	assert.Equal(t, patches, appIns.Spec.Patches)
+	assert.Equal(t, int64(0), appIns.Spec.RollbackToRevision)
+
+	// The rollback is skipped if the template version of the revision is gone
+	appIns.Spec.TemplateVersion = "0.2.0"
+	_, err = ac.recordAppInstanceRevision(appIns, [][]byte{[]byte("d")})
+	assert.NoError(t, err)
+	appIns.Spec.TemplateVersion = "0.3.0"
+	appIns.Spec.RollbackToRevision = 4
+	assert.NoError(t, ac.rollbackAppInstance(appIns))
+	assert.Equal(t, "0.3.0", appIns.Spec.TemplateVersion)
+	assert.Equal(t, int64(0), appIns.Spec.RollbackToRevision)
+	got, err := ac.openappClient.AppV1alpha1().AppInstances(appIns.Namespace).
+		Get(context.Background(), appIns.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), got.Spec.RollbackToRevision)
+	condition := meta.FindStatusCondition(got.Status.Conditions, commonv1alpha1.ConditionRolledBack)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, commonv1alpha1.ReasonRollbackFailed, condition.Reason)
+	}
+}
@@ -2,6 +2,7 @@ package appinstance
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 
 	corev1 "k8s.io/api/core/v1"
@@ -10,8 +11,10 @@ import (
 	pkgtypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
 
+	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
 	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
 	"github.com/openapp-dev/openapp/pkg/controller/types"
 	"github.com/openapp-dev/openapp/pkg/generated/clientset/versioned"
@@ -22,6 +25,7 @@ type AppInstanceServiceController struct {
 	k8sClient     kubernetes.Interface
 	openappClient versioned.Interface
 	workqueue     *utils.WorkQueue
+	eventRecorder record.EventRecorder
 }
 
 func NewAppInstanceServiceController(openappHelper *utils.OpenAPPHelper) types.ControllerInterface {
@@ -29,6 +33,7 @@ func NewAppInstanceServiceController(openappHelper *utils.OpenAPPHelper) types.C
 	sc.workqueue = utils.NewWorkQueue(sc.Reconcile)
 	sc.k8sClient = openappHelper.K8sClient
 	sc.openappClient = openappHelper.OpenAPPClient
+	sc.eventRecorder = openappHelper.EventRecorder
 
 	handlefunc := func(obj interface{}) {
 		svc, ok := obj.(*corev1.Service)
@@ -110,9 +115,27 @@ func (sc *AppInstanceServiceController) updateAppInstanceServiceURL(service *cor
 	appInsCopy := appIns.DeepCopy()
 	appInsCopy.Status.ExternalServiceURL = publicURL
 	appInsCopy.Status.LocalServiceURL = localURL
+	setExposedCondition(appInsCopy)
 	_, err = sc.openappClient.AppV1alpha1().AppInstances(utils.InstanceNamespace).UpdateStatus(context.Background(), appInsCopy, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Errorf("Failed to update app instance status: %v", err)
+		return err
+	}
+	if appIns.Status.LocalServiceURL != localURL || appIns.Status.ExternalServiceURL != publicURL {
+		sc.recordServiceURLEvent(appInsCopy)
+	}
+
+	return nil
+}
 
-	return err
+func (sc *AppInstanceServiceController) recordServiceURLEvent(appIns *appv1alpha1.AppInstance) {
+	if appIns.Status.LocalServiceURL == "" {
+		sc.eventRecorder.Eventf(appIns, corev1.EventTypeWarning, commonv1alpha1.ReasonServiceNotFound,
+			"No service is exposed by the app instance")
+		return
+	}
+	sc.eventRecorder.Eventf(appIns, corev1.EventTypeNormal, commonv1alpha1.EventReasonURLChanged,
+		"Service is exposed at %s, public URL: %q", appIns.Status.LocalServiceURL, appIns.Status.ExternalServiceURL)
 }
 
 func (sc *AppInstanceServiceController) getServiceURL(service *corev1.Service) (string, string, error) {
@@ -144,7 +167,9 @@ func (sc *AppInstanceServiceController) getServiceURL(service *corev1.Service) (
 	}
 	localURL := url + ":" + strconv.Itoa(int(localPort))
 
-	appTemp, err := sc.openappClient.AppV1alpha1().AppTemplates().Get(context.Background(), appIns.Spec.AppTemplate, metav1.GetOptions{})
+	appTemp, err := utils.GetAppTemplate(func(name string) (*appv1alpha1.AppTemplate, error) {
+		return sc.openappClient.AppV1alpha1().AppTemplates().Get(context.Background(), name, metav1.GetOptions{})
+	}, appIns.Spec.AppTemplate)
 	if err != nil {
 		klog.Errorf("Failed to get app template: %v", err)
 		return "", "", err
@@ -157,3 +182,19 @@ func (sc *AppInstanceServiceController) getServiceURL(service *corev1.Service) (
 	}
 	return "http://" + localURL, "http://" + publicURL, nil
 }
+
+func setExposedCondition(appIns *appv1alpha1.AppInstance) {
+	switch {
+	case appIns.Status.LocalServiceURL == "":
+		utils.SetInstanceCondition(&appIns.Status.Conditions, appIns.Generation,
+			commonv1alpha1.ConditionExposed, false, commonv1alpha1.ReasonServiceNotFound,
+			"No service is exposed by the app instance")
+	case appIns.Spec.PublicServiceClass != "" && appIns.Status.ExternalServiceURL == "":
+		utils.SetInstanceCondition(&appIns.Status.Conditions, appIns.Generation,
+			commonv1alpha1.ConditionExposed, false, commonv1alpha1.ReasonPublicURLPending,
+			fmt.Sprintf("Waiting for publicservice(%s) to assign the public URL", appIns.Spec.PublicServiceClass))
+	default:
+		utils.SetInstanceCondition(&appIns.Status.Conditions, appIns.Generation,
+			commonv1alpha1.ConditionExposed, true, commonv1alpha1.ReasonServiceExposed, "")
+	}
+}
@@ -2,15 +2,20 @@ package appinstance
 
 import (
 	"context"
+	"fmt"
 
 	v1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	pkgtypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
 
+	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
+	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
 	"github.com/openapp-dev/openapp/pkg/controller/types"
 	"github.com/openapp-dev/openapp/pkg/generated/clientset/versioned"
 	"github.com/openapp-dev/openapp/pkg/utils"
@@ -19,6 +24,7 @@ import (
 type AppInstanceStatusController struct {
 	k8sClient     kubernetes.Interface
 	openappClient versioned.Interface
+	eventRecorder record.EventRecorder
 	workqueue     *utils.WorkQueue
 }
 
@@ -27,6 +33,7 @@ func NewAppInstanceStatusController(openappHelper *utils.OpenAPPHelper) types.Co
 	ac.workqueue = utils.NewWorkQueue(ac.Reconcile)
 	ac.openappClient = openappHelper.OpenAPPClient
 	ac.k8sClient = openappHelper.K8sClient
+	ac.eventRecorder = openappHelper.EventRecorder
 
 	handlefunc := func(obj interface{}) {
 		sts, ok := obj.(*v1.StatefulSet)
@@ -96,12 +103,19 @@ func (ac *AppInstanceStatusController) Reconcile(resourceKey pkgtypes.Namespaced
 	instanceName := sts.Labels[utils.AppInstanceLabelKey]
 	ins, err := ac.openappClient.AppV1alpha1().AppInstances(utils.InstanceNamespace).
 		Get(context.Background(), instanceName, metav1.GetOptions{})
-	if err != nil && apierrors.IsNotFound(err) {
-		return nil
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		klog.Errorf("Failed to get app instance: %v", err)
+		return err
 	}
 
 	insCopy := ins.DeepCopy()
 	insCopy.Status.AppReady = ready
+	insCopy.Status.Rollouts = updateStatefulSetRolloutStatus(insCopy.Status.Rollouts, sts)
+	setWorkloadReadyCondition(insCopy, sts)
+	ac.recordRolloutEvent(ins, insCopy, sts.Name)
 	_, err = ac.openappClient.AppV1alpha1().AppInstances(utils.InstanceNamespace).
 		UpdateStatus(context.Background(), insCopy, metav1.UpdateOptions{})
 	if err != nil {
@@ -111,3 +125,84 @@ func (ac *AppInstanceStatusController) Reconcile(resourceKey pkgtypes.Namespaced
 
 	return nil
 }
+
+func updateStatefulSetRolloutStatus(rollouts []appv1alpha1.StatefulSetRolloutStatus,
+	sts *v1.StatefulSet) []appv1alpha1.StatefulSetRolloutStatus {
+	replicas := int32(1)
+	if sts.Spec.Replicas != nil {
+		replicas = *sts.Spec.Replicas
+	}
+	partition := int32(0)
+	if sts.Spec.UpdateStrategy.RollingUpdate != nil &&
+		sts.Spec.UpdateStrategy.RollingUpdate.Partition != nil {
+		partition = *sts.Spec.UpdateStrategy.RollingUpdate.Partition
+	}
+	rollout := appv1alpha1.StatefulSetRolloutStatus{
+		Name:            sts.Name,
+		Replicas:        replicas,
+		ReadyReplicas:   sts.Status.ReadyReplicas,
+		UpdatedReplicas: sts.Status.UpdatedReplicas,
+		Partition:       partition,
+		CurrentRevision: sts.Status.CurrentRevision,
+		UpdateRevision:  sts.Status.UpdateRevision,
+	}
+	// The pods below the partition are kept in the current revision on purpose,
+	// so the rollout is completed once all the pods above it are updated.
+	rollout.Completed = sts.Status.ObservedGeneration >= sts.Generation &&
+		sts.Status.UpdatedReplicas >= replicas-partition &&
+		sts.Status.ReadyReplicas >= replicas
+
+	for i := range rollouts {
+		if rollouts[i].Name == sts.Name {
+			rollouts[i] = rollout
+			return rollouts
+		}
+	}
+	return append(rollouts, rollout)
+}
+
+func (ac *AppInstanceStatusController) recordRolloutEvent(oldIns, newIns *appv1alpha1.AppInstance, stsName string) {
+	oldRollout := findStatefulSetRolloutStatus(oldIns.Status.Rollouts, stsName)
+	newRollout := findStatefulSetRolloutStatus(newIns.Status.Rollouts, stsName)
+	if oldRollout == nil || newRollout == nil || oldRollout.Completed == newRollout.Completed {
+		return
+	}
+	if newRollout.Completed {
+		ac.eventRecorder.Eventf(newIns, corev1.EventTypeNormal, commonv1alpha1.EventReasonRolloutCompleted,
+			"StatefulSet(%s) rolled out revision %s", stsName, newRollout.UpdateRevision)
+		return
+	}
+	ac.eventRecorder.Eventf(newIns, corev1.EventTypeNormal, commonv1alpha1.EventReasonRolloutStarted,
+		"StatefulSet(%s) started rolling out revision %s", stsName, newRollout.UpdateRevision)
+}
+
+func findStatefulSetRolloutStatus(rollouts []appv1alpha1.StatefulSetRolloutStatus,
+	name string) *appv1alpha1.StatefulSetRolloutStatus {
+	for i := range rollouts {
+		if rollouts[i].Name == name {
+			return &rollouts[i]
+		}
+	}
+	return nil
+}
+
+func setWorkloadReadyCondition(ins *appv1alpha1.AppInstance, sts *v1.StatefulSet) {
+	for _, rollout := range ins.Status.Rollouts {
+		if rollout.Name != sts.Name {
+			continue
+		}
+		switch {
+		case rollout.ReadyReplicas == 0:
+			utils.SetInstanceCondition(&ins.Status.Conditions, ins.Generation,
+				commonv1alpha1.ConditionWorkloadReady, false, commonv1alpha1.ReasonReplicasNotReady,
+				fmt.Sprintf("StatefulSet(%s) has no ready replicas", sts.Name))
+		case !rollout.Completed:
+			utils.SetInstanceCondition(&ins.Status.Conditions, ins.Generation,
+				commonv1alpha1.ConditionWorkloadReady, false, commonv1alpha1.ReasonRolloutInProgress,
+				fmt.Sprintf("StatefulSet(%s) has %d/%d updated replicas", sts.Name, rollout.UpdatedReplicas, rollout.Replicas))
+		default:
+			utils.SetInstanceCondition(&ins.Status.Conditions, ins.Generation,
+				commonv1alpha1.ConditionWorkloadReady, true, commonv1alpha1.ReasonReplicasReady, "")
+		}
+	}
+}
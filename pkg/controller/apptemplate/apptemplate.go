@@ -3,49 +3,50 @@ package apptemplate
 import (
 	"context"
 	"os"
+	"path"
 
-	v1 "k8s.io/api/core/v1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	pkgtypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
 
 	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
+	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
 	"github.com/openapp-dev/openapp/pkg/controller/types"
 	"github.com/openapp-dev/openapp/pkg/generated/clientset/versioned"
+	listerappv1alpha1 "github.com/openapp-dev/openapp/pkg/generated/listers/app/v1alpha1"
+	listerregistryv1alpha1 "github.com/openapp-dev/openapp/pkg/generated/listers/registry/v1alpha1"
 	"github.com/openapp-dev/openapp/pkg/utils"
 )
 
 type AppTemplateController struct {
-	openappClient versioned.Interface
-	workqueue     *utils.WorkQueue
+	openappClient  versioned.Interface
+	appInsLister   listerappv1alpha1.AppInstanceLister
+	registryLister listerregistryv1alpha1.RegistryLister
+	eventRecorder  record.EventRecorder
+	workqueue      *utils.WorkQueue
 }
 
 func NewAppTempalteController(openappHelper *utils.OpenAPPHelper) types.ControllerInterface {
 	ac := &AppTemplateController{}
 	ac.workqueue = utils.NewWorkQueue(ac.Reconcile)
 	ac.openappClient = openappHelper.OpenAPPClient
+	ac.appInsLister = openappHelper.AppInstanceLister
+	ac.registryLister = openappHelper.RegistryLister
+	ac.eventRecorder = openappHelper.EventRecorder
 
-	_, _ = openappHelper.ConfigMapInformer.AddEventHandler(cache.FilteringResourceEventHandler{
-		FilterFunc: func(obj interface{}) bool {
-			cm, ok := obj.(*v1.ConfigMap)
-			if !ok {
-				return false
-			}
-			if cm.Name != utils.SystemConfigMap || cm.Namespace != utils.SystemNamespace {
-				return false
-			}
-			return true
-		},
-		Handler: cache.ResourceEventHandlerFuncs{
-			AddFunc: func(_ interface{}) {
-				ac.workqueue.Add(pkgtypes.NamespacedName{})
-			},
-			UpdateFunc: func(_, _ interface{}) {
-				ac.workqueue.Add(pkgtypes.NamespacedName{})
-			},
+	_, _ = openappHelper.RegistryInformer.AddEventHandler(utils.NewRegistrySyncedHandler(func() {
+		ac.workqueue.Add(pkgtypes.NamespacedName{})
+	}))
+	// The deprecated templates are pruned once no instance uses them
+	_, _ = openappHelper.AppInstanceInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: func(_ interface{}) {
+			ac.workqueue.Add(pkgtypes.NamespacedName{})
 		},
 	})
 
@@ -58,8 +59,16 @@ func (ac *AppTemplateController) Start() {
 
 func (ac *AppTemplateController) Reconcile(_ pkgtypes.NamespacedName) error {
 	klog.Infof("Reconciling app template...")
+	// The registries are ordered by priority, the template is taken from the
+	// first registry if multiple registries have templates with the same name.
+	synced := map[string]bool{}
+	present := map[string]bool{}
 	registries := utils.GetRegistryPaths()
 	for _, registry := range registries {
+		// The cache of the deleted registry may not be removed yet
+		if _, err := ac.registryLister.Get(path.Base(registry)); apierrors.IsNotFound(err) {
+			continue
+		}
 		templates := utils.GetAppTemplatePath(registry)
 		for _, templateFile := range templates {
 			d, err := os.ReadFile(templateFile)
@@ -72,12 +81,102 @@ func (ac *AppTemplateController) Reconcile(_ pkgtypes.NamespacedName) error {
 				klog.Errorf("Failed to unmarshal template: %v", err)
 				continue
 			}
-			if err := createOrUpdateAppTemplate(ac.openappClient, appTemplate); err != nil {
+			// The shadowed template is kept with the name prefixed by its registry,
+			// so it can still be referenced as `registry/template`.
+			registryName := path.Base(registry)
+			tempName := appTemplate.Name
+			if synced[tempName] {
+				appTemplate.Name = utils.ShadowedTemplateName(registryName, tempName)
+				klog.Warningf("App template(%s) of %s is shadowed by another registry, synced as %s",
+					tempName, registryName, appTemplate.Name)
+			}
+			// The dotted template name may be the same as the shadowed one
+			if present[appTemplate.Name] {
+				klog.Warningf("App template(%s) of %s collides with a template synced before, skip it",
+					appTemplate.Name, registryName)
+				continue
+			}
+			synced[tempName] = true
+			present[appTemplate.Name] = true
+			utils.SetTemplateSource(appTemplate, registryName, tempName)
+			appTemplate.Spec.Registry = registryName
+			appTemplate.Spec.Versions = utils.ListTemplateVersions(registryName, tempName, utils.AppTemplateBasePath)
+			err = createOrUpdateAppTemplate(ac.openappClient, appTemplate)
+			// The template rejected by the validation is skipped, the others of
+			// the registries are still synced
+			if apierrors.IsInvalid(err) || apierrors.IsBadRequest(err) {
+				klog.Warningf("App template(%s) of %s is rejected, skip it: %v", appTemplate.Name, registryName, err)
+				continue
+			}
+			if err != nil {
 				return err
 			}
 		}
 	}
 
+	return ac.pruneAppTemplates(present)
+}
+
+// pruneAppTemplates deletes the synced templates which are removed from the
+// registries, the templates still used by instances are marked deprecated.
+func (ac *AppTemplateController) pruneAppTemplates(present map[string]bool) error {
+	appTemps, err := ac.openappClient.AppV1alpha1().AppTemplates().
+		List(context.Background(), metav1.ListOptions{LabelSelector: utils.RegistryLabelKey})
+	if err != nil {
+		klog.Errorf("Failed to list app templates: %v", err)
+		return err
+	}
+	appInstances, err := ac.appInsLister.AppInstances(utils.InstanceNamespace).List(labels.Everything())
+	if err != nil {
+		klog.Errorf("Failed to list app instances: %v", err)
+		return err
+	}
+
+	for i := range appTemps.Items {
+		appTemp := &appTemps.Items[i]
+		registry := appTemp.Labels[utils.RegistryLabelKey]
+		if present[appTemp.Name] || !utils.IsRegistryPruneable(ac.registryLister, registry) {
+			continue
+		}
+		users := []*appv1alpha1.AppInstance{}
+		for _, appIns := range appInstances {
+			if utils.IsTemplateReferenced(appIns.Spec.AppTemplate, appTemp) {
+				users = append(users, appIns)
+			}
+		}
+
+		if len(users) == 0 {
+			klog.Infof("Pruning app template(%s) removed from registry(%s)...", appTemp.Name, registry)
+			err := ac.openappClient.AppV1alpha1().AppTemplates().
+				Delete(context.Background(), appTemp.Name, metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				klog.Errorf("Failed to delete app template(%s): %v", appTemp.Name, err)
+				return err
+			}
+			continue
+		}
+		if utils.IsTemplateDeprecated(appTemp) {
+			continue
+		}
+
+		klog.Warningf("App template(%s) is removed from registry(%s) but used by %d instances",
+			appTemp.Name, registry, len(users))
+		if appTemp.Annotations == nil {
+			appTemp.Annotations = map[string]string{}
+		}
+		appTemp.Annotations[utils.TemplateDeprecatedAnnotationKey] = "removed from registry " + registry
+		_, err := ac.openappClient.AppV1alpha1().AppTemplates().
+			Update(context.Background(), appTemp, metav1.UpdateOptions{})
+		if err != nil {
+			klog.Errorf("Failed to deprecate app template(%s): %v", appTemp.Name, err)
+			return err
+		}
+		for _, appIns := range users {
+			ac.eventRecorder.Eventf(appIns, corev1.EventTypeWarning, commonv1alpha1.EventReasonTemplateRemoved,
+				"AppTemplate(%s) is removed from registry(%s), it's deprecated and will be pruned once no instance uses it",
+				appTemp.Name, registry)
+		}
+	}
 	return nil
 }
 
@@ -98,6 +197,8 @@ func createOrUpdateAppTemplate(openappClient versioned.Interface, appTemplate *a
 
 	template := templateExist.DeepCopy()
 	template.Spec = appTemplate.Spec
+	utils.SetTemplateSource(template, appTemplate.Spec.Registry, utils.GetTemplateName(appTemplate))
+	delete(template.Annotations, utils.TemplateDeprecatedAnnotationKey)
 	_, err = openappClient.AppV1alpha1().AppTemplates().Update(context.Background(), template, metav1.UpdateOptions{})
 	if err != nil {
 		klog.Errorf("Failed to update app template: %v", err)
@@ -0,0 +1,185 @@
+package derivedresource
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	pkgtypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+
+	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
+	"github.com/openapp-dev/openapp/pkg/controller/types"
+	listerappv1alpha1 "github.com/openapp-dev/openapp/pkg/generated/listers/app/v1alpha1"
+	listerservicev1alpha1 "github.com/openapp-dev/openapp/pkg/generated/listers/service/v1alpha1"
+	"github.com/openapp-dev/openapp/pkg/utils"
+)
+
+// DerivedResourceGCController sweeps the derived resources whose instance no
+// longer exists, they are leaked if the instance status was not updated or the
+// resources were created before owner references were set.
+type DerivedResourceGCController struct {
+	discoveryClient             discovery.DiscoveryInterface
+	dynamicClient               dynamic.Interface
+	restMapper                  meta.RESTMapper
+	appInstanceLister           listerappv1alpha1.AppInstanceLister
+	publicServiceInstanceLister listerservicev1alpha1.PublicServiceInstanceLister
+	workqueue                   *utils.WorkQueue
+	eventRecorder               record.EventRecorder
+}
+
+func NewDerivedResourceGCController(openappHelper *utils.OpenAPPHelper) types.ControllerInterface {
+	dc := &DerivedResourceGCController{
+		discoveryClient:             openappHelper.K8sClient.Discovery(),
+		dynamicClient:               openappHelper.DynamicClient,
+		restMapper:                  openappHelper.RESTMapper,
+		appInstanceLister:           openappHelper.AppInstanceLister,
+		publicServiceInstanceLister: openappHelper.PublicServiceInstanceLister,
+		eventRecorder:               openappHelper.EventRecorder,
+	}
+	dc.workqueue = utils.NewWorkQueue(dc.Reconcile)
+
+	return dc
+}
+
+func (dc *DerivedResourceGCController) Start() {
+	go dc.workqueue.Run()
+
+	dc.workqueue.Add(pkgtypes.NamespacedName{})
+	ticker := time.NewTicker(time.Minute * 10)
+	for range ticker.C {
+		dc.workqueue.Add(pkgtypes.NamespacedName{})
+	}
+}
+
+func (dc *DerivedResourceGCController) Reconcile(_ pkgtypes.NamespacedName) error {
+	klog.Infof("Reconciling orphaned derived resources...")
+	resources, err := dc.getDeletableResources()
+	if err != nil {
+		klog.Errorf("Failed to discover deletable resources: %v", err)
+		return err
+	}
+
+	for _, gvr := range resources {
+		if err := dc.cleanOrphanedResources(gvr, utils.AppInstanceLabelKey, dc.appInstanceExists); err != nil {
+			return err
+		}
+		if err := dc.cleanOrphanedResources(gvr, utils.PublicServiceInstanceLabelKey, dc.publicServiceInstanceExists); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (dc *DerivedResourceGCController) getDeletableResources() ([]schema.GroupVersionResource, error) {
+	resourceLists, err := dc.discoveryClient.ServerPreferredNamespacedResources()
+	if err != nil && len(resourceLists) == 0 {
+		return nil, err
+	}
+	// Some aggregated apis may be unavailable, go on with the discovered ones
+	if err != nil {
+		klog.Warningf("Failed to discover some resources: %v", err)
+	}
+
+	ret := []schema.GroupVersionResource{}
+	for _, resourceList := range resourceLists {
+		gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, r := range resourceList.APIResources {
+			verbs := sets.NewString(r.Verbs...)
+			if !verbs.HasAll("list", "delete") {
+				continue
+			}
+			ret = append(ret, gv.WithResource(r.Name))
+		}
+	}
+	return ret, nil
+}
+
+func (dc *DerivedResourceGCController) cleanOrphanedResources(gvr schema.GroupVersionResource,
+	labelKey string,
+	instanceExists func(name string) (bool, error)) error {
+	client := dc.dynamicClient.Resource(gvr).Namespace(utils.InstanceNamespace)
+	objs, err := client.List(context.Background(), metav1.ListOptions{
+		LabelSelector: labelKey,
+	})
+	if err != nil {
+		if apierrors.IsNotFound(err) || apierrors.IsMethodNotSupported(err) {
+			return nil
+		}
+		klog.Errorf("Failed to list %s: %v", gvr, err)
+		return err
+	}
+
+	for _, obj := range objs.Items {
+		if !isOrphanCandidate(&obj) {
+			continue
+		}
+		exists, err := instanceExists(obj.GetLabels()[labelKey])
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		klog.Infof("Deleting orphaned %s(%s/%s)...", gvr.Resource, obj.GetNamespace(), obj.GetName())
+		err = utils.CleanInstanceDerivedResource(dc.dynamicClient, dc.restMapper,
+			commonv1alpha1.DerivedResource{
+				APIVersion: obj.GetAPIVersion(),
+				Kind:       obj.GetKind(),
+				Name:       obj.GetName(),
+			}, obj.GetNamespace())
+		if err != nil {
+			return err
+		}
+		// The instance is gone, the event is recorded on the swept object
+		dc.eventRecorder.Eventf(&obj, corev1.EventTypeNormal, commonv1alpha1.EventReasonOrphanDeleted,
+			"Deleted the orphaned %s, its instance(%s) no longer exists", gvr.Resource, obj.GetLabels()[labelKey])
+	}
+	return nil
+}
+
+// isOrphanCandidate skips the objects managed by other controllers, such as the
+// pods of a statefulset, and the ones already being deleted.
+func isOrphanCandidate(obj *unstructured.Unstructured) bool {
+	if !obj.GetDeletionTimestamp().IsZero() {
+		return false
+	}
+	return metav1.GetControllerOfNoCopy(obj) == nil
+}
+
+func (dc *DerivedResourceGCController) appInstanceExists(name string) (bool, error) {
+	_, err := dc.appInstanceLister.AppInstances(utils.InstanceNamespace).Get(name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		klog.Errorf("Failed to get app instance(%s): %v", name, err)
+		return false, err
+	}
+	return true, nil
+}
+
+func (dc *DerivedResourceGCController) publicServiceInstanceExists(name string) (bool, error) {
+	_, err := dc.publicServiceInstanceLister.PublicServiceInstances(utils.InstanceNamespace).Get(name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		klog.Errorf("Failed to get publicservice instance(%s): %v", name, err)
+		return false, err
+	}
+	return true, nil
+}
@@ -2,15 +2,20 @@ package publicserviceinstance
 
 import (
 	"context"
-	"path"
+	"fmt"
 	"reflect"
 	"strconv"
 
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	pkgtypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
 
 	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
@@ -24,6 +29,10 @@ import (
 type PublicServiceInstanceController struct {
 	k8sClient     kubernetes.Interface
 	openappClient versioned.Interface
+	dynamicClient dynamic.Interface
+	restMapper    meta.RESTMapper
+	eventRecorder record.EventRecorder
+	lookup        utils.InstanceLookupFunc
 	workqueue     *utils.WorkQueue
 }
 
@@ -32,6 +41,11 @@ func NewPublicServiceInstanceController(openappHelper *utils.OpenAPPHelper) type
 	pc.workqueue = utils.NewWorkQueue(pc.Reconcile)
 	pc.openappClient = openappHelper.OpenAPPClient
 	pc.k8sClient = openappHelper.K8sClient
+	pc.dynamicClient = openappHelper.DynamicClient
+	pc.restMapper = openappHelper.RESTMapper
+	pc.eventRecorder = openappHelper.EventRecorder
+	pc.lookup = utils.NewInstanceLookupFunc(openappHelper.AppInstanceLister,
+		openappHelper.PublicServiceInstanceLister)
 
 	_, _ = openappHelper.PublicServiceInstanceInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
@@ -113,6 +127,10 @@ func (pc *PublicServiceInstanceController) Start() {
 	go pc.workqueue.Run()
 }
 
+func (pc *PublicServiceInstanceController) getPublicServiceTemplate(name string) (*v1alpha1.PublicServiceTemplate, error) {
+	return pc.openappClient.ServiceV1alpha1().PublicServiceTemplates().Get(context.Background(), name, metav1.GetOptions{})
+}
+
 func (pc *PublicServiceInstanceController) Reconcile(resourceKey pkgtypes.NamespacedName) error {
 	klog.Infof("Reconciling publicservice instance(%s)...", resourceKey)
 	publicServiceIns, err := pc.openappClient.ServiceV1alpha1().PublicServiceInstances(resourceKey.Namespace).
@@ -140,18 +158,104 @@ func (pc *PublicServiceInstanceController) Reconcile(resourceKey pkgtypes.Namesp
 	publicServiceTemp := publicServiceIns.Spec.PublicServiceTemplate
 	if publicServiceTemp == "" {
 		klog.Errorf("PublicServiceTemplate is not specified in PublicServiceInstance(%s/%s)", publicServiceIns.Namespace, publicServiceIns.Name)
+		pc.updatePublicServiceInstanceCondition(publicServiceIns, commonv1alpha1.ConditionTemplateResolved, false,
+			commonv1alpha1.ReasonTemplateNotSpecified, "PublicServiceTemplate is not specified")
 		return nil
 	}
+	// Pin the instance to the latest version, so the registry pull won't change it
+	if publicServiceIns.Spec.TemplateVersion == "" {
+		temp, err := utils.GetPublicServiceTemplate(pc.getPublicServiceTemplate, publicServiceTemp)
+		if err != nil && !apierrors.IsNotFound(err) {
+			klog.Errorf("Failed to get publicservice template(%s): %v", publicServiceTemp, err)
+			return err
+		}
+		if err == nil && temp.Spec.Version != "" {
+			publicServiceIns.Spec.TemplateVersion = temp.Spec.Version
+			_, err = pc.openappClient.ServiceV1alpha1().PublicServiceInstances(publicServiceIns.Namespace).
+				Update(context.Background(), publicServiceIns, metav1.UpdateOptions{})
+			if err != nil {
+				klog.Errorf("Failed to pin publicservice instance template version: %v", err)
+				return err
+			}
+			return nil
+		}
+	}
+	templateVersion := publicServiceIns.Spec.TemplateVersion
+	registry, tempName := utils.ParseTemplateReference(publicServiceTemp)
+	manifests := utils.FindPublicServiceTemplateResources(registry, tempName, templateVersion)
+	if len(manifests) == 0 {
+		pc.updatePublicServiceInstanceCondition(publicServiceIns, commonv1alpha1.ConditionTemplateResolved, false,
+			commonv1alpha1.ReasonTemplateNotFound, fmt.Sprintf("PublicServiceTemplate(%s) %s is not found in registries",
+				publicServiceTemp, templateVersion))
+		return fmt.Errorf("publicservice template(%s) %s not found", publicServiceTemp, templateVersion)
+	}
+	utils.SetInstanceCondition(&publicServiceIns.Status.Conditions, publicServiceIns.Generation,
+		commonv1alpha1.ConditionTemplateResolved, true, commonv1alpha1.ReasonTemplateFound, "")
 
-	derivedResource := []commonv1alpha1.DerivedResource{}
-	manifests := utils.FindTemplateResources(publicServiceTemp, utils.PublicServiceTemplateBasePath)
+	inputSchema, err := utils.LoadTemplateInputSchema(registry, tempName, templateVersion,
+		utils.PublicServiceTemplateBasePath)
+	if err != nil {
+		return err
+	}
+	values, err := utils.ConstructPublicServiceInstanceValues(publicServiceIns, inputSchema)
+	if err != nil {
+		pc.updatePublicServiceInstanceCondition(publicServiceIns, commonv1alpha1.ConditionInputsValid, false,
+			commonv1alpha1.ReasonInvalidInputs, err.Error())
+		return nil
+	}
+	utils.SetInstanceCondition(&publicServiceIns.Status.Conditions, publicServiceIns.Generation,
+		commonv1alpha1.ConditionInputsValid, true, commonv1alpha1.ReasonInputsAccepted, "")
+
+	manifestContents := [][]byte{}
+	objs := []*unstructured.Unstructured{}
 	for _, manifest := range manifests {
-		if err := pc.handlePublicServiceInstanceDerivedResourceCreation(publicServiceIns, manifest, &derivedResource); err != nil {
+		manifestContent, err := utils.ConstructTemplateWithValues(manifest, values, pc.lookup)
+		if err != nil {
+			klog.Errorf("Failed to construct template with values: %v", err)
+			pc.updatePublicServiceInstanceCondition(publicServiceIns, commonv1alpha1.ConditionResourcesApplied, false,
+				commonv1alpha1.ReasonRenderFailed, fmt.Sprintf("Failed to render %s: %v", utils.TemplateManifestName(manifest), err))
+			return nil
+		}
+		manifestObjs, err := utils.DecodeManifests(manifestContent)
+		if err != nil {
+			klog.Errorf("Failed to decode manifest: %v", err)
+			pc.updatePublicServiceInstanceCondition(publicServiceIns, commonv1alpha1.ConditionResourcesApplied, false,
+				commonv1alpha1.ReasonRenderFailed, fmt.Sprintf("Failed to decode %s: %v", utils.TemplateManifestName(manifest), err))
+			return nil
+		}
+		manifestContents = append(manifestContents, manifestContent)
+		objs = append(objs, manifestObjs...)
+	}
+	pc.eventRecorder.Eventf(publicServiceIns, corev1.EventTypeNormal, commonv1alpha1.EventReasonRendered,
+		"Rendered %d manifests from publicservice template %s", len(manifestContents), publicServiceTemp)
+
+	derivedResource := []commonv1alpha1.DerivedResource{}
+	// Apply the objects after the ones they depend on, e.g. the configs before the workloads
+	utils.SortManifestObjects(objs)
+	for _, obj := range objs {
+		if err := pc.handlePublicServiceInstanceDerivedResourceCreation(publicServiceIns, obj, &derivedResource); err != nil {
+			reason, message := commonv1alpha1.ReasonApplyFailed, err.Error()
+			if msg, ok := utils.DerivedResourceConflictMessage(err); ok {
+				reason, message = commonv1alpha1.ReasonApplyConflict, msg
+			}
+			pc.updatePublicServiceInstanceCondition(publicServiceIns, commonv1alpha1.ConditionResourcesApplied, false,
+				reason, message)
 			return err
 		}
 	}
 
+	if err := utils.CleanStaleDerivedResources(pc.dynamicClient, pc.restMapper,
+		publicServiceIns.Status.DerivedResources, derivedResource, publicServiceIns.Namespace); err != nil {
+		return err
+	}
+
 	publicServiceIns.Status.DerivedResources = derivedResource
+	publicServiceIns.Status.TemplateVersion = templateVersion
+	publicServiceIns.Status.Message = ""
+	appliedMessage := fmt.Sprintf("%d resources applied", len(derivedResource))
+	utils.SetInstanceCondition(&publicServiceIns.Status.Conditions, publicServiceIns.Generation,
+		commonv1alpha1.ConditionResourcesApplied, true, commonv1alpha1.ReasonApplied, appliedMessage)
+	pc.eventRecorder.Event(publicServiceIns, corev1.EventTypeNormal, commonv1alpha1.ReasonApplied, appliedMessage)
 	_, err = pc.openappClient.ServiceV1alpha1().PublicServiceInstances(publicServiceIns.Namespace).
 		UpdateStatus(context.Background(), publicServiceIns, metav1.UpdateOptions{})
 	if err != nil {
@@ -163,37 +267,33 @@ func (pc *PublicServiceInstanceController) Reconcile(resourceKey pkgtypes.Namesp
 }
 
 func (pc *PublicServiceInstanceController) handlePublicServiceInstanceDerivedResourceCreation(pubclicServiceIns *v1alpha1.PublicServiceInstance,
-	manifest string,
+	obj *unstructured.Unstructured,
 	derivedResource *[]commonv1alpha1.DerivedResource) error {
-	values, err := utils.ConstructPublicServiceInstanceValues(pubclicServiceIns)
-	if err != nil {
-		return err
+	labels := map[string]string{
+		utils.PublicServiceInstanceLabelKey: pubclicServiceIns.Name,
+		utils.InstanceGenerationLabelKey:    strconv.Itoa(int(pubclicServiceIns.Generation)),
 	}
-	manifestContent, err := utils.ConstructTemplateWithValues(manifest, values)
+	owner := metav1.NewControllerRef(pubclicServiceIns, v1alpha1.SchemeGroupVersion.WithKind("PublicServiceInstance"))
+	return utils.ApplyDerivedResource(pc.dynamicClient, pc.restMapper,
+		obj, derivedResource, labels, owner)
+}
+
+// updatePublicServiceInstanceCondition records the failed condition on the
+// instance, the message is also shown in the status message and a warning event.
+func (pc *PublicServiceInstanceController) updatePublicServiceInstanceCondition(publicServiceIns *v1alpha1.PublicServiceInstance,
+	conditionType string,
+	ok bool,
+	reason, message string) {
+	pc.eventRecorder.Event(publicServiceIns, corev1.EventTypeWarning, reason, message)
+	insCopy := publicServiceIns.DeepCopy()
+	utils.SetInstanceCondition(&insCopy.Status.Conditions, insCopy.Generation,
+		conditionType, ok, reason, message)
+	insCopy.Status.Message = message
+	_, err := pc.openappClient.ServiceV1alpha1().PublicServiceInstances(insCopy.Namespace).
+		UpdateStatus(context.Background(), insCopy, metav1.UpdateOptions{})
 	if err != nil {
-		klog.Errorf("Failed to construct template with values: %v", err)
-		return err
-	}
-	file := path.Base(manifest)
-	switch file {
-	case utils.TemplateManifestStatefulSetFile:
-		err = pc.statefulsetHandler(manifestContent, derivedResource, pubclicServiceIns)
-		if err != nil {
-			return err
-		}
-	case utils.TemplateManifestConfigMapFile:
-		err = pc.configmapHandler(manifestContent, derivedResource, pubclicServiceIns.Name)
-		if err != nil {
-			return err
-		}
-	case utils.TemplateManifestServiceFile:
-		err = pc.serviceHandler(manifestContent, derivedResource, pubclicServiceIns.Name)
-		if err != nil {
-			return err
-		}
+		klog.Errorf("Failed to update publicservice instance condition: %v", err)
 	}
-
-	return nil
 }
 
 func (pc *PublicServiceInstanceController) deletePublicServiceInstanceResources(publicServiceIns *v1alpha1.PublicServiceInstance) error {
@@ -208,6 +308,8 @@ func (pc *PublicServiceInstanceController) deletePublicServiceInstanceResources(
 	for _, ins := range appIns.Items {
 		if ins.Spec.PublicServiceClass == publicServiceIns.Name {
 			publicServiceIns.Status.Message = "APP instance is using this publicservice instance, cannot be deleted"
+			pc.eventRecorder.Eventf(publicServiceIns, corev1.EventTypeWarning, commonv1alpha1.EventReasonPublicServiceInUse,
+				"APP instance(%s) is using this publicservice instance, cannot be deleted", ins.Name)
 			_, err = pc.openappClient.ServiceV1alpha1().PublicServiceInstances(publicServiceIns.Namespace).
 				UpdateStatus(context.Background(), publicServiceIns, metav1.UpdateOptions{})
 			if err != nil {
@@ -220,25 +322,15 @@ func (pc *PublicServiceInstanceController) deletePublicServiceInstanceResources(
 	}
 
 	for _, d := range publicServiceIns.Status.DerivedResources {
-		switch d.Kind {
-		case utils.InstanceDerivedResourceServiceKind:
-			if err := utils.CleanInstanceDerivedServiceResource(pc.k8sClient,
-				d.Name, publicServiceIns.Namespace); err != nil {
-				return err
-			}
-		case utils.InstanceDerivedResourceConfigMapKind:
-			if err := utils.CleanInstanceDerivedConfigMapResource(pc.k8sClient,
-				d.Name, publicServiceIns.Namespace); err != nil {
-				return err
-			}
-		case utils.InstanceDerivedResourceStatefulSetKind:
-			if err := utils.CleanInstanceDerivedStatefulSetResource(pc.k8sClient,
-				d.Name, publicServiceIns.Namespace); err != nil {
-				return err
-			}
+		if err := utils.CleanInstanceDerivedResource(pc.dynamicClient, pc.restMapper,
+			d, publicServiceIns.Namespace); err != nil {
+			return err
 		}
 	}
 
+	pc.eventRecorder.Eventf(publicServiceIns, corev1.EventTypeNormal, commonv1alpha1.EventReasonDeleted,
+		"Deleted %d derived resources", len(publicServiceIns.Status.DerivedResources))
+
 	publicServiceIns.Finalizers = nil
 	_, err = pc.openappClient.ServiceV1alpha1().PublicServiceInstances(publicServiceIns.Namespace).
 		Update(context.Background(), publicServiceIns, metav1.UpdateOptions{})
@@ -249,40 +341,3 @@ func (pc *PublicServiceInstanceController) deletePublicServiceInstanceResources(
 
 	return nil
 }
-
-func (pc *PublicServiceInstanceController) serviceHandler(manifestContent []byte,
-	derivedResoruce *[]commonv1alpha1.DerivedResource,
-	instanceName string) error {
-	labels := map[string]string{
-		utils.PublicServiceInstanceLabelKey: instanceName,
-	}
-	return utils.CreateOrUpdateService(pc.k8sClient,
-		manifestContent,
-		derivedResoruce,
-		labels)
-}
-
-func (pc *PublicServiceInstanceController) configmapHandler(manifestContent []byte,
-	derivedResoruce *[]commonv1alpha1.DerivedResource,
-	instanceName string) error {
-	labels := map[string]string{
-		utils.PublicServiceInstanceLabelKey: instanceName,
-	}
-	return utils.CreateOrUpdateConfigmap(pc.k8sClient,
-		manifestContent,
-		derivedResoruce,
-		labels)
-}
-
-func (pc *PublicServiceInstanceController) statefulsetHandler(manifestContent []byte,
-	derivedResoruce *[]commonv1alpha1.DerivedResource,
-	publicServiceIns *v1alpha1.PublicServiceInstance) error {
-	labels := map[string]string{
-		utils.PublicServiceInstanceLabelKey: publicServiceIns.Name,
-		utils.InstanceGenerationLabelKey:    strconv.Itoa(int(publicServiceIns.Generation)),
-	}
-	return utils.CreateOrUpdateStatefulset(pc.k8sClient,
-		manifestContent,
-		derivedResoruce,
-		labels)
-}
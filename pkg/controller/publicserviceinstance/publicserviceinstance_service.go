@@ -10,8 +10,10 @@ import (
 	pkgtypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
 
+	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
 	"github.com/openapp-dev/openapp/pkg/controller/types"
 	"github.com/openapp-dev/openapp/pkg/generated/clientset/versioned"
 	"github.com/openapp-dev/openapp/pkg/utils"
@@ -21,6 +23,7 @@ type PublicServiceInstanceServiceController struct {
 	k8sClient     kubernetes.Interface
 	openappClient versioned.Interface
 	workqueue     *utils.WorkQueue
+	eventRecorder record.EventRecorder
 }
 
 func NewPublicServiceInstanceServiceController(openappHandler *utils.OpenAPPHelper) types.ControllerInterface {
@@ -28,6 +31,7 @@ func NewPublicServiceInstanceServiceController(openappHandler *utils.OpenAPPHelp
 	pc.workqueue = utils.NewWorkQueue(pc.Reconcile)
 	pc.k8sClient = openappHandler.K8sClient
 	pc.openappClient = openappHandler.OpenAPPClient
+	pc.eventRecorder = openappHandler.EventRecorder
 
 	handlefunc := func(obj interface{}) {
 		svc, ok := obj.(*corev1.Service)
@@ -121,7 +125,29 @@ func (sc *PublicServiceInstanceServiceController) updatePublicServiceInstanceSer
 	}
 	insCopy := ins.DeepCopy()
 	insCopy.Status.LocalServiceURL = localURL
+	if localURL != "" {
+		utils.SetInstanceCondition(&insCopy.Status.Conditions, insCopy.Generation,
+			commonv1alpha1.ConditionExposed, true, commonv1alpha1.ReasonServiceExposed, "")
+	} else {
+		utils.SetInstanceCondition(&insCopy.Status.Conditions, insCopy.Generation,
+			commonv1alpha1.ConditionExposed, false, commonv1alpha1.ReasonServiceNotFound,
+			"No service is exposed by the publicservice instance")
+	}
 	_, err = sc.openappClient.ServiceV1alpha1().PublicServiceInstances(utils.InstanceNamespace).UpdateStatus(context.Background(), insCopy, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Errorf("Failed to update publicservice instance status: %v", err)
+		return err
+	}
+	if ins.Status.LocalServiceURL == localURL {
+		return nil
+	}
+	if localURL == "" {
+		sc.eventRecorder.Eventf(insCopy, corev1.EventTypeWarning, commonv1alpha1.ReasonServiceNotFound,
+			"No service is exposed by the publicservice instance")
+	} else {
+		sc.eventRecorder.Eventf(insCopy, corev1.EventTypeNormal, commonv1alpha1.EventReasonURLChanged,
+			"Service is exposed at %s", localURL)
+	}
 
-	return err
+	return nil
 }
@@ -2,15 +2,19 @@ package publicserviceinstance
 
 import (
 	"context"
+	"fmt"
 
 	v1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	pkgtypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
 
+	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
 	"github.com/openapp-dev/openapp/pkg/controller/types"
 	"github.com/openapp-dev/openapp/pkg/generated/clientset/versioned"
 	"github.com/openapp-dev/openapp/pkg/utils"
@@ -20,6 +24,7 @@ type PublicServiceInstanceStatusController struct {
 	k8sClient     kubernetes.Interface
 	openappClient versioned.Interface
 	workqueue     *utils.WorkQueue
+	eventRecorder record.EventRecorder
 }
 
 func NewPublicServiceInstanceStatusController(openappHelper *utils.OpenAPPHelper) types.ControllerInterface {
@@ -27,6 +32,7 @@ func NewPublicServiceInstanceStatusController(openappHelper *utils.OpenAPPHelper
 	pc.workqueue = utils.NewWorkQueue(pc.Reconcile)
 	pc.openappClient = openappHelper.OpenAPPClient
 	pc.k8sClient = openappHelper.K8sClient
+	pc.eventRecorder = openappHelper.EventRecorder
 
 	handlefunc := func(obj interface{}) {
 		sts, ok := obj.(*v1.StatefulSet)
@@ -107,12 +113,29 @@ func (pc *PublicServiceInstanceStatusController) Reconcile(resourceKey pkgtypes.
 
 	insCopy := ins.DeepCopy()
 	insCopy.Status.PublicServiceReady = ready
+	if ready {
+		utils.SetInstanceCondition(&insCopy.Status.Conditions, insCopy.Generation,
+			commonv1alpha1.ConditionWorkloadReady, true, commonv1alpha1.ReasonReplicasReady, "")
+	} else {
+		utils.SetInstanceCondition(&insCopy.Status.Conditions, insCopy.Generation,
+			commonv1alpha1.ConditionWorkloadReady, false, commonv1alpha1.ReasonReplicasNotReady,
+			fmt.Sprintf("StatefulSet(%s) has no ready replicas", sts.Name))
+	}
 	_, err = pc.openappClient.ServiceV1alpha1().PublicServiceInstances(utils.InstanceNamespace).
 		UpdateStatus(context.Background(), insCopy, metav1.UpdateOptions{})
 	if err != nil {
 		klog.Errorf("Failed to update publicservice instance status: %v", err)
 		return err
 	}
+	if ins.Status.PublicServiceReady != ready {
+		if ready {
+			pc.eventRecorder.Eventf(insCopy, corev1.EventTypeNormal, commonv1alpha1.ReasonReplicasReady,
+				"StatefulSet(%s) is ready", sts.Name)
+		} else {
+			pc.eventRecorder.Eventf(insCopy, corev1.EventTypeWarning, commonv1alpha1.ReasonReplicasNotReady,
+				"StatefulSet(%s) has no ready replicas", sts.Name)
+		}
+	}
 
 	return nil
 }
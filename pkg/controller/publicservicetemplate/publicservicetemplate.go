@@ -3,46 +3,50 @@ package publicservicetemplate
 import (
 	"context"
 	"os"
+	"path"
 
-	v1 "k8s.io/api/core/v1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	pkgtypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
 
+	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
 	servicev1alpha1 "github.com/openapp-dev/openapp/pkg/apis/service/v1alpha1"
 	"github.com/openapp-dev/openapp/pkg/controller/types"
 	"github.com/openapp-dev/openapp/pkg/generated/clientset/versioned"
+	listerregistryv1alpha1 "github.com/openapp-dev/openapp/pkg/generated/listers/registry/v1alpha1"
+	listerservicev1alpha1 "github.com/openapp-dev/openapp/pkg/generated/listers/service/v1alpha1"
 	"github.com/openapp-dev/openapp/pkg/utils"
 )
 
 type PublicServiceTemplateController struct {
-	openappClient versioned.Interface
-	workqueue     *utils.WorkQueue
+	openappClient  versioned.Interface
+	insLister      listerservicev1alpha1.PublicServiceInstanceLister
+	registryLister listerregistryv1alpha1.RegistryLister
+	eventRecorder  record.EventRecorder
+	workqueue      *utils.WorkQueue
 }
 
 func NewPublicServiceTemplateController(openappHelper *utils.OpenAPPHelper) types.ControllerInterface {
 	pc := &PublicServiceTemplateController{}
 	pc.workqueue = utils.NewWorkQueue(pc.Reconcile)
 	pc.openappClient = openappHelper.OpenAPPClient
+	pc.insLister = openappHelper.PublicServiceInstanceLister
+	pc.registryLister = openappHelper.RegistryLister
+	pc.eventRecorder = openappHelper.EventRecorder
 
-	_, _ = openappHelper.ConfigMapInformer.AddEventHandler(cache.FilteringResourceEventHandler{
-		FilterFunc: func(obj interface{}) bool {
-			cm := obj.(*v1.ConfigMap)
-			if cm.Name != utils.SystemConfigMap || cm.Namespace != utils.SystemNamespace {
-				return false
-			}
-			return true
-		},
-		Handler: cache.ResourceEventHandlerFuncs{
-			AddFunc: func(_ interface{}) {
-				pc.workqueue.Add(pkgtypes.NamespacedName{})
-			},
-			UpdateFunc: func(_, _ interface{}) {
-				pc.workqueue.Add(pkgtypes.NamespacedName{})
-			},
+	_, _ = openappHelper.RegistryInformer.AddEventHandler(utils.NewRegistrySyncedHandler(func() {
+		pc.workqueue.Add(pkgtypes.NamespacedName{})
+	}))
+	// The deprecated templates are pruned once no instance uses them
+	_, _ = openappHelper.PublicServiceInstanceInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: func(_ interface{}) {
+			pc.workqueue.Add(pkgtypes.NamespacedName{})
 		},
 	})
 
@@ -55,8 +59,14 @@ func (ac *PublicServiceTemplateController) Start() {
 
 func (ac *PublicServiceTemplateController) Reconcile(_ pkgtypes.NamespacedName) error {
 	klog.Infof("Reconciling publicservice template...")
+	synced := map[string]bool{}
+	present := map[string]bool{}
 	registries := utils.GetRegistryPaths()
 	for _, registry := range registries {
+		// The cache of the deleted registry may not be removed yet
+		if _, err := ac.registryLister.Get(path.Base(registry)); apierrors.IsNotFound(err) {
+			continue
+		}
 		templates := utils.GetPublicServiceTemplatePath(registry)
 		for _, templateFile := range templates {
 			d, err := os.ReadFile(templateFile)
@@ -69,11 +79,101 @@ func (ac *PublicServiceTemplateController) Reconcile(_ pkgtypes.NamespacedName)
 				klog.Errorf("Failed to unmarshal template: %v", err)
 				continue
 			}
-			if err := createOrUpdateServiceTemplate(ac.openappClient, serviceTemplate); err != nil {
+			// The shadowed template is kept with the name prefixed by its registry,
+			// so it can still be referenced as `registry/template`.
+			registryName := path.Base(registry)
+			tempName := serviceTemplate.Name
+			if synced[tempName] {
+				serviceTemplate.Name = utils.ShadowedTemplateName(registryName, tempName)
+				klog.Warningf("Publicservice template(%s) of %s is shadowed by another registry, synced as %s",
+					tempName, registryName, serviceTemplate.Name)
+			}
+			// The dotted template name may be the same as the shadowed one
+			if present[serviceTemplate.Name] {
+				klog.Warningf("Publicservice template(%s) of %s collides with a template synced before, skip it",
+					serviceTemplate.Name, registryName)
+				continue
+			}
+			synced[tempName] = true
+			present[serviceTemplate.Name] = true
+			utils.SetTemplateSource(serviceTemplate, registryName, tempName)
+			serviceTemplate.Spec.Registry = registryName
+			serviceTemplate.Spec.Versions = utils.ListTemplateVersions(registryName, tempName, utils.PublicServiceTemplateBasePath)
+			err = createOrUpdateServiceTemplate(ac.openappClient, serviceTemplate)
+			// The template rejected by the validation is skipped, the others of
+			// the registries are still synced
+			if apierrors.IsInvalid(err) || apierrors.IsBadRequest(err) {
+				klog.Warningf("Publicservice template(%s) of %s is rejected, skip it: %v", serviceTemplate.Name, registryName, err)
+				continue
+			}
+			if err != nil {
 				return err
 			}
 		}
 	}
+	return ac.pruneServiceTemplates(present)
+}
+
+// pruneServiceTemplates deletes the synced templates which are removed from
+// the registries, the templates still used by instances are marked deprecated.
+func (ac *PublicServiceTemplateController) pruneServiceTemplates(present map[string]bool) error {
+	temps, err := ac.openappClient.ServiceV1alpha1().PublicServiceTemplates().
+		List(context.Background(), metav1.ListOptions{LabelSelector: utils.RegistryLabelKey})
+	if err != nil {
+		klog.Errorf("Failed to list publicservice templates: %v", err)
+		return err
+	}
+	instances, err := ac.insLister.PublicServiceInstances(utils.InstanceNamespace).List(labels.Everything())
+	if err != nil {
+		klog.Errorf("Failed to list publicservice instances: %v", err)
+		return err
+	}
+
+	for i := range temps.Items {
+		temp := &temps.Items[i]
+		registry := temp.Labels[utils.RegistryLabelKey]
+		if present[temp.Name] || !utils.IsRegistryPruneable(ac.registryLister, registry) {
+			continue
+		}
+		users := []*servicev1alpha1.PublicServiceInstance{}
+		for _, ins := range instances {
+			if utils.IsTemplateReferenced(ins.Spec.PublicServiceTemplate, temp) {
+				users = append(users, ins)
+			}
+		}
+
+		if len(users) == 0 {
+			klog.Infof("Pruning publicservice template(%s) removed from registry(%s)...", temp.Name, registry)
+			err := ac.openappClient.ServiceV1alpha1().PublicServiceTemplates().
+				Delete(context.Background(), temp.Name, metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				klog.Errorf("Failed to delete publicservice template(%s): %v", temp.Name, err)
+				return err
+			}
+			continue
+		}
+		if utils.IsTemplateDeprecated(temp) {
+			continue
+		}
+
+		klog.Warningf("Publicservice template(%s) is removed from registry(%s) but used by %d instances",
+			temp.Name, registry, len(users))
+		if temp.Annotations == nil {
+			temp.Annotations = map[string]string{}
+		}
+		temp.Annotations[utils.TemplateDeprecatedAnnotationKey] = "removed from registry " + registry
+		_, err := ac.openappClient.ServiceV1alpha1().PublicServiceTemplates().
+			Update(context.Background(), temp, metav1.UpdateOptions{})
+		if err != nil {
+			klog.Errorf("Failed to deprecate publicservice template(%s): %v", temp.Name, err)
+			return err
+		}
+		for _, ins := range users {
+			ac.eventRecorder.Eventf(ins, corev1.EventTypeWarning, commonv1alpha1.EventReasonTemplateRemoved,
+				"PublicServiceTemplate(%s) is removed from registry(%s), it's deprecated and will be pruned once no instance uses it",
+				temp.Name, registry)
+		}
+	}
 	return nil
 }
 
@@ -94,6 +194,8 @@ func createOrUpdateServiceTemplate(openappClient versioned.Interface, serviceTem
 
 	template := templateExist.DeepCopy()
 	template.Spec = serviceTemplate.Spec
+	utils.SetTemplateSource(template, serviceTemplate.Spec.Registry, utils.GetTemplateName(serviceTemplate))
+	delete(template.Annotations, utils.TemplateDeprecatedAnnotationKey)
 	_, err = openappClient.ServiceV1alpha1().PublicServiceTemplates().Update(context.Background(), template, metav1.UpdateOptions{})
 	if err != nil {
 		klog.Errorf("Failed to update public service template: %v", err)
@@ -0,0 +1,145 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+
+	"github.com/openapp-dev/openapp/pkg/utils"
+)
+
+const (
+	// The keys of the registry credential secret, the ssh and basic auth keys
+	// are the same as the kubernetes.io/ssh-auth and kubernetes.io/basic-auth
+	// secret types.
+	registrySecretSSHKnownHostsKey   = "known_hosts"
+	registrySecretSSHPassphraseKey   = "ssh-passphrase"
+	registrySecretTokenKey           = "token"
+	registrySecretCABundleKey        = "ca.crt"
+	registryDefaultSSHUser           = "git"
+	registryDefaultTokenAuthUsername = "git"
+)
+
+// RegistryCredential configures how the registry with the url is accessed, it's
+// converted to the auth of the Registry migrated from the config.
+type RegistryCredential struct {
+	URL string `json:"url"`
+	// SecretName is the name of the secret in the openapp-system namespace.
+	SecretName string `json:"secretName,omitempty"`
+	// InsecureSkipTLSVerify disables the TLS verification of the registry.
+	InsecureSkipTLSVerify bool `json:"insecureSkipTLSVerify,omitempty"`
+}
+
+// registryAccess is the auth and TLS options to clone and fetch a registry.
+type registryAccess struct {
+	auth            transport.AuthMethod
+	caBundle        []byte
+	insecureSkipTLS bool
+}
+
+// getRegistryCredentials parses the registry credentials of the config, the
+// credentials are indexed by the registry url.
+func getRegistryCredentials(cm *v1.ConfigMap) (map[string]RegistryCredential, error) {
+	credentials := []RegistryCredential{}
+	if err := yaml.Unmarshal([]byte(cm.Data[utils.RegistryCredentialsKey]), &credentials); err != nil {
+		klog.Errorf("Failed to unmarshal registry credentials: %v", err)
+		return nil, err
+	}
+	ret := map[string]RegistryCredential{}
+	for _, credential := range credentials {
+		ret[strings.TrimSpace(credential.URL)] = credential
+	}
+	return ret, nil
+}
+
+// newRegistryAccess loads the auth of the registry from its secret, a ssh
+// private key takes precedence over the token and the basic auth.
+func newRegistryAccess(k8sClient kubernetes.Interface,
+	secretName string,
+	insecureSkipTLSVerify bool) (*registryAccess, error) {
+	access := &registryAccess{insecureSkipTLS: insecureSkipTLSVerify}
+	if secretName == "" {
+		return access, nil
+	}
+
+	secret, err := k8sClient.CoreV1().Secrets(utils.SystemNamespace).
+		Get(context.Background(), secretName, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("Failed to get registry secret(%s): %v", secretName, err)
+		return nil, err
+	}
+	access.caBundle = secret.Data[registrySecretCABundleKey]
+
+	switch {
+	case len(secret.Data[v1.SSHAuthPrivateKey]) != 0:
+		access.auth, err = newSSHAuth(secret)
+		if err != nil {
+			klog.Errorf("Failed to load ssh key of secret(%s): %v", secret.Name, err)
+			return nil, err
+		}
+	case len(secret.Data[registrySecretTokenKey]) != 0:
+		username := string(secret.Data[v1.BasicAuthUsernameKey])
+		if username == "" {
+			username = registryDefaultTokenAuthUsername
+		}
+		access.auth = &http.BasicAuth{
+			Username: username,
+			Password: string(secret.Data[registrySecretTokenKey]),
+		}
+	case len(secret.Data[v1.BasicAuthUsernameKey]) != 0:
+		access.auth = &http.BasicAuth{
+			Username: string(secret.Data[v1.BasicAuthUsernameKey]),
+			Password: string(secret.Data[v1.BasicAuthPasswordKey]),
+		}
+	}
+	return access, nil
+}
+
+// newSSHAuth loads the ssh key of the secret, the host key is checked against
+// the known_hosts of the secret or the default known_hosts files.
+func newSSHAuth(secret *v1.Secret) (*gitssh.PublicKeys, error) {
+	auth, err := gitssh.NewPublicKeys(registryDefaultSSHUser, secret.Data[v1.SSHAuthPrivateKey],
+		string(secret.Data[registrySecretSSHPassphraseKey]))
+	if err != nil {
+		return nil, err
+	}
+
+	knownHosts := secret.Data[registrySecretSSHKnownHostsKey]
+	if len(knownHosts) == 0 {
+		auth.HostKeyCallback, err = gitssh.NewKnownHostsCallback()
+		if err != nil {
+			return nil, fmt.Errorf("no known_hosts in secret and the default known_hosts can't be loaded: %v", err)
+		}
+		return auth, nil
+	}
+
+	// The known hosts are loaded when the callback is created, the file
+	// isn't needed afterwards
+	f, err := os.CreateTemp("", "known_hosts")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(knownHosts); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	auth.HostKeyCallback, err = gitssh.NewKnownHostsCallback(f.Name())
+	if err != nil {
+		return nil, err
+	}
+	return auth, nil
+}
@@ -0,0 +1,85 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/openapp-dev/openapp/pkg/utils"
+)
+
+func TestNewRegistryAccess(t *testing.T) {
+	k8sClient := fake.NewSimpleClientset(
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: utils.SystemNamespace},
+			Data: map[string][]byte{
+				registrySecretTokenKey:    []byte("secret-token"),
+				registrySecretCABundleKey: []byte("ca"),
+			},
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "basic", Namespace: utils.SystemNamespace},
+			Type:       v1.SecretTypeBasicAuth,
+			Data: map[string][]byte{
+				v1.BasicAuthUsernameKey: []byte("openapp"),
+				v1.BasicAuthPasswordKey: []byte("password"),
+			},
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid-ssh", Namespace: utils.SystemNamespace},
+			Type:       v1.SecretTypeSSHAuth,
+			Data: map[string][]byte{
+				v1.SSHAuthPrivateKey: []byte("invalid"),
+			},
+		},
+	)
+
+	access, err := newRegistryAccess(k8sClient, "", false)
+	assert.NoError(t, err)
+	assert.False(t, access.insecureSkipTLS)
+	assert.Nil(t, access.auth)
+
+	access, err = newRegistryAccess(k8sClient, "", true)
+	assert.NoError(t, err)
+	assert.True(t, access.insecureSkipTLS)
+
+	access, err = newRegistryAccess(k8sClient, "token", false)
+	assert.NoError(t, err)
+	assert.Equal(t, &http.BasicAuth{Username: registryDefaultTokenAuthUsername, Password: "secret-token"}, access.auth)
+	assert.Equal(t, []byte("ca"), access.caBundle)
+
+	access, err = newRegistryAccess(k8sClient, "basic", false)
+	assert.NoError(t, err)
+	assert.Equal(t, &http.BasicAuth{Username: "openapp", Password: "password"}, access.auth)
+
+	_, err = newRegistryAccess(k8sClient, "invalid-ssh", false)
+	assert.Error(t, err)
+
+	_, err = newRegistryAccess(k8sClient, "not-exist", false)
+	assert.Error(t, err)
+}
+
+func TestGetRegistryCredentials(t *testing.T) {
+	cm := &v1.ConfigMap{
+		Data: map[string]string{
+			utils.RegistryCredentialsKey: `
+- url: https://example.com/private-registry
+  secretName: private-registry
+  insecureSkipTLSVerify: true
+`,
+		},
+	}
+	credentials, err := getRegistryCredentials(cm)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]RegistryCredential{
+		"https://example.com/private-registry": {
+			URL:                   "https://example.com/private-registry",
+			SecretName:            "private-registry",
+			InsecureSkipTLSVerify: true,
+		},
+	}, credentials)
+}
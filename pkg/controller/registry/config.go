@@ -0,0 +1,130 @@
+package registry
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog"
+
+	registryv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/registry/v1alpha1"
+	"github.com/openapp-dev/openapp/pkg/utils"
+)
+
+var invalidRegistryNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// migrateConfigRegistries keeps a Registry object for each registry of the
+// config, the objects are labeled so they are deleted once removed from the
+// config. The Registry objects created by users are never touched.
+func (rc *RegistryController) migrateConfigRegistries() error {
+	desired, err := rc.getConfigRegistries()
+	if err != nil {
+		return err
+	}
+
+	registryClient := rc.openappClient.RegistryV1alpha1().Registries()
+	migrated, err := rc.registryLister.List(labels.SelectorFromSet(labels.Set{
+		utils.RegistryFromConfigLabelKey: "true",
+	}))
+	if err != nil {
+		klog.Errorf("Failed to list registries: %v", err)
+		return err
+	}
+	for _, registry := range migrated {
+		if _, ok := desired[registry.Name]; ok {
+			continue
+		}
+		err := registryClient.Delete(context.Background(), registry.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			klog.Errorf("Failed to delete registry(%s): %v", registry.Name, err)
+			return err
+		}
+	}
+
+	for name, registry := range desired {
+		existing, err := rc.registryLister.Get(name)
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				klog.Errorf("Failed to get registry(%s): %v", name, err)
+				return err
+			}
+			if _, err := registryClient.Create(context.Background(), registry, metav1.CreateOptions{}); err != nil {
+				klog.Errorf("Failed to create registry(%s): %v", name, err)
+				return err
+			}
+			continue
+		}
+		if existing.Labels[utils.RegistryFromConfigLabelKey] != "true" {
+			klog.Warningf("Registry(%s) of the config is already created, skip it", name)
+			continue
+		}
+
+		registryCopy := existing.DeepCopy()
+		registryCopy.Spec.URL = registry.Spec.URL
+		registryCopy.Spec.Ref = registry.Spec.Ref
+		registryCopy.Spec.AuthSecretName = registry.Spec.AuthSecretName
+		registryCopy.Spec.InsecureSkipTLSVerify = registry.Spec.InsecureSkipTLSVerify
+		if reflect.DeepEqual(registryCopy.Spec, existing.Spec) {
+			continue
+		}
+		if _, err := registryClient.Update(context.Background(), registryCopy, metav1.UpdateOptions{}); err != nil {
+			klog.Errorf("Failed to update registry(%s): %v", name, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getConfigRegistries converts the comma-separated `url@ref` registries and
+// their credentials of the config to Registry objects.
+func (rc *RegistryController) getConfigRegistries() (map[string]*registryv1alpha1.Registry, error) {
+	ret := map[string]*registryv1alpha1.Registry{}
+	cm, err := rc.cmLister.ConfigMaps(utils.SystemNamespace).Get(utils.SystemConfigMap)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return ret, nil
+		}
+		klog.Errorf("Failed to get config: %v", err)
+		return nil, err
+	}
+	credentials, err := getRegistryCredentials(cm)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range strings.Split(cm.Data[utils.RegistryKey], ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		repoURL, ref, dir := getRepoURLAndRef(entry)
+		registry := &registryv1alpha1.Registry{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: getRegistryName(dir),
+				Labels: map[string]string{
+					utils.RegistryFromConfigLabelKey: "true",
+				},
+			},
+			Spec: registryv1alpha1.RegistrySpec{
+				URL: repoURL,
+				Ref: ref,
+			},
+		}
+		if credential, ok := credentials[repoURL]; ok {
+			registry.Spec.AuthSecretName = credential.SecretName
+			registry.Spec.InsecureSkipTLSVerify = credential.InsecureSkipTLSVerify
+		}
+		ret[registry.Name] = registry
+	}
+	return ret, nil
+}
+
+// getRegistryName converts the repository name to a valid object name.
+func getRegistryName(dir string) string {
+	name := strings.TrimSuffix(strings.ToLower(dir), ".git")
+	return strings.Trim(invalidRegistryNameChars.ReplaceAllString(name, "-"), "-.")
+}
@@ -0,0 +1,87 @@
+package registry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	registryv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/registry/v1alpha1"
+	openappfake "github.com/openapp-dev/openapp/pkg/generated/clientset/versioned/fake"
+	listerregistryv1alpha1 "github.com/openapp-dev/openapp/pkg/generated/listers/registry/v1alpha1"
+	"github.com/openapp-dev/openapp/pkg/utils"
+)
+
+func TestMigrateConfigRegistries(t *testing.T) {
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: utils.SystemConfigMap, Namespace: utils.SystemNamespace},
+		Data: map[string]string{
+			utils.RegistryKey: "https://github.com/openapp-dev/openapp-registry@main,\n" +
+				"https://example.com/private/Private_Registry.git@v1.0.0\n",
+			utils.RegistryCredentialsKey: `
+- url: https://example.com/private/Private_Registry.git
+  secretName: private-registry
+`,
+		},
+	}
+	stale := &registryv1alpha1.Registry{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "stale",
+			Labels: map[string]string{utils.RegistryFromConfigLabelKey: "true"},
+		},
+		Spec: registryv1alpha1.RegistrySpec{URL: "https://example.com/stale"},
+	}
+	userManaged := &registryv1alpha1.Registry{
+		ObjectMeta: metav1.ObjectMeta{Name: "openapp-registry"},
+		Spec:       registryv1alpha1.RegistrySpec{URL: "https://example.com/openapp-registry", Priority: 10},
+	}
+
+	cmIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	assert.NoError(t, cmIndexer.Add(cm))
+	registryIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	assert.NoError(t, registryIndexer.Add(stale))
+	assert.NoError(t, registryIndexer.Add(userManaged))
+	openappClient := openappfake.NewSimpleClientset(stale, userManaged)
+	rc := &RegistryController{
+		openappClient:  openappClient,
+		cmLister:       corev1.NewConfigMapLister(cmIndexer),
+		registryLister: listerregistryv1alpha1.NewRegistryLister(registryIndexer),
+	}
+
+	assert.NoError(t, rc.migrateConfigRegistries())
+
+	registries, err := openappClient.RegistryV1alpha1().Registries().List(context.Background(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	got := map[string]registryv1alpha1.RegistrySpec{}
+	for _, registry := range registries.Items {
+		got[registry.Name] = registry.Spec
+	}
+	assert.Equal(t, map[string]registryv1alpha1.RegistrySpec{
+		"openapp-registry": userManaged.Spec,
+		"private-registry": {
+			URL:            "https://example.com/private/Private_Registry.git",
+			Ref:            "v1.0.0",
+			AuthSecretName: "private-registry",
+		},
+	}, got)
+}
+
+func TestNeedSync(t *testing.T) {
+	now := time.Now()
+	registry := &registryv1alpha1.Registry{
+		ObjectMeta: metav1.ObjectMeta{Name: "not-cached", Generation: 1},
+	}
+	assert.True(t, needSync(registry, now))
+
+	registry.Status.LastSyncTime = &metav1.Time{Time: now.Add(-time.Minute)}
+	registry.Status.ObservedGeneration = 1
+	assert.True(t, needSync(registry, now), "registry is not cached")
+
+	registry.Generation = 2
+	assert.True(t, needSync(registry, now), "registry is changed")
+}
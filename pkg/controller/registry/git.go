@@ -0,0 +1,231 @@
+package registry
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	gitv5 "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"k8s.io/klog"
+
+	"github.com/openapp-dev/openapp/pkg/utils"
+)
+
+// pinnedCommitRefName keeps the pinned commit fetched by SHA.
+const pinnedCommitRefName = "refs/openapp/pin"
+
+func CloneOpenAPPRegistry(registryList []string) error {
+	for _, registry := range registryList {
+		repoURL, ref, dir := getRepoURLAndRef(registry)
+		_, err := syncGitRegistry(repoURL, ref, path.Join(utils.RegistryCachePath, dir), &registryAccess{}, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// syncGitRegistry fetches the repository into the path and checks out the ref,
+// the ref can be a branch, a tag or a commit SHA. The default branch is used if
+// no ref is specified. It returns the resolved commit.
+func syncGitRegistry(repoURL, ref, repoPath string, access *registryAccess, verifier *registryVerifier) (string, error) {
+	// The checkout is staged and verified out of the cache, so a failed sync
+	// keeps the content verified last time
+	revision := ""
+	err := replaceCacheDir(repoPath, func(dir string) (string, error) {
+		// The last checkout is reused to fetch the new commits only, the registry
+		// is cloned again if its url is changed or the cache isn't a git repository
+		if isGitRegistryCache(repoPath, repoURL) {
+			if err := utils.CopyDir(repoPath, dir); err != nil {
+				klog.Errorf("Failed to copy registry cache %s: %v", repoPath, err)
+				return "", err
+			}
+		}
+		var err error
+		revision, err = checkoutGitRegistry(repoURL, ref, dir, access, verifier)
+		return dir, err
+	})
+	if err != nil {
+		return "", err
+	}
+	return revision, nil
+}
+
+func isGitRegistryCache(repoPath, repoURL string) bool {
+	r, err := gitv5.PlainOpen(repoPath)
+	if err != nil {
+		return false
+	}
+	remote, err := r.Remote(gitv5.DefaultRemoteName)
+	return err == nil && len(remote.Config().URLs) != 0 && remote.Config().URLs[0] == repoURL
+}
+
+// checkoutGitRegistry clones or fetches the repository in the directory, then
+// checks out the verified commit of the ref.
+func checkoutGitRegistry(repoURL, ref, repoPath string, access *registryAccess, verifier *registryVerifier) (string, error) {
+	r, err := gitv5.PlainClone(repoPath, false, &gitv5.CloneOptions{
+		URL:             repoURL,
+		Auth:            access.auth,
+		CABundle:        access.caBundle,
+		InsecureSkipTLS: access.insecureSkipTLS,
+	})
+	if err != nil {
+		if err != gitv5.ErrRepositoryAlreadyExists {
+			klog.Errorf("Failed to clone registry %s: %v", repoURL, err)
+			return "", err
+		}
+		r, err = gitv5.PlainOpen(repoPath)
+		if err != nil {
+			klog.Errorf("Failed to open registry %s: %v", repoURL, err)
+			return "", err
+		}
+		err = r.Fetch(&gitv5.FetchOptions{
+			RemoteName: gitv5.DefaultRemoteName,
+			RefSpecs: []config.RefSpec{
+				"+refs/heads/*:refs/remotes/origin/*",
+				"+refs/tags/*:refs/tags/*",
+			},
+			Force:           true,
+			Auth:            access.auth,
+			CABundle:        access.caBundle,
+			InsecureSkipTLS: access.insecureSkipTLS,
+		})
+		if err != nil && err != gitv5.NoErrAlreadyUpToDate {
+			klog.Errorf("Failed to fetch registry %s: %v", repoURL, err)
+			return "", err
+		}
+	}
+
+	hash, err := resolveRegistryRef(r, ref, access)
+	if err != nil {
+		klog.Errorf("Failed to resolve ref(%s) of registry %s: %v", ref, repoURL, err)
+		return "", err
+	}
+	if err := verifier.verifyCommit(r, ref, *hash); err != nil {
+		klog.Errorf("Failed to verify %s of registry %s: %v", hash, repoURL, err)
+		return "", err
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		klog.Errorf("Failed to get worktree: %v", err)
+		return "", err
+	}
+	if err := w.Checkout(&gitv5.CheckoutOptions{Hash: *hash, Force: true}); err != nil {
+		klog.Errorf("Failed to checkout %s of registry %s: %v", hash, repoURL, err)
+		return "", err
+	}
+	if err := verifier.verifyDir(repoPath); err != nil {
+		klog.Errorf("Failed to verify %s of registry %s: %v", hash, repoURL, err)
+		return "", err
+	}
+
+	return hash.String(), nil
+}
+
+// resolveRegistryRef resolves the ref as a remote branch, a tag or a commit SHA
+// in order, the remote HEAD is used if the ref is empty.
+func resolveRegistryRef(r *gitv5.Repository, ref string, access *registryAccess) (*plumbing.Hash, error) {
+	if ref == "" {
+		return resolveRemoteHead(r, access)
+	}
+
+	candidates := []plumbing.Revision{
+		plumbing.Revision(plumbing.NewRemoteReferenceName(gitv5.DefaultRemoteName, ref)),
+		plumbing.Revision(plumbing.NewTagReferenceName(ref)),
+	}
+	if isCommitSHA(ref) {
+		candidates = append(candidates, plumbing.Revision(ref))
+	}
+	for _, candidate := range candidates {
+		hash, err := r.ResolveRevision(candidate)
+		if err == nil {
+			return hash, nil
+		}
+	}
+	if !isCommitSHA(ref) {
+		return nil, fmt.Errorf("ref %s is not a branch, tag or commit", ref)
+	}
+	// Only the branches and tags are fetched, the pinned commit out of them can
+	// only be fetched by the full SHA
+	if len(ref) != 40 {
+		return nil, fmt.Errorf("commit %s is not reachable from a branch or tag, pin the full commit SHA to fetch it", ref)
+	}
+	if err := fetchPinnedCommit(r, ref, access); err != nil {
+		return nil, fmt.Errorf("commit %s is not reachable from a branch or tag and can't be fetched: %v", ref, err)
+	}
+	return r.ResolveRevision(plumbing.Revision(ref))
+}
+
+// fetchPinnedCommit fetches the commit by SHA, the remote may refuse it if
+// fetching the unadvertised objects is not allowed.
+func fetchPinnedCommit(r *gitv5.Repository, sha string, access *registryAccess) error {
+	err := r.Fetch(&gitv5.FetchOptions{
+		RemoteName: gitv5.DefaultRemoteName,
+		RefSpecs: []config.RefSpec{
+			config.RefSpec("+" + sha + ":" + pinnedCommitRefName),
+		},
+		Force:           true,
+		Auth:            access.auth,
+		CABundle:        access.caBundle,
+		InsecureSkipTLS: access.insecureSkipTLS,
+	})
+	if err != nil && err != gitv5.NoErrAlreadyUpToDate {
+		return err
+	}
+	return nil
+}
+
+func resolveRemoteHead(r *gitv5.Repository, access *registryAccess) (*plumbing.Hash, error) {
+	remote, err := r.Remote(gitv5.DefaultRemoteName)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := remote.List(&gitv5.ListOptions{
+		Auth:            access.auth,
+		CABundle:        access.caBundle,
+		InsecureSkipTLS: access.insecureSkipTLS,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range refs {
+		if ref.Name() != plumbing.HEAD {
+			continue
+		}
+		if ref.Type() == plumbing.HashReference {
+			hash := ref.Hash()
+			return &hash, nil
+		}
+		branch := ref.Target().Short()
+		return r.ResolveRevision(plumbing.Revision(plumbing.NewRemoteReferenceName(gitv5.DefaultRemoteName, branch)))
+	}
+	return nil, fmt.Errorf("remote HEAD is not found")
+}
+
+// getRepoURLAndRef splits `url@ref` into the repository url, the ref and the
+// directory name of the cached registry.
+func getRepoURLAndRef(registry string) (string, string, string) {
+	repoURL, ref := strings.TrimSpace(registry), ""
+	// The user info of the url also contains "@", the ref is after the path
+	if i := strings.LastIndex(repoURL, "@"); i > 0 && !strings.ContainsAny(repoURL[i:], "/:") {
+		repoURL, ref = repoURL[:i], repoURL[i+1:]
+	}
+	dirs := strings.Split(strings.TrimSuffix(repoURL, "/"), "/")
+	return repoURL, ref, dirs[len(dirs)-1]
+}
+
+// isCommitSHA reports whether the ref looks like a full or abbreviated commit SHA.
+func isCommitSHA(ref string) bool {
+	if len(ref) < 4 || len(ref) > 40 {
+		return false
+	}
+	for _, c := range ref {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
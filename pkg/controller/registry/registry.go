@@ -2,54 +2,71 @@ package registry
 
 import (
 	"context"
+	"errors"
+	"os"
 	"path"
-	"reflect"
-	"strings"
+	"sort"
 	"time"
 
-	gitv5 "github.com/go-git/go-git/v5"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	pkgtypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	corev1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
 
+	registryv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/registry/v1alpha1"
 	"github.com/openapp-dev/openapp/pkg/controller/types"
+	"github.com/openapp-dev/openapp/pkg/generated/clientset/versioned"
+	listerregistryv1alpha1 "github.com/openapp-dev/openapp/pkg/generated/listers/registry/v1alpha1"
 	"github.com/openapp-dev/openapp/pkg/utils"
 )
 
+const (
+	defaultSyncInterval = 30 * time.Minute
+	syncCheckInterval   = time.Minute
+)
+
 type RegistryController struct {
-	k8sClient kubernetes.Interface
-	cmLister  corev1.ConfigMapLister
-	workqueue *utils.WorkQueue
+	k8sClient      kubernetes.Interface
+	openappClient  versioned.Interface
+	cmLister       corev1.ConfigMapLister
+	registryLister listerregistryv1alpha1.RegistryLister
+	localWatcher   *localWatcher
+	workqueue      *utils.WorkQueue
+	eventRecorder  record.EventRecorder
 }
 
 func NewRegistryController(openappHelper *utils.OpenAPPHelper) types.ControllerInterface {
 	rc := &RegistryController{
-		cmLister: openappHelper.ConfigMapLister,
+		cmLister:       openappHelper.ConfigMapLister,
+		registryLister: openappHelper.RegistryLister,
 	}
 	rc.workqueue = utils.NewWorkQueue(rc.Reconcile)
 	rc.k8sClient = openappHelper.K8sClient
+	rc.openappClient = openappHelper.OpenAPPClient
+	rc.eventRecorder = openappHelper.EventRecorder
+	rc.localWatcher = newLocalWatcher(func(name string) {
+		rc.workqueue.Add(pkgtypes.NamespacedName{Name: name})
+	})
+
+	// The registries in the config are migrated to Registry objects
 	_, _ = openappHelper.ConfigMapInformer.AddEventHandler(cache.FilteringResourceEventHandler{
 		FilterFunc: func(obj interface{}) bool {
-			cm := obj.(*v1.ConfigMap)
-			if cm.Name != utils.SystemConfigMap || cm.Namespace != utils.SystemNamespace {
+			cm, ok := obj.(*v1.ConfigMap)
+			if !ok {
 				return false
 			}
-			return true
+			return cm.Name == utils.SystemConfigMap && cm.Namespace == utils.SystemNamespace
 		},
 		Handler: cache.ResourceEventHandlerFuncs{
-			AddFunc: func(obj interface{}) {
-				cm, ok := obj.(*v1.ConfigMap)
-				if !ok {
-					return
-				}
-				rc.workqueue.Add(pkgtypes.NamespacedName{
-					Namespace: cm.Namespace,
-					Name:      cm.Name,
-				})
+			AddFunc: func(_ interface{}) {
+				rc.enqueueConfig()
 			},
 			UpdateFunc: func(oldObj, newObj interface{}) {
 				oldCM, ok := oldObj.(*v1.ConfigMap)
@@ -60,24 +77,57 @@ func NewRegistryController(openappHelper *utils.OpenAPPHelper) types.ControllerI
 				if !ok {
 					return
 				}
-				if reflect.DeepEqual(oldCM.Data, newCM.Data) {
+				if oldCM.Data[utils.RegistryKey] == newCM.Data[utils.RegistryKey] &&
+					oldCM.Data[utils.RegistryCredentialsKey] == newCM.Data[utils.RegistryCredentialsKey] {
 					return
 				}
-				rc.workqueue.Add(pkgtypes.NamespacedName{
-					Namespace: utils.SystemNamespace,
-					Name:      utils.SystemConfigMap,
-				})
+				rc.enqueueConfig()
+			},
+			DeleteFunc: func(_ interface{}) {
+				rc.enqueueConfig()
 			},
-			DeleteFunc: func(obj interface{}) {
-				cm, ok := obj.(*v1.ConfigMap)
+		},
+	})
+
+	_, _ = openappHelper.RegistryInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			registry, ok := obj.(*registryv1alpha1.Registry)
+			if !ok {
+				return
+			}
+			rc.workqueue.Add(pkgtypes.NamespacedName{Name: registry.Name})
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldRegistry, ok := oldObj.(*registryv1alpha1.Registry)
+			if !ok {
+				return
+			}
+			newRegistry, ok := newObj.(*registryv1alpha1.Registry)
+			if !ok {
+				return
+			}
+			// Skip the status update, the sync requested by the webhook is
+			// recorded in the annotation
+			if oldRegistry.Generation == newRegistry.Generation &&
+				oldRegistry.Annotations[utils.RegistrySyncRequestedAnnotationKey] ==
+					newRegistry.Annotations[utils.RegistrySyncRequestedAnnotationKey] {
+				return
+			}
+			rc.workqueue.Add(pkgtypes.NamespacedName{Name: newRegistry.Name})
+		},
+		DeleteFunc: func(obj interface{}) {
+			registry, ok := obj.(*registryv1alpha1.Registry)
+			if !ok {
+				tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
 				if !ok {
 					return
 				}
-				rc.workqueue.Add(pkgtypes.NamespacedName{
-					Namespace: cm.Namespace,
-					Name:      cm.Name,
-				})
-			},
+				registry, ok = tombstone.Obj.(*registryv1alpha1.Registry)
+				if !ok {
+					return
+				}
+			}
+			rc.workqueue.Add(pkgtypes.NamespacedName{Name: registry.Name})
 		},
 	})
 
@@ -87,87 +137,228 @@ func NewRegistryController(openappHelper *utils.OpenAPPHelper) types.ControllerI
 func (rc *RegistryController) Start() {
 	go rc.workqueue.Run()
 
-	ticker := time.NewTicker(time.Minute * 30)
+	ticker := time.NewTicker(syncCheckInterval)
 	for range ticker.C {
-		cm, err := rc.cmLister.ConfigMaps(utils.SystemNamespace).Get(utils.SystemConfigMap)
+		registries, err := rc.registryLister.List(labels.Everything())
 		if err != nil {
-			klog.Errorf("Failed to get config: %v", err)
+			klog.Errorf("Failed to list registries: %v", err)
 			continue
 		}
-		rc.workqueue.Add(pkgtypes.NamespacedName{
-			Namespace: cm.Namespace,
-			Name:      cm.Name,
-		})
+		for _, registry := range registries {
+			if needSync(registry, time.Now()) {
+				rc.workqueue.Add(pkgtypes.NamespacedName{Name: registry.Name})
+			}
+		}
 	}
 }
 
-func (rc *RegistryController) Reconcile(_ pkgtypes.NamespacedName) error {
-	klog.Infof("Reconciling config update...")
-	cm, err := rc.k8sClient.CoreV1().ConfigMaps(utils.SystemNamespace).
-		Get(context.Background(), utils.SystemConfigMap, metav1.GetOptions{})
+func (rc *RegistryController) enqueueConfig() {
+	rc.workqueue.Add(pkgtypes.NamespacedName{
+		Namespace: utils.SystemNamespace,
+		Name:      utils.SystemConfigMap,
+	})
+}
+
+// Reconcile migrates the registries of the config if the key is the config,
+// otherwise it syncs the registry with the key name.
+func (rc *RegistryController) Reconcile(key pkgtypes.NamespacedName) error {
+	if key.Namespace != "" {
+		klog.Infof("Reconciling config update...")
+		return rc.migrateConfigRegistries()
+	}
+
+	klog.Infof("Reconciling registry(%s)...", key.Name)
+	registry, err := rc.registryLister.Get(key.Name)
 	if err != nil {
-		klog.Errorf("Failed to convert object to configmap")
+		if !apierrors.IsNotFound(err) {
+			klog.Errorf("Failed to get registry(%s): %v", key.Name, err)
+			return err
+		}
+		rc.localWatcher.Stop(key.Name)
+		if err := os.RemoveAll(getRegistryCachePath(key.Name)); err != nil {
+			klog.Errorf("Failed to remove registry(%s) cache: %v", key.Name, err)
+			return err
+		}
+		return rc.updateRegistryIndex()
+	}
+	if registry.DeletionTimestamp != nil {
+		return nil
+	}
+	changed := false
+	if registry.Spec.Type == registryv1alpha1.RegistrySourceLocal {
+		// The directory isn't watched after the controller restarts
+		changed = rc.localWatcher.TakeChanged(registry.Name) || !rc.localWatcher.IsWatching(registry.Name)
+	} else {
+		rc.localWatcher.Stop(registry.Name)
+	}
+	if !changed && !needSync(registry, time.Now()) {
 		return nil
 	}
 
-	registries := strings.Split(cm.Data[utils.RegistryKey], ",")
-	if err := CloneOpenAPPRegistry(registries); err != nil {
-		klog.Errorf("Failed to clone openapp registry: %v", err)
-		return err
+	return rc.syncRegistry(registry.DeepCopy())
+}
+
+// needSync reports whether the registry is changed, failed in the last sync,
+// requested to sync, not cached or its sync interval has passed.
+func needSync(registry *registryv1alpha1.Registry, now time.Time) bool {
+	status := registry.Status
+	if status.LastSyncTime == nil || status.LastError != "" || status.ObservedGeneration != registry.Generation {
+		return true
+	}
+	// The last sync time is stored in seconds, the push in the same second may
+	// come after the sync started, so it's synced again.
+	if requested, err := time.Parse(time.RFC3339, registry.Annotations[utils.RegistrySyncRequestedAnnotationKey]); err == nil &&
+		!requested.Truncate(time.Second).Before(status.LastSyncTime.Time) {
+		return true
+	}
+	if _, err := os.Stat(getRegistryCachePath(registry.Name)); err != nil {
+		return true
+	}
+	interval := defaultSyncInterval
+	if registry.Spec.SyncInterval != nil && registry.Spec.SyncInterval.Duration > 0 {
+		interval = registry.Spec.SyncInterval.Duration
+	}
+	return !now.Before(status.LastSyncTime.Add(interval))
+}
+
+func (rc *RegistryController) syncRegistry(registry *registryv1alpha1.Registry) error {
+	start := time.Now()
+	verifier, syncErr := newRegistryVerifier(registry.Spec.Verification)
+	commit := ""
+	if syncErr == nil {
+		commit, syncErr = rc.syncRegistrySource(registry, verifier)
+	}
+	if syncErr == nil && registry.Spec.Type == registryv1alpha1.RegistrySourceLocal {
+		syncErr = rc.localWatcher.Watch(registry.Name, registry.Spec.Path)
+	}
+	if syncErr == nil {
+		syncErr = rc.updateRegistryIndex()
+	}
+	if syncErr == nil {
+		syncErr = utils.SnapshotTemplateVersions()
 	}
 
-	cmCopy := cm.DeepCopy()
-	if cmCopy.Annotations == nil {
-		cmCopy.Annotations = map[string]string{}
+	status := &registry.Status
+	lastCommit := status.LastSyncedCommit
+	status.ObservedGeneration = registry.Generation
+	status.LastSyncTime = &metav1.Time{Time: start}
+	status.LastSyncDuration = &metav1.Duration{Duration: time.Since(start).Round(time.Millisecond)}
+	if syncErr != nil {
+		klog.Errorf("Failed to sync registry(%s): %v", registry.Name, syncErr)
+		rc.eventRecorder.Eventf(registry, v1.EventTypeWarning, registryv1alpha1.ReasonSyncFailed,
+			"Failed to sync registry: %v", syncErr)
+		status.LastError = syncErr.Error()
+		utils.SetInstanceCondition(&status.Conditions, registry.Generation, registryv1alpha1.ConditionSynced,
+			false, registryv1alpha1.ReasonSyncFailed, syncErr.Error())
+	} else {
+		cachePath := getRegistryCachePath(registry.Name)
+		status.LastError = ""
+		status.LastSyncedCommit = commit
+		status.AppTemplateCount = int32(len(utils.GetAppTemplatePath(cachePath)))
+		status.PublicServiceTemplateCount = int32(len(utils.GetPublicServiceTemplatePath(cachePath)))
+		utils.SetInstanceCondition(&status.Conditions, registry.Generation, registryv1alpha1.ConditionSynced,
+			true, registryv1alpha1.ReasonSyncSucceeded, "Checked out commit "+commit)
+		if commit != lastCommit {
+			rc.eventRecorder.Eventf(registry, v1.EventTypeNormal, registryv1alpha1.ReasonSyncSucceeded,
+				"Checked out commit %s", commit)
+		}
 	}
-	cmCopy.Annotations[utils.RegistryUpdateTimeAnnotationKey] = time.Now().Format(time.RFC3339)
-	_, err = rc.k8sClient.CoreV1().ConfigMaps(utils.SystemNamespace).
-		Update(context.Background(), cmCopy, metav1.UpdateOptions{})
+
+	setVerificationStatus(registry, verifier, syncErr)
+
+	_, err := rc.openappClient.RegistryV1alpha1().Registries().
+		UpdateStatus(context.Background(), registry, metav1.UpdateOptions{})
 	if err != nil {
-		klog.Errorf("Failed to update configmap: %v", err)
+		klog.Errorf("Failed to update registry(%s) status: %v", registry.Name, err)
 		return err
 	}
+	return syncErr
+}
 
-	return nil
+func (rc *RegistryController) syncRegistrySource(registry *registryv1alpha1.Registry,
+	verifier *registryVerifier) (string, error) {
+	source, err := newRegistrySource(rc.k8sClient, registry, verifier)
+	if err != nil {
+		return "", err
+	}
+	return source.Sync(getRegistryCachePath(registry.Name))
 }
 
-func CloneOpenAPPRegistry(registryList []string) error {
-	for _, registry := range registryList {
-		/// TBD: Can't clone specific branch, still don't know why
-		repoURL, _, dir := getRepoURLAndBranch(registry)
-		r, err := gitv5.PlainClone(path.Join(utils.RegistryCachePath, dir), false, &gitv5.CloneOptions{
-			URL:             repoURL,
-			InsecureSkipTLS: true,
-		})
-		if err != nil {
-			if err != gitv5.ErrRepositoryAlreadyExists {
-				klog.Errorf("Failed to clone registry %s: %v", registry, err)
-				return err
-			}
-			r, err = gitv5.PlainOpen(path.Join(utils.RegistryCachePath, dir))
-			if err != nil {
-				klog.Errorf("Failed to open registry %s: %v", registry, err)
-				return err
-			}
+// setVerificationStatus records the verification result of the sync, the
+// result is kept if the sync failed before the content is verified.
+func setVerificationStatus(registry *registryv1alpha1.Registry, verifier *registryVerifier, syncErr error) {
+	status := &registry.Status
+	if registry.Spec.Verification == nil {
+		status.Verification = nil
+		meta.RemoveStatusCondition(&status.Conditions, registryv1alpha1.ConditionVerified)
+		return
+	}
+
+	method := registry.Spec.Verification.Method
+	var verifyErr *verificationError
+	switch {
+	case syncErr == nil:
+		status.Verification = &registryv1alpha1.RegistryVerificationStatus{
+			Verified: true,
+			Method:   method,
+			Signer:   verifier.signer,
 		}
-		w, err := r.Worktree()
-		if err != nil {
-			klog.Errorf("Failed to get worktree: %v", err)
-			return err
+		utils.SetInstanceCondition(&status.Conditions, registry.Generation, registryv1alpha1.ConditionVerified,
+			true, registryv1alpha1.ReasonSignatureVerified, "Signed by "+verifier.signer)
+	case errors.As(syncErr, &verifyErr):
+		status.Verification = &registryv1alpha1.RegistryVerificationStatus{
+			Verified: false,
+			Method:   method,
+			Message:  verifyErr.Error(),
+		}
+		utils.SetInstanceCondition(&status.Conditions, registry.Generation, registryv1alpha1.ConditionVerified,
+			false, registryv1alpha1.ReasonVerificationFailed, verifyErr.Error())
+	}
+}
+
+// updateRegistryIndex orders the registries by priority to look up templates,
+// the caches of the registries which don't exist anymore are removed.
+func (rc *RegistryController) updateRegistryIndex() error {
+	registries, err := rc.registryLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("Failed to list registries: %v", err)
+		return err
+	}
+	sort.Slice(registries, func(i, j int) bool {
+		if registries[i].Spec.Priority != registries[j].Spec.Priority {
+			return registries[i].Spec.Priority > registries[j].Spec.Priority
 		}
-		// TBD: it couldn't pull the latest code sometimes
-		err = w.Pull(&gitv5.PullOptions{RemoteName: "origin", InsecureSkipTLS: true, Force: true})
-		if err != nil && err != gitv5.NoErrAlreadyUpToDate {
-			klog.Errorf("Failed to pull registry %s: %v", registry, err)
+		return registries[i].Name < registries[j].Name
+	})
+	names := []string{}
+	existing := map[string]bool{}
+	for _, registry := range registries {
+		names = append(names, registry.Name)
+		existing[registry.Name] = true
+	}
+
+	dirs, err := os.ReadDir(utils.RegistryCachePath)
+	if err != nil && !os.IsNotExist(err) {
+		klog.Errorf("Failed to read registry cache path: %v", err)
+		return err
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() || existing[dir.Name()] {
+			continue
+		}
+		if err := os.RemoveAll(getRegistryCachePath(dir.Name())); err != nil {
+			klog.Errorf("Failed to remove registry(%s) cache: %v", dir.Name(), err)
 			return err
 		}
 	}
 
+	if err := utils.WriteRegistryIndex(names); err != nil {
+		klog.Errorf("Failed to write registry index: %v", err)
+		return err
+	}
 	return nil
 }
 
-func getRepoURLAndBranch(url string) (string, string, string) {
-	infos := strings.Split(url, "@")
-	dirs := strings.Split(infos[0], "/")
-	return infos[0], infos[1], dirs[len(dirs)-1]
+func getRegistryCachePath(name string) string {
+	return path.Join(utils.RegistryCachePath, name)
 }
@@ -1,8 +1,14 @@
 package registry
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	gitv5 "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -13,3 +19,92 @@ func TestCloneOpenAPPRegistry(t *testing.T) {
 
 	assert.NoError(t, err)
 }
+
+func TestGetRepoURLAndRef(t *testing.T) {
+	tests := []struct {
+		registry string
+		url      string
+		ref      string
+		dir      string
+	}{
+		{"https://github.com/openapp-dev/openapp-registry@main", "https://github.com/openapp-dev/openapp-registry", "main", "openapp-registry"},
+		{" https://github.com/openapp-dev/openapp-registry@v1.0.0\n", "https://github.com/openapp-dev/openapp-registry", "v1.0.0", "openapp-registry"},
+		{"https://github.com/openapp-dev/openapp-registry", "https://github.com/openapp-dev/openapp-registry", "", "openapp-registry"},
+		{"https://user@example.com/openapp-registry", "https://user@example.com/openapp-registry", "", "openapp-registry"},
+		{"git@github.com:openapp-dev/openapp-registry@3f2a9c1", "git@github.com:openapp-dev/openapp-registry", "3f2a9c1", "openapp-registry"},
+	}
+	for _, tt := range tests {
+		url, ref, dir := getRepoURLAndRef(tt.registry)
+		assert.Equal(t, tt.url, url)
+		assert.Equal(t, tt.ref, ref)
+		assert.Equal(t, tt.dir, dir)
+	}
+}
+
+func TestResolveRegistryRef(t *testing.T) {
+	srcDir := t.TempDir()
+	src, err := gitv5.PlainInit(srcDir, false)
+	assert.NoError(t, err)
+	w, err := src.Worktree()
+	assert.NoError(t, err)
+	sig := &object.Signature{Name: "openapp", Email: "openapp@openapp.dev", When: time.Now()}
+
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "README.md"), []byte("v1"), 0644))
+	_, err = w.Add("README.md")
+	assert.NoError(t, err)
+	first, err := w.Commit("first", &gitv5.CommitOptions{Author: sig})
+	assert.NoError(t, err)
+	_, err = src.CreateTag("v1.0.0", first, &gitv5.CreateTagOptions{Tagger: sig, Message: "v1.0.0"})
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "README.md"), []byte("v2"), 0644))
+	_, err = w.Add("README.md")
+	assert.NoError(t, err)
+	second, err := w.Commit("second", &gitv5.CommitOptions{Author: sig})
+	assert.NoError(t, err)
+	head, err := src.Head()
+	assert.NoError(t, err)
+
+	r, err := gitv5.PlainClone(t.TempDir(), false, &gitv5.CloneOptions{URL: srcDir})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		ref  string
+		want plumbing.Hash
+	}{
+		{"", second},
+		{head.Name().Short(), second},
+		{"v1.0.0", first},
+		{first.String(), first},
+		{first.String()[:7], first},
+	}
+	for _, tt := range tests {
+		hash, err := resolveRegistryRef(r, tt.ref, &registryAccess{})
+		assert.NoError(t, err, tt.ref)
+		if assert.NotNil(t, hash, tt.ref) {
+			assert.Equal(t, tt.want, *hash, tt.ref)
+		}
+	}
+
+	_, err = resolveRegistryRef(r, "not-exist", &registryAccess{})
+	assert.Error(t, err)
+
+	// The commit out of the branches and tags is fetched by the full SHA
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "README.md"), []byte("v3"), 0644))
+	_, err = w.Add("README.md")
+	assert.NoError(t, err)
+	unreachable, err := w.Commit("unreachable", &gitv5.CommitOptions{Author: sig})
+	assert.NoError(t, err)
+	assert.NoError(t, src.Storer.SetReference(plumbing.NewHashReference(head.Name(), second)))
+	cfg, err := src.Config()
+	assert.NoError(t, err)
+	cfg.Raw.Section("uploadpack").SetOption("allowAnySHA1InWant", "true")
+	assert.NoError(t, src.SetConfig(cfg))
+
+	_, err = resolveRegistryRef(r, unreachable.String()[:7], &registryAccess{})
+	assert.ErrorContains(t, err, "pin the full commit SHA")
+	hash, err := resolveRegistryRef(r, unreachable.String(), &registryAccess{})
+	assert.NoError(t, err)
+	if assert.NotNil(t, hash) {
+		assert.Equal(t, unreachable, *hash)
+	}
+}
@@ -0,0 +1,182 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+
+	registryv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/registry/v1alpha1"
+	"github.com/openapp-dev/openapp/pkg/utils"
+)
+
+// RegistrySource fetches the templates of a registry into the cache.
+type RegistrySource interface {
+	// Sync fetches the registry into the cache path, it returns the revision
+	// of the fetched content.
+	Sync(cachePath string) (string, error)
+}
+
+func newRegistrySource(k8sClient kubernetes.Interface, registry *registryv1alpha1.Registry,
+	verifier *registryVerifier) (RegistrySource, error) {
+	if verifier != nil && verifier.method == registryv1alpha1.RegistryVerificationCommit &&
+		registry.Spec.Type != registryv1alpha1.RegistrySourceGit && registry.Spec.Type != "" {
+		return nil, fmt.Errorf("commit verification is only supported by git registry")
+	}
+
+	switch registry.Spec.Type {
+	case registryv1alpha1.RegistrySourceGit, "":
+		if registry.Spec.URL == "" {
+			return nil, fmt.Errorf("url is required for git registry")
+		}
+		access, err := newRegistryAccess(k8sClient, registry.Spec.AuthSecretName, registry.Spec.InsecureSkipTLSVerify)
+		if err != nil {
+			return nil, err
+		}
+		return &gitSource{url: registry.Spec.URL, ref: registry.Spec.Ref, access: access, verifier: verifier}, nil
+	case registryv1alpha1.RegistrySourceLocal:
+		if registry.Spec.Path == "" {
+			return nil, fmt.Errorf("path is required for local registry")
+		}
+		return &localSource{path: registry.Spec.Path, verifier: verifier}, nil
+	case registryv1alpha1.RegistrySourceArchive:
+		if registry.Spec.URL == "" {
+			return nil, fmt.Errorf("url is required for archive registry")
+		}
+		access, err := newRegistryAccess(k8sClient, registry.Spec.AuthSecretName, registry.Spec.InsecureSkipTLSVerify)
+		if err != nil {
+			return nil, err
+		}
+		return &archiveSource{
+			url:      registry.Spec.URL,
+			checksum: registry.Spec.Checksum,
+			access:   access,
+			verifier: verifier,
+		}, nil
+	case registryv1alpha1.RegistrySourceOCI:
+		if registry.Spec.URL == "" && registry.Spec.Path == "" {
+			return nil, fmt.Errorf("url or path is required for OCI registry")
+		}
+		access, err := newRegistryAccess(k8sClient, registry.Spec.AuthSecretName, registry.Spec.InsecureSkipTLSVerify)
+		if err != nil {
+			return nil, err
+		}
+		return &ociSource{
+			url:        registry.Spec.URL,
+			layoutPath: registry.Spec.Path,
+			ref:        registry.Spec.Ref,
+			access:     access,
+			verifier:   verifier,
+		}, nil
+	default:
+		return nil, fmt.Errorf("registry type %s is not supported", registry.Spec.Type)
+	}
+}
+
+type gitSource struct {
+	url      string
+	ref      string
+	access   *registryAccess
+	verifier *registryVerifier
+}
+
+func (s *gitSource) Sync(cachePath string) (string, error) {
+	return syncGitRegistry(s.url, s.ref, cachePath, s.access, s.verifier)
+}
+
+type localSource struct {
+	path     string
+	verifier *registryVerifier
+}
+
+func (s *localSource) Sync(cachePath string) (string, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		klog.Errorf("Failed to read local registry %s: %v", s.path, err)
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("local registry %s is not a directory", s.path)
+	}
+
+	digest, err := hashDir(s.path)
+	if err != nil {
+		klog.Errorf("Failed to hash local registry %s: %v", s.path, err)
+		return "", err
+	}
+	err = replaceCacheDir(cachePath, func(dir string) (string, error) {
+		if err := utils.CopyDir(s.path, dir); err != nil {
+			return "", err
+		}
+		return dir, s.verifier.verifyDir(dir)
+	})
+	if err != nil {
+		klog.Errorf("Failed to copy local registry %s: %v", s.path, err)
+		return "", err
+	}
+	return digest, nil
+}
+
+// replaceCacheDir fills a temporary directory and swaps it with the cache, so
+// the cache is never read half written. The fill func returns the directory
+// to use as the registry root, which is the temporary directory or a child.
+func replaceCacheDir(cachePath string, fill func(dir string) (string, error)) error {
+	// The temporary directory is out of the registry cache path, otherwise
+	// it would be taken as a registry
+	cacheRoot := path.Dir(cachePath)
+	if err := os.MkdirAll(cacheRoot, 0755); err != nil {
+		return err
+	}
+	tmpDir, err := os.MkdirTemp(path.Dir(cacheRoot), "registry-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	root, err := fill(tmpDir)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(cachePath); err != nil {
+		return err
+	}
+	return os.Rename(root, cachePath)
+}
+
+// hashDir returns the digest of the file paths and contents in the directory.
+func hashDir(dir string) (string, error) {
+	h := sha256.New()
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		fmt.Fprintf(h, "%s\x00%d\x00", rel, info.Size())
+		_, err = io.Copy(h, f)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
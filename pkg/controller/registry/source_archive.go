@@ -0,0 +1,203 @@
+package registry
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"k8s.io/klog"
+
+	"github.com/openapp-dev/openapp/pkg/utils"
+)
+
+const (
+	archiveDownloadTimeout = 5 * time.Minute
+	// archiveMaxFileSize limits the size of the unpacked files to avoid a
+	// decompression bomb filling the disk.
+	archiveMaxFileSize = 64 << 20
+)
+
+type archiveSource struct {
+	url      string
+	checksum string
+	access   *registryAccess
+	verifier *registryVerifier
+}
+
+func (s *archiveSource) Sync(cachePath string) (string, error) {
+	f, err := os.CreateTemp("", "registry-*.tar.gz")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	digest, err := s.download(f)
+	if err != nil {
+		klog.Errorf("Failed to download registry archive %s: %v", s.url, err)
+		return "", err
+	}
+	if s.checksum != "" && !strings.EqualFold(s.checksum, digest) {
+		return "", fmt.Errorf("checksum of archive %s is %s, expected %s", s.url, digest, s.checksum)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	err = replaceCacheDir(cachePath, func(dir string) (string, error) {
+		if err := unpackArchive(f, dir); err != nil {
+			return "", err
+		}
+		root := archiveRoot(dir)
+		return root, s.verifier.verifyDir(root)
+	})
+	if err != nil {
+		klog.Errorf("Failed to unpack registry archive %s: %v", s.url, err)
+		return "", err
+	}
+	return digest, nil
+}
+
+// download writes the archive into the file and returns its digest.
+func (s *archiveSource) download(f *os.File) (string, error) {
+	client, err := newHTTPClient(s.access)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return "", err
+	}
+	switch auth := s.access.auth.(type) {
+	case nil:
+	case *githttp.BasicAuth:
+		req.SetBasicAuth(auth.Username, auth.Password)
+	default:
+		return "", fmt.Errorf("auth %s is not supported for archive registry", auth.Name())
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), resp.Body); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// newHTTPClient returns the http client honoring the TLS options of the access.
+func newHTTPClient(access *registryAccess) (*http.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: access.insecureSkipTLS}
+	if len(access.caBundle) != 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(access.caBundle) {
+			return nil, fmt.Errorf("invalid ca bundle")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport, Timeout: archiveDownloadTimeout}, nil
+}
+
+// unpackArchive unpacks the directories and regular files of the .tar.gz, the
+// entries escaping the directory are refused.
+func unpackArchive(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.Clean(hdr.Name)
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("archive entry %s is out of the registry", hdr.Name)
+		}
+		target := filepath.Join(dir, name)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if hdr.Size > archiveMaxFileSize {
+				return fmt.Errorf("archive entry %s is too large", hdr.Name)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := writeArchiveFile(target, tr, hdr.Size); err != nil {
+				return err
+			}
+		default:
+			// Links and special files aren't needed by the templates
+			continue
+		}
+	}
+}
+
+func writeArchiveFile(target string, r io.Reader, size int64) error {
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.CopyN(f, r, size); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// archiveRoot returns the directory containing the templates, it's the only
+// top-level directory of the archives generated by the git hosts, e.g.
+// `openapp-registry-main/`.
+func archiveRoot(dir string) string {
+	if hasTemplateDirs(dir) {
+		return dir
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 || !entries[0].IsDir() {
+		return dir
+	}
+	return filepath.Join(dir, entries[0].Name())
+}
+
+func hasTemplateDirs(dir string) bool {
+	for _, name := range []string{utils.AppTemplatePath, utils.PublicServiceTemplatePath} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
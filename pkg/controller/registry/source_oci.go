@@ -0,0 +1,172 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"k8s.io/klog"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/content/oci"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+const (
+	// RegistryArtifactType is the artifact type of the template bundles.
+	RegistryArtifactType = "application/vnd.openapp.registry.v1"
+	// RegistryLayerMediaType is the media type of the .tar.gz layer containing
+	// the app-template and publicservice-template directories.
+	RegistryLayerMediaType = "application/vnd.openapp.registry.layer.v1.tar+gzip"
+
+	defaultOCIRef = "latest"
+)
+
+type ociSource struct {
+	url        string
+	layoutPath string
+	ref        string
+	access     *registryAccess
+	verifier   *registryVerifier
+}
+
+func (s *ociSource) Sync(cachePath string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), archiveDownloadTimeout)
+	defer cancel()
+
+	target, err := s.target(ctx)
+	if err != nil {
+		klog.Errorf("Failed to open OCI registry %s: %v", s.name(), err)
+		return "", err
+	}
+	ref := s.ref
+	if ref == "" {
+		ref = defaultOCIRef
+	}
+	desc, err := target.Resolve(ctx, ref)
+	if err != nil {
+		klog.Errorf("Failed to resolve %s of OCI registry %s: %v", ref, s.name(), err)
+		return "", err
+	}
+	if pinned, err := digest.Parse(ref); err == nil && pinned != desc.Digest {
+		return "", fmt.Errorf("digest of %s is %s, expected %s", s.name(), desc.Digest, pinned)
+	}
+
+	// The fetched content is verified against the digest of its descriptor
+	manifestContent, err := content.FetchAll(ctx, target, desc)
+	if err != nil {
+		klog.Errorf("Failed to fetch manifest of OCI registry %s: %v", s.name(), err)
+		return "", err
+	}
+	manifest := ocispec.Manifest{}
+	if err := json.Unmarshal(manifestContent, &manifest); err != nil {
+		return "", fmt.Errorf("invalid manifest of %s: %v", s.name(), err)
+	}
+	layer, err := getRegistryLayer(&manifest)
+	if err != nil {
+		return "", err
+	}
+
+	rc, err := target.Fetch(ctx, layer)
+	if err != nil {
+		klog.Errorf("Failed to fetch layer of OCI registry %s: %v", s.name(), err)
+		return "", err
+	}
+	defer rc.Close()
+	vr := content.NewVerifyReader(rc, layer)
+	err = replaceCacheDir(cachePath, func(dir string) (string, error) {
+		if err := unpackArchive(vr, dir); err != nil {
+			return "", err
+		}
+		if _, err := io.Copy(io.Discard, vr); err != nil {
+			return "", err
+		}
+		if err := vr.Verify(); err != nil {
+			return "", err
+		}
+		root := archiveRoot(dir)
+		return root, s.verifier.verifyDir(root)
+	})
+	if err != nil {
+		klog.Errorf("Failed to unpack OCI registry %s: %v", s.name(), err)
+		return "", err
+	}
+	return desc.Digest.String(), nil
+}
+
+func (s *ociSource) name() string {
+	if s.layoutPath != "" {
+		return s.layoutPath
+	}
+	return s.url
+}
+
+// target returns the OCI image layout if the layout path is set, otherwise
+// the remote repository.
+func (s *ociSource) target(ctx context.Context) (oras.ReadOnlyTarget, error) {
+	if s.layoutPath != "" {
+		return oci.NewFromFS(ctx, os.DirFS(s.layoutPath))
+	}
+
+	reference, plainHTTP := parseOCIURL(s.url)
+	repo, err := remote.NewRepository(reference)
+	if err != nil {
+		return nil, err
+	}
+	repo.PlainHTTP = plainHTTP
+	client, err := newHTTPClient(s.access)
+	if err != nil {
+		return nil, err
+	}
+	authClient := &auth.Client{Client: client, Cache: auth.NewCache()}
+	switch a := s.access.auth.(type) {
+	case nil:
+	case *githttp.BasicAuth:
+		authClient.Credential = auth.StaticCredential(repo.Reference.Registry, auth.Credential{
+			Username: a.Username,
+			Password: a.Password,
+		})
+	default:
+		return nil, fmt.Errorf("auth %s is not supported for OCI registry", a.Name())
+	}
+	repo.Client = authClient
+	return repo, nil
+}
+
+// parseOCIURL returns the repository reference of the url and whether it's
+// accessed via plain HTTP.
+func parseOCIURL(url string) (string, bool) {
+	switch {
+	case strings.HasPrefix(url, "http://"):
+		return strings.TrimPrefix(url, "http://"), true
+	case strings.HasPrefix(url, "https://"):
+		return strings.TrimPrefix(url, "https://"), false
+	default:
+		return strings.TrimPrefix(url, "oci://"), false
+	}
+}
+
+// getRegistryLayer returns the template bundle layer, the only .tar.gz layer
+// is taken if no layer has the registry media type.
+func getRegistryLayer(manifest *ocispec.Manifest) (ocispec.Descriptor, error) {
+	candidates := []ocispec.Descriptor{}
+	for _, layer := range manifest.Layers {
+		switch layer.MediaType {
+		case RegistryLayerMediaType:
+			return layer, nil
+		case ocispec.MediaTypeImageLayerGzip:
+			candidates = append(candidates, layer)
+		}
+	}
+	if len(candidates) != 1 {
+		return ocispec.Descriptor{}, fmt.Errorf("no %s layer in the artifact", RegistryLayerMediaType)
+	}
+	return candidates[0], nil
+}
@@ -0,0 +1,67 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/content/oci"
+)
+
+// newTestOCILayout pushes the template bundle into an OCI image layout, it
+// stands in for a remote registry.
+func newTestOCILayout(t *testing.T, files map[string]string) (string, string) {
+	ctx := context.Background()
+	layoutPath := t.TempDir()
+	store, err := oci.New(layoutPath)
+	assert.NoError(t, err)
+
+	archive := newTestArchive(t, files)
+	layer := content.NewDescriptorFromBytes(RegistryLayerMediaType, archive)
+	assert.NoError(t, store.Push(ctx, layer, bytes.NewReader(archive)))
+	manifest, err := oras.PackManifest(ctx, store, oras.PackManifestVersion1_1, RegistryArtifactType,
+		oras.PackManifestOptions{Layers: []ocispec.Descriptor{layer}})
+	assert.NoError(t, err)
+	assert.NoError(t, store.Tag(ctx, manifest, "v1"))
+	return layoutPath, manifest.Digest.String()
+}
+
+func TestOCISource(t *testing.T) {
+	layoutPath, manifestDigest := newTestOCILayout(t, map[string]string{
+		"app-template/nginx/template.yaml": "nginx",
+	})
+
+	cachePath := filepath.Join(t.TempDir(), "registry", "oci")
+	source := &ociSource{layoutPath: layoutPath, ref: "v1", access: &registryAccess{}}
+	revision, err := source.Sync(cachePath)
+	assert.NoError(t, err)
+	assert.Equal(t, manifestDigest, revision)
+	content, err := os.ReadFile(filepath.Join(cachePath, "app-template", "nginx", "template.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "nginx", string(content))
+
+	source.ref = manifestDigest
+	revision, err = source.Sync(cachePath)
+	assert.NoError(t, err)
+	assert.Equal(t, manifestDigest, revision)
+
+	source.ref = "v2"
+	_, err = source.Sync(cachePath)
+	assert.Error(t, err)
+}
+
+func TestParseOCIURL(t *testing.T) {
+	reference, plainHTTP := parseOCIURL("oci://ghcr.io/openapp-dev/registry")
+	assert.Equal(t, "ghcr.io/openapp-dev/registry", reference)
+	assert.False(t, plainHTTP)
+
+	reference, plainHTTP = parseOCIURL("http://localhost:5000/registry")
+	assert.Equal(t, "localhost:5000/registry", reference)
+	assert.True(t, plainHTTP)
+}
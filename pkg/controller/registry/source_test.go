@@ -0,0 +1,158 @@
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	gitv5 "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+
+	registryv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/registry/v1alpha1"
+)
+
+func newTestArchive(t *testing.T, files map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		assert.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := tw.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestLocalSource(t *testing.T) {
+	localDir := t.TempDir()
+	templateFile := filepath.Join(localDir, "app-template", "nginx", "template.yaml")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(templateFile), 0755))
+	assert.NoError(t, os.WriteFile(templateFile, []byte("v1"), 0644))
+
+	cachePath := filepath.Join(t.TempDir(), "registry", "local")
+	source := &localSource{path: localDir}
+	digest, err := source.Sync(cachePath)
+	assert.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(cachePath, "app-template", "nginx", "template.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", string(content))
+
+	sameDigest, err := source.Sync(cachePath)
+	assert.NoError(t, err)
+	assert.Equal(t, digest, sameDigest)
+
+	assert.NoError(t, os.WriteFile(templateFile, []byte("v2"), 0644))
+	newDigest, err := source.Sync(cachePath)
+	assert.NoError(t, err)
+	assert.NotEqual(t, digest, newDigest)
+}
+
+func TestArchiveSource(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{
+		"openapp-registry-main/app-template/nginx/template.yaml": "nginx",
+	})
+	sum := sha256.Sum256(archive)
+	checksum := "sha256:" + hex.EncodeToString(sum[:])
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(archive)
+	}))
+	defer server.Close()
+
+	cachePath := filepath.Join(t.TempDir(), "registry", "archive")
+	source := &archiveSource{url: server.URL, checksum: checksum, access: &registryAccess{}}
+	digest, err := source.Sync(cachePath)
+	assert.NoError(t, err)
+	assert.Equal(t, checksum, digest)
+	content, err := os.ReadFile(filepath.Join(cachePath, "app-template", "nginx", "template.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "nginx", string(content))
+
+	source.checksum = "sha256:0000"
+	_, err = source.Sync(cachePath)
+	assert.Error(t, err)
+}
+
+func TestUnpackArchiveRefusesEscapedEntries(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"../evil": "evil"})
+	err := unpackArchive(bytes.NewReader(archive), t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestLocalWatcher(t *testing.T) {
+	dir := t.TempDir()
+	changed := make(chan string, 1)
+	lw := newLocalWatcher(func(name string) {
+		changed <- name
+	})
+	assert.NoError(t, lw.Watch("local", dir))
+	defer lw.Stop("local")
+	assert.True(t, lw.IsWatching("local"))
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "template.yaml"), []byte("v1"), 0644))
+	select {
+	case name := <-changed:
+		assert.Equal(t, "local", name)
+	case <-time.After(10 * time.Second):
+		t.Fatal("change of the local registry is not notified")
+	}
+	assert.True(t, lw.TakeChanged("local"))
+	assert.False(t, lw.TakeChanged("local"))
+}
+
+func TestGitSourceKeepsVerifiedCheckout(t *testing.T) {
+	verifier, entity := newTestVerifier(t, registryv1alpha1.RegistryVerificationCommit)
+	repoDir := t.TempDir()
+	r, err := gitv5.PlainInit(repoDir, false)
+	assert.NoError(t, err)
+	w, err := r.Worktree()
+	assert.NoError(t, err)
+	sig := &object.Signature{Name: "openapp", Email: "openapp@openapp.dev", When: time.Now()}
+	commit := func(content string, signKey *openpgp.Entity) plumbing.Hash {
+		assert.NoError(t, os.WriteFile(filepath.Join(repoDir, "README.md"), []byte(content), 0644))
+		_, err := w.Add("README.md")
+		assert.NoError(t, err)
+		hash, err := w.Commit(content, &gitv5.CommitOptions{Author: sig, SignKey: signKey})
+		assert.NoError(t, err)
+		return hash
+	}
+
+	signed := commit("v1", entity)
+	cachePath := filepath.Join(t.TempDir(), "registry", "git")
+	source := &gitSource{url: repoDir, access: &registryAccess{}, verifier: verifier}
+	revision, err := source.Sync(cachePath)
+	assert.NoError(t, err)
+	assert.Equal(t, signed.String(), revision)
+
+	// The unsigned commit is never checked out into the cache
+	commit("v2", nil)
+	_, err = source.Sync(cachePath)
+	assert.ErrorContains(t, err, "not signed")
+	content, err := os.ReadFile(filepath.Join(cachePath, "README.md"))
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", string(content))
+
+	commit("v3", entity)
+	_, err = source.Sync(cachePath)
+	assert.NoError(t, err)
+	content, err = os.ReadFile(filepath.Join(cachePath, "README.md"))
+	assert.NoError(t, err)
+	assert.Equal(t, "v3", string(content))
+}
@@ -0,0 +1,239 @@
+package registry
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	gitv5 "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	registryv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/registry/v1alpha1"
+)
+
+const (
+	// RegistryManifestFileName lists the sha256 digests of all the registry
+	// files in the format of `sha256sum`, e.g. `sha256sum $(git ls-files)`.
+	RegistryManifestFileName = "openapp-registry.sha256"
+	// RegistryManifestSignatureFileName is the ASCII armored detached signature
+	// of the manifest, e.g. `gpg --armor --detach-sign openapp-registry.sha256`.
+	RegistryManifestSignatureFileName = RegistryManifestFileName + ".asc"
+)
+
+// verificationError is the error of the registry content not signed by the
+// trusted keys, it's distinguished from the errors fetching the registry.
+type verificationError struct {
+	err error
+}
+
+func (e *verificationError) Error() string {
+	return e.err.Error()
+}
+
+func newVerificationError(format string, a ...interface{}) error {
+	return &verificationError{err: fmt.Errorf(format, a...)}
+}
+
+// registryVerifier verifies the content of the registry before it's used, the
+// nil verifier accepts any content.
+type registryVerifier struct {
+	method     registryv1alpha1.RegistryVerificationMethod
+	publicKeys string
+	keyring    openpgp.EntityList
+	// signer is the signer of the last verified content
+	signer string
+}
+
+func newRegistryVerifier(verification *registryv1alpha1.RegistryVerification) (*registryVerifier, error) {
+	if verification == nil {
+		return nil, nil
+	}
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(verification.PublicKeys))
+	if err != nil {
+		return nil, fmt.Errorf("invalid public keys: %v", err)
+	}
+	return &registryVerifier{
+		method:     verification.Method,
+		publicKeys: verification.PublicKeys,
+		keyring:    keyring,
+	}, nil
+}
+
+// verifyCommit verifies the signature of the tag if the ref is a signed
+// annotated tag of the commit, otherwise the signature of the commit.
+func (v *registryVerifier) verifyCommit(r *gitv5.Repository, ref string, hash plumbing.Hash) error {
+	if v == nil || v.method != registryv1alpha1.RegistryVerificationCommit {
+		return nil
+	}
+
+	if tag := getSignedTag(r, ref, hash); tag != nil {
+		entity, err := tag.Verify(v.publicKeys)
+		if err != nil {
+			return newVerificationError("failed to verify signature of tag %s: %v", ref, err)
+		}
+		v.signer = getSigner(entity)
+		return nil
+	}
+
+	commit, err := r.CommitObject(hash)
+	if err != nil {
+		return err
+	}
+	if commit.PGPSignature == "" {
+		return newVerificationError("commit %s is not signed", hash)
+	}
+	entity, err := commit.Verify(v.publicKeys)
+	if err != nil {
+		return newVerificationError("failed to verify signature of commit %s: %v", hash, err)
+	}
+	v.signer = getSigner(entity)
+	return nil
+}
+
+// getSignedTag returns the signed annotated tag of the ref, it's nil if the ref
+// isn't such a tag of the commit, e.g. a branch with the same name is used.
+func getSignedTag(r *gitv5.Repository, ref string, hash plumbing.Hash) *object.Tag {
+	if ref == "" {
+		return nil
+	}
+	tagRef, err := r.Tag(ref)
+	if err != nil {
+		return nil
+	}
+	tag, err := r.TagObject(tagRef.Hash())
+	if err != nil || tag.PGPSignature == "" {
+		return nil
+	}
+	if commit, err := tag.Commit(); err != nil || commit.Hash != hash {
+		return nil
+	}
+	return tag
+}
+
+// verifyDir verifies the signature of the manifest in the directory, and the
+// files of the directory are exactly the ones listed in the manifest.
+func (v *registryVerifier) verifyDir(dir string) error {
+	if v == nil || v.method != registryv1alpha1.RegistryVerificationManifest {
+		return nil
+	}
+
+	manifest, err := os.ReadFile(filepath.Join(dir, RegistryManifestFileName))
+	if err != nil {
+		return newVerificationError("failed to read manifest: %v", err)
+	}
+	signature, err := os.ReadFile(filepath.Join(dir, RegistryManifestSignatureFileName))
+	if err != nil {
+		return newVerificationError("failed to read manifest signature: %v", err)
+	}
+	entity, err := openpgp.CheckArmoredDetachedSignature(v.keyring,
+		bytes.NewReader(manifest), bytes.NewReader(signature), nil)
+	if err != nil {
+		return newVerificationError("failed to verify signature of manifest: %v", err)
+	}
+	digests, err := parseManifest(manifest)
+	if err != nil {
+		return newVerificationError("invalid manifest: %v", err)
+	}
+
+	err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == RegistryManifestFileName || rel == RegistryManifestSignatureFileName {
+			return nil
+		}
+		// The symlinks may point to the files out of the registry
+		if !info.Mode().IsRegular() {
+			return newVerificationError("%s is not a regular file", rel)
+		}
+		expected, ok := digests[rel]
+		if !ok {
+			return newVerificationError("%s is not in the manifest", rel)
+		}
+		digest, err := fileDigest(p)
+		if err != nil {
+			return err
+		}
+		if digest != expected {
+			return newVerificationError("digest of %s doesn't match the manifest", rel)
+		}
+		delete(digests, rel)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(digests) != 0 {
+		missing := []string{}
+		for name := range digests {
+			missing = append(missing, name)
+		}
+		sort.Strings(missing)
+		return newVerificationError("%s in the manifest is missing", strings.Join(missing, ", "))
+	}
+
+	v.signer = getSigner(entity)
+	return nil
+}
+
+// parseManifest returns the digests of the files in the `sha256sum` output.
+func parseManifest(manifest []byte) (map[string]string, error) {
+	digests := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(manifest))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		digest, name, found := strings.Cut(line, " ")
+		if !found || len(digest) != sha256.Size*2 {
+			return nil, fmt.Errorf("invalid line %q", line)
+		}
+		// The binary mode of sha256sum prefixes the file name with "*"
+		name = strings.TrimPrefix(strings.TrimLeft(name, " "), "*")
+		name = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(name)), "./")
+		digests[name] = strings.ToLower(digest)
+	}
+	return digests, scanner.Err()
+}
+
+func fileDigest(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func getSigner(entity *openpgp.Entity) string {
+	keyID := entity.PrimaryKey.KeyIdString()
+	if identity := entity.PrimaryIdentity(); identity != nil {
+		return fmt.Sprintf("%s (%s)", identity.Name, keyID)
+	}
+	return keyID
+}
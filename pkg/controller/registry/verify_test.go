@@ -0,0 +1,128 @@
+package registry
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	gitv5 "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+
+	registryv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/registry/v1alpha1"
+)
+
+func newTestVerifier(t *testing.T, method registryv1alpha1.RegistryVerificationMethod) (*registryVerifier, *openpgp.Entity) {
+	entity, err := openpgp.NewEntity("openapp", "", "openapp@openapp.dev", nil)
+	assert.NoError(t, err)
+	buf := &bytes.Buffer{}
+	w, err := armor.Encode(buf, openpgp.PublicKeyType, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, entity.Serialize(w))
+	assert.NoError(t, w.Close())
+
+	verifier, err := newRegistryVerifier(&registryv1alpha1.RegistryVerification{
+		Method:     method,
+		PublicKeys: buf.String(),
+	})
+	assert.NoError(t, err)
+	return verifier, entity
+}
+
+func writeSignedManifest(t *testing.T, dir string, entity *openpgp.Entity, files map[string]string) {
+	manifest := &bytes.Buffer{}
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		assert.NoError(t, os.WriteFile(p, []byte(content), 0644))
+		digest := sha256.Sum256([]byte(content))
+		fmt.Fprintf(manifest, "%s  %s\n", hex.EncodeToString(digest[:]), name)
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, RegistryManifestFileName), manifest.Bytes(), 0644))
+	signature := &bytes.Buffer{}
+	assert.NoError(t, openpgp.ArmoredDetachSign(signature, entity, bytes.NewReader(manifest.Bytes()), nil))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, RegistryManifestSignatureFileName), signature.Bytes(), 0644))
+}
+
+func TestVerifyDir(t *testing.T) {
+	verifier, entity := newTestVerifier(t, registryv1alpha1.RegistryVerificationManifest)
+	files := map[string]string{
+		"app-template/nginx/template.yaml":           "name: nginx",
+		"app-template/nginx/manifests/service.yaml":  "kind: Service",
+		"publicservice-template/frp/template.yaml":   "name: frp",
+		"publicservice-template/frp/manifests/a.yml": "kind: ConfigMap",
+	}
+
+	dir := t.TempDir()
+	writeSignedManifest(t, dir, entity, files)
+	assert.NoError(t, verifier.verifyDir(dir))
+	assert.Contains(t, verifier.signer, "openapp <openapp@openapp.dev>")
+
+	// The file changed after signing
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "app-template/nginx/template.yaml"), []byte("name: evil"), 0644))
+	assert.ErrorContains(t, verifier.verifyDir(dir), "doesn't match")
+
+	// The file not listed in the manifest
+	dir = t.TempDir()
+	writeSignedManifest(t, dir, entity, files)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "app-template/nginx/manifests/extra.yaml"), nil, 0644))
+	assert.ErrorContains(t, verifier.verifyDir(dir), "not in the manifest")
+
+	// The file listed in the manifest is removed
+	dir = t.TempDir()
+	writeSignedManifest(t, dir, entity, files)
+	assert.NoError(t, os.Remove(filepath.Join(dir, "publicservice-template/frp/manifests/a.yml")))
+	assert.ErrorContains(t, verifier.verifyDir(dir), "missing")
+
+	// The manifest signed by an untrusted key
+	dir = t.TempDir()
+	_, untrusted := newTestVerifier(t, registryv1alpha1.RegistryVerificationManifest)
+	writeSignedManifest(t, dir, untrusted, files)
+	err := verifier.verifyDir(dir)
+	var verifyErr *verificationError
+	assert.ErrorAs(t, err, &verifyErr)
+
+	// Nothing is verified without the verification
+	var noVerifier *registryVerifier
+	assert.NoError(t, noVerifier.verifyDir(t.TempDir()))
+}
+
+func TestVerifyCommit(t *testing.T) {
+	verifier, entity := newTestVerifier(t, registryv1alpha1.RegistryVerificationCommit)
+	dir := t.TempDir()
+	r, err := gitv5.PlainInit(dir, false)
+	assert.NoError(t, err)
+	w, err := r.Worktree()
+	assert.NoError(t, err)
+	sig := &object.Signature{Name: "openapp", Email: "openapp@openapp.dev", When: time.Now()}
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("v1"), 0644))
+	_, err = w.Add("README.md")
+	assert.NoError(t, err)
+	signed, err := w.Commit("signed", &gitv5.CommitOptions{Author: sig, SignKey: entity})
+	assert.NoError(t, err)
+	assert.NoError(t, verifier.verifyCommit(r, "", signed))
+	assert.Contains(t, verifier.signer, entity.PrimaryKey.KeyIdString())
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("v2"), 0644))
+	_, err = w.Add("README.md")
+	assert.NoError(t, err)
+	unsigned, err := w.Commit("unsigned", &gitv5.CommitOptions{Author: sig})
+	assert.NoError(t, err)
+	assert.ErrorContains(t, verifier.verifyCommit(r, "", unsigned), "not signed")
+
+	// The signed tag of the unsigned commit is accepted
+	_, err = r.CreateTag("v1.0.0", unsigned, &gitv5.CreateTagOptions{Tagger: sig, Message: "v1.0.0", SignKey: entity})
+	assert.NoError(t, err)
+	assert.NoError(t, verifier.verifyCommit(r, "v1.0.0", unsigned))
+
+	other, _ := newTestVerifier(t, registryv1alpha1.RegistryVerificationCommit)
+	assert.Error(t, other.verifyCommit(r, "", signed))
+}
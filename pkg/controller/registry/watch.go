@@ -0,0 +1,156 @@
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"k8s.io/klog"
+)
+
+// localChangeDelay merges the burst of file events, e.g. an editor saving a
+// file or a git checkout, into one sync.
+const localChangeDelay = time.Second
+
+// localWatcher watches the directories of the local registries with inotify,
+// the changed registries are synced without waiting for the sync interval.
+type localWatcher struct {
+	lock     sync.Mutex
+	watchers map[string]*fsnotify.Watcher
+	paths    map[string]string
+	timers   map[string]*time.Timer
+	changed  map[string]bool
+	onChange func(name string)
+}
+
+func newLocalWatcher(onChange func(name string)) *localWatcher {
+	return &localWatcher{
+		watchers: map[string]*fsnotify.Watcher{},
+		paths:    map[string]string{},
+		timers:   map[string]*time.Timer{},
+		changed:  map[string]bool{},
+		onChange: onChange,
+	}
+}
+
+// Watch starts watching the directory of the registry, the previous directory
+// of the registry is not watched anymore.
+func (lw *localWatcher) Watch(name, dir string) error {
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+	if lw.paths[name] == dir {
+		return nil
+	}
+	lw.stopLocked(name)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		klog.Errorf("Failed to create watcher: %v", err)
+		return err
+	}
+	if err := addWatchDirs(watcher, dir); err != nil {
+		klog.Errorf("Failed to watch local registry %s: %v", dir, err)
+		_ = watcher.Close()
+		return err
+	}
+	lw.watchers[name] = watcher
+	lw.paths[name] = dir
+	go lw.run(name, watcher)
+	return nil
+}
+
+// Stop stops watching the directory of the registry.
+func (lw *localWatcher) Stop(name string) {
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+	lw.stopLocked(name)
+}
+
+func (lw *localWatcher) stopLocked(name string) {
+	if watcher, ok := lw.watchers[name]; ok {
+		_ = watcher.Close()
+	}
+	if timer, ok := lw.timers[name]; ok {
+		timer.Stop()
+	}
+	delete(lw.watchers, name)
+	delete(lw.paths, name)
+	delete(lw.timers, name)
+	delete(lw.changed, name)
+}
+
+// IsWatching reports whether the directory of the registry is watched.
+func (lw *localWatcher) IsWatching(name string) bool {
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+	_, ok := lw.watchers[name]
+	return ok
+}
+
+// TakeChanged reports whether the directory of the registry is changed since
+// the last call.
+func (lw *localWatcher) TakeChanged(name string) bool {
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+	changed := lw.changed[name]
+	delete(lw.changed, name)
+	return changed
+}
+
+func (lw *localWatcher) run(name string, watcher *fsnotify.Watcher) {
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			// The new directories are not watched by inotify automatically
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := addWatchDirs(watcher, event.Name); err != nil {
+						klog.Errorf("Failed to watch %s: %v", event.Name, err)
+					}
+				}
+			}
+			lw.notify(name, watcher)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			klog.Errorf("Failed to watch local registry(%s): %v", name, err)
+		}
+	}
+}
+
+func (lw *localWatcher) notify(name string, watcher *fsnotify.Watcher) {
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+	if lw.watchers[name] != watcher {
+		return
+	}
+	lw.changed[name] = true
+	if timer, ok := lw.timers[name]; ok {
+		timer.Reset(localChangeDelay)
+		return
+	}
+	lw.timers[name] = time.AfterFunc(localChangeDelay, func() {
+		lw.onChange(name)
+	})
+}
+
+func addWatchDirs(watcher *fsnotify.Watcher, dir string) error {
+	return filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		return watcher.Add(p)
+	})
+}
@@ -12,6 +12,7 @@ import (
 	"net/http"
 
 	appv1alpha1 "github.com/openapp-dev/openapp/pkg/generated/clientset/versioned/typed/app/v1alpha1"
+	registryv1alpha1 "github.com/openapp-dev/openapp/pkg/generated/clientset/versioned/typed/registry/v1alpha1"
 	servicev1alpha1 "github.com/openapp-dev/openapp/pkg/generated/clientset/versioned/typed/service/v1alpha1"
 	discovery "k8s.io/client-go/discovery"
 	rest "k8s.io/client-go/rest"
@@ -21,6 +22,7 @@ import (
 type Interface interface {
 	Discovery() discovery.DiscoveryInterface
 	AppV1alpha1() appv1alpha1.AppV1alpha1Interface
+	RegistryV1alpha1() registryv1alpha1.RegistryV1alpha1Interface
 	ServiceV1alpha1() servicev1alpha1.ServiceV1alpha1Interface
 }
 
@@ -28,8 +30,9 @@ type Interface interface {
 // version included in a Clientset.
 type Clientset struct {
 	*discovery.DiscoveryClient
-	appV1alpha1     *appv1alpha1.AppV1alpha1Client
-	serviceV1alpha1 *servicev1alpha1.ServiceV1alpha1Client
+	appV1alpha1      *appv1alpha1.AppV1alpha1Client
+	registryV1alpha1 *registryv1alpha1.RegistryV1alpha1Client
+	serviceV1alpha1  *servicev1alpha1.ServiceV1alpha1Client
 }
 
 // AppV1alpha1 retrieves the AppV1alpha1Client
@@ -37,6 +40,11 @@ func (c *Clientset) AppV1alpha1() appv1alpha1.AppV1alpha1Interface {
 	return c.appV1alpha1
 }
 
+// RegistryV1alpha1 retrieves the RegistryV1alpha1Client
+func (c *Clientset) RegistryV1alpha1() registryv1alpha1.RegistryV1alpha1Interface {
+	return c.registryV1alpha1
+}
+
 // ServiceV1alpha1 retrieves the ServiceV1alpha1Client
 func (c *Clientset) ServiceV1alpha1() servicev1alpha1.ServiceV1alpha1Interface {
 	return c.serviceV1alpha1
@@ -90,6 +98,10 @@ func NewForConfigAndClient(c *rest.Config, httpClient *http.Client) (*Clientset,
 	if err != nil {
 		return nil, err
 	}
+	cs.registryV1alpha1, err = registryv1alpha1.NewForConfigAndClient(&configShallowCopy, httpClient)
+	if err != nil {
+		return nil, err
+	}
 	cs.serviceV1alpha1, err = servicev1alpha1.NewForConfigAndClient(&configShallowCopy, httpClient)
 	if err != nil {
 		return nil, err
@@ -116,6 +128,7 @@ func NewForConfigOrDie(c *rest.Config) *Clientset {
 func New(c rest.Interface) *Clientset {
 	var cs Clientset
 	cs.appV1alpha1 = appv1alpha1.New(c)
+	cs.registryV1alpha1 = registryv1alpha1.New(c)
 	cs.serviceV1alpha1 = servicev1alpha1.New(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClient(c)
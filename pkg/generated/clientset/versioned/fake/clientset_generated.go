@@ -11,6 +11,8 @@ import (
 	clientset "github.com/openapp-dev/openapp/pkg/generated/clientset/versioned"
 	appv1alpha1 "github.com/openapp-dev/openapp/pkg/generated/clientset/versioned/typed/app/v1alpha1"
 	fakeappv1alpha1 "github.com/openapp-dev/openapp/pkg/generated/clientset/versioned/typed/app/v1alpha1/fake"
+	registryv1alpha1 "github.com/openapp-dev/openapp/pkg/generated/clientset/versioned/typed/registry/v1alpha1"
+	fakeregistryv1alpha1 "github.com/openapp-dev/openapp/pkg/generated/clientset/versioned/typed/registry/v1alpha1/fake"
 	servicev1alpha1 "github.com/openapp-dev/openapp/pkg/generated/clientset/versioned/typed/service/v1alpha1"
 	fakeservicev1alpha1 "github.com/openapp-dev/openapp/pkg/generated/clientset/versioned/typed/service/v1alpha1/fake"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -75,6 +77,11 @@ func (c *Clientset) AppV1alpha1() appv1alpha1.AppV1alpha1Interface {
 	return &fakeappv1alpha1.FakeAppV1alpha1{Fake: &c.Fake}
 }
 
+// RegistryV1alpha1 retrieves the RegistryV1alpha1Client
+func (c *Clientset) RegistryV1alpha1() registryv1alpha1.RegistryV1alpha1Interface {
+	return &fakeregistryv1alpha1.FakeRegistryV1alpha1{Fake: &c.Fake}
+}
+
 // ServiceV1alpha1 retrieves the ServiceV1alpha1Client
 func (c *Clientset) ServiceV1alpha1() servicev1alpha1.ServiceV1alpha1Interface {
 	return &fakeservicev1alpha1.FakeServiceV1alpha1{Fake: &c.Fake}
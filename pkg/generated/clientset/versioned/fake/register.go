@@ -9,6 +9,7 @@ package fake
 
 import (
 	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
+	registryv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/registry/v1alpha1"
 	servicev1alpha1 "github.com/openapp-dev/openapp/pkg/apis/service/v1alpha1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
@@ -22,6 +23,7 @@ var codecs = serializer.NewCodecFactory(scheme)
 
 var localSchemeBuilder = runtime.SchemeBuilder{
 	appv1alpha1.AddToScheme,
+	registryv1alpha1.AddToScheme,
 	servicev1alpha1.AddToScheme,
 }
 
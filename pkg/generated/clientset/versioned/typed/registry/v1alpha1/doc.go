@@ -0,0 +1,9 @@
+/*
+Copyright 2024 The OpenAPP Authors.
+SPDX-License-Identifier: BUSL-1.1
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+// This package has the automatically generated typed clients.
+package v1alpha1
@@ -0,0 +1,122 @@
+/*
+Copyright 2024 The OpenAPP Authors.
+SPDX-License-Identifier: BUSL-1.1
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+
+	v1alpha1 "github.com/openapp-dev/openapp/pkg/apis/registry/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeRegistries implements RegistryInterface
+type FakeRegistries struct {
+	Fake *FakeRegistryV1alpha1
+}
+
+var registriesResource = schema.GroupVersionResource{Group: "registry.openapp.dev", Version: "v1alpha1", Resource: "registries"}
+
+var registriesKind = schema.GroupVersionKind{Group: "registry.openapp.dev", Version: "v1alpha1", Kind: "Registry"}
+
+// Get takes name of the registry, and returns the corresponding registry object, and an error if there is any.
+func (c *FakeRegistries) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.Registry, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootGetAction(registriesResource, name), &v1alpha1.Registry{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.Registry), err
+}
+
+// List takes label and field selectors, and returns the list of Registries that match those selectors.
+func (c *FakeRegistries) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha1.RegistryList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootListAction(registriesResource, registriesKind, opts), &v1alpha1.RegistryList{})
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.RegistryList{ListMeta: obj.(*v1alpha1.RegistryList).ListMeta}
+	for _, item := range obj.(*v1alpha1.RegistryList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested registries.
+func (c *FakeRegistries) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewRootWatchAction(registriesResource, opts))
+}
+
+// Create takes the representation of a registry and creates it.  Returns the server's representation of the registry, and an error, if there is any.
+func (c *FakeRegistries) Create(ctx context.Context, registry *v1alpha1.Registry, opts v1.CreateOptions) (result *v1alpha1.Registry, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootCreateAction(registriesResource, registry), &v1alpha1.Registry{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.Registry), err
+}
+
+// Update takes the representation of a registry and updates it. Returns the server's representation of the registry, and an error, if there is any.
+func (c *FakeRegistries) Update(ctx context.Context, registry *v1alpha1.Registry, opts v1.UpdateOptions) (result *v1alpha1.Registry, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootUpdateAction(registriesResource, registry), &v1alpha1.Registry{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.Registry), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeRegistries) UpdateStatus(ctx context.Context, registry *v1alpha1.Registry, opts v1.UpdateOptions) (*v1alpha1.Registry, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootUpdateSubresourceAction(registriesResource, "status", registry), &v1alpha1.Registry{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.Registry), err
+}
+
+// Delete takes name of the registry and deletes it. Returns an error if one occurs.
+func (c *FakeRegistries) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewRootDeleteActionWithOptions(registriesResource, name, opts), &v1alpha1.Registry{})
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeRegistries) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	action := testing.NewRootDeleteCollectionAction(registriesResource, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.RegistryList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched registry.
+func (c *FakeRegistries) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.Registry, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootPatchSubresourceAction(registriesResource, name, pt, data, subresources...), &v1alpha1.Registry{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.Registry), err
+}
@@ -15,6 +15,7 @@ import (
 	versioned "github.com/openapp-dev/openapp/pkg/generated/clientset/versioned"
 	app "github.com/openapp-dev/openapp/pkg/generated/informers/externalversions/app"
 	internalinterfaces "github.com/openapp-dev/openapp/pkg/generated/informers/externalversions/internalinterfaces"
+	registry "github.com/openapp-dev/openapp/pkg/generated/informers/externalversions/registry"
 	service "github.com/openapp-dev/openapp/pkg/generated/informers/externalversions/service"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
@@ -163,6 +164,7 @@ type SharedInformerFactory interface {
 	WaitForCacheSync(stopCh <-chan struct{}) map[reflect.Type]bool
 
 	App() app.Interface
+	Registry() registry.Interface
 	Service() service.Interface
 }
 
@@ -170,6 +172,10 @@ func (f *sharedInformerFactory) App() app.Interface {
 	return app.New(f, f.namespace, f.tweakListOptions)
 }
 
+func (f *sharedInformerFactory) Registry() registry.Interface {
+	return registry.New(f, f.namespace, f.tweakListOptions)
+}
+
 func (f *sharedInformerFactory) Service() service.Interface {
 	return service.New(f, f.namespace, f.tweakListOptions)
 }
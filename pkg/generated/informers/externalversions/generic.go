@@ -11,6 +11,7 @@ import (
 	"fmt"
 
 	v1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
+	registryv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/registry/v1alpha1"
 	servicev1alpha1 "github.com/openapp-dev/openapp/pkg/apis/service/v1alpha1"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
 	cache "k8s.io/client-go/tools/cache"
@@ -48,6 +49,10 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 	case v1alpha1.SchemeGroupVersion.WithResource("apptemplates"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.App().V1alpha1().AppTemplates().Informer()}, nil
 
+		// Group=registry.openapp.dev, Version=v1alpha1
+	case registryv1alpha1.SchemeGroupVersion.WithResource("registries"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Registry().V1alpha1().Registries().Informer()}, nil
+
 		// Group=service.openapp.dev, Version=v1alpha1
 	case servicev1alpha1.SchemeGroupVersion.WithResource("publicserviceinstances"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Service().V1alpha1().PublicServiceInstances().Informer()}, nil
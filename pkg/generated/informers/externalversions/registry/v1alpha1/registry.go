@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The OpenAPP Authors.
+SPDX-License-Identifier: BUSL-1.1
+*/
+
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"context"
+	time "time"
+
+	registryv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/registry/v1alpha1"
+	versioned "github.com/openapp-dev/openapp/pkg/generated/clientset/versioned"
+	internalinterfaces "github.com/openapp-dev/openapp/pkg/generated/informers/externalversions/internalinterfaces"
+	v1alpha1 "github.com/openapp-dev/openapp/pkg/generated/listers/registry/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// RegistryInformer provides access to a shared informer and lister for
+// Registries.
+type RegistryInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1alpha1.RegistryLister
+}
+
+type registryInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+}
+
+// NewRegistryInformer constructs a new informer for Registry type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewRegistryInformer(client versioned.Interface, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredRegistryInformer(client, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredRegistryInformer constructs a new informer for Registry type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredRegistryInformer(client versioned.Interface, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.RegistryV1alpha1().Registries().List(context.TODO(), options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.RegistryV1alpha1().Registries().Watch(context.TODO(), options)
+			},
+		},
+		&registryv1alpha1.Registry{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *registryInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredRegistryInformer(client, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *registryInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&registryv1alpha1.Registry{}, f.defaultInformer)
+}
+
+func (f *registryInformer) Lister() v1alpha1.RegistryLister {
+	return v1alpha1.NewRegistryLister(f.Informer().GetIndexer())
+}
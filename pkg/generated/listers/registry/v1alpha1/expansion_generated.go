@@ -0,0 +1,12 @@
+/*
+Copyright 2024 The OpenAPP Authors.
+SPDX-License-Identifier: BUSL-1.1
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+// RegistryListerExpansion allows custom methods to be added to
+// RegistryLister.
+type RegistryListerExpansion interface{}
@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The OpenAPP Authors.
+SPDX-License-Identifier: BUSL-1.1
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1alpha1 "github.com/openapp-dev/openapp/pkg/apis/registry/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// RegistryLister helps list Registries.
+// All objects returned here must be treated as read-only.
+type RegistryLister interface {
+	// List lists all Registries in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1alpha1.Registry, err error)
+	// Get retrieves the Registry from the index for a given name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*v1alpha1.Registry, error)
+	RegistryListerExpansion
+}
+
+// registryLister implements the RegistryLister interface.
+type registryLister struct {
+	indexer cache.Indexer
+}
+
+// NewRegistryLister returns a new RegistryLister.
+func NewRegistryLister(indexer cache.Indexer) RegistryLister {
+	return &registryLister{indexer: indexer}
+}
+
+// List lists all Registries in the indexer.
+func (s *registryLister) List(selector labels.Selector) (ret []*v1alpha1.Registry, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.Registry))
+	})
+	return ret, err
+}
+
+// Get retrieves the Registry from the index for a given name.
+func (s *registryLister) Get(name string) (*v1alpha1.Registry, error) {
+	obj, exists, err := s.indexer.GetByKey(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("registry"), name)
+	}
+	return obj.(*v1alpha1.Registry), nil
+}
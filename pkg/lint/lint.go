@@ -0,0 +1,378 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/ghodss/yaml"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	serializerjson "k8s.io/apimachinery/pkg/runtime/serializer/json"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
+	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
+	servicev1alpha1 "github.com/openapp-dev/openapp/pkg/apis/service/v1alpha1"
+	"github.com/openapp-dev/openapp/pkg/utils"
+)
+
+const (
+	AppTemplateKind           = "AppTemplate"
+	PublicServiceTemplateKind = "PublicServiceTemplate"
+)
+
+// strictDecoder decodes the built-in kubernetes objects and reports the unknown
+// fields, which are dropped silently when the manifests are applied.
+var strictDecoder = serializerjson.NewSerializerWithOptions(serializerjson.DefaultMetaFactory,
+	scheme.Scheme, scheme.Scheme, serializerjson.SerializerOptions{Strict: true})
+
+// Problem is an issue found in a file of the template.
+type Problem struct {
+	File    string
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s", p.File, p.Message)
+}
+
+// Template is the template loaded from a template directory of a registry.
+type Template struct {
+	Dir         string
+	Kind        string
+	Name        string
+	Version     string
+	Inputs      string
+	InputSchema *commonv1alpha1.InputSchema
+	// Chart is the Helm chart of the helm template
+	Chart *appv1alpha1.HelmChart
+	// Compose is the compose file of the compose template
+	Compose string
+}
+
+// Manifest is a manifest of the template rendered with the inputs.
+type Manifest struct {
+	File    string
+	Content []byte
+}
+
+// FindTemplateDirs returns the template directories in the path, which is
+// either a template directory or a registry.
+func FindTemplateDirs(dir string) ([]string, error) {
+	if _, err := os.Stat(path.Join(dir, utils.TemplateFileName)); err == nil {
+		return []string{dir}, nil
+	}
+	ret := []string{}
+	for _, basePath := range []string{utils.AppTemplateBasePath, utils.PublicServiceTemplateBasePath} {
+		entries, err := os.ReadDir(path.Join(dir, basePath))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				ret = append(ret, path.Join(dir, basePath, e.Name()))
+			}
+		}
+	}
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("no template found in %s", dir)
+	}
+	return ret, nil
+}
+
+// LoadTemplate loads the template file in the directory and validates it
+// against the CRD schema, the template is nil if it can't be decoded.
+func LoadTemplate(dir string) (*Template, []Problem) {
+	templateFile := path.Join(dir, utils.TemplateFileName)
+	problems := []Problem{}
+	report := func(format string, a ...interface{}) {
+		problems = append(problems, Problem{File: templateFile, Message: fmt.Sprintf(format, a...)})
+	}
+
+	d, err := os.ReadFile(templateFile)
+	if err != nil {
+		report("failed to read template: %v", err)
+		return nil, problems
+	}
+	jsonContent, err := yaml.YAMLToJSON(d)
+	if err != nil {
+		report("invalid YAML: %v", err)
+		return nil, problems
+	}
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(jsonContent, &obj); err != nil {
+		report("template must be an object: %v", err)
+		return nil, problems
+	}
+
+	kind, _ := obj["kind"].(string)
+	if kind == "" {
+		kind = getTemplateKind(dir)
+	}
+	var typed interface{}
+	switch kind {
+	case AppTemplateKind:
+		typed = &appv1alpha1.AppTemplate{}
+	case PublicServiceTemplateKind:
+		typed = &servicev1alpha1.PublicServiceTemplate{}
+	default:
+		report("unknown template kind %q, the template must be in %s or %s", kind,
+			utils.AppTemplateBasePath, utils.PublicServiceTemplateBasePath)
+		return nil, problems
+	}
+
+	for _, err := range validateSchema(kind, obj) {
+		report("%v", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(jsonContent))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(typed); err != nil {
+		report("%v", err)
+		// Load the template anyway, the unknown fields are reported already
+		if err := json.Unmarshal(jsonContent, typed); err != nil {
+			return nil, problems
+		}
+	}
+
+	t := &Template{Dir: dir, Kind: kind}
+	switch temp := typed.(type) {
+	case *appv1alpha1.AppTemplate:
+		t.Name, t.Version, t.Inputs, t.InputSchema = temp.Name, temp.Spec.Version, temp.Spec.Inputs, temp.Spec.InputSchema
+		if temp.Spec.Type == appv1alpha1.AppTemplateHelm {
+			t.Chart = temp.Spec.Chart
+			if t.Chart == nil {
+				report("spec.chart is required by the helm template")
+				return nil, problems
+			}
+		}
+		if temp.Spec.Type == appv1alpha1.AppTemplateCompose {
+			if t.Compose, err = utils.LoadTemplateCompose(dir); err != nil {
+				report("failed to load compose file: %v", err)
+				return nil, problems
+			}
+			if t.InputSchema == nil {
+				if t.InputSchema, err = utils.LoadComposeInputSchema(t.Compose); err != nil {
+					report("failed to load compose file: %v", err)
+					return nil, problems
+				}
+			}
+		}
+	case *servicev1alpha1.PublicServiceTemplate:
+		t.Name, t.Version, t.Inputs, t.InputSchema = temp.Name, temp.Spec.Version, temp.Spec.Inputs, temp.Spec.InputSchema
+	}
+	if t.Name == "" {
+		report("metadata.name is required")
+	} else {
+		for _, msg := range validation.IsDNS1123Subdomain(t.Name) {
+			report("invalid metadata.name %q: %s", t.Name, msg)
+		}
+	}
+	return t, problems
+}
+
+// getTemplateKind returns the kind of the template by the registry directory
+// it's placed in.
+func getTemplateKind(dir string) string {
+	switch path.Base(path.Dir(path.Clean(dir))) {
+	case utils.AppTemplateBasePath:
+		return AppTemplateKind
+	case utils.PublicServiceTemplateBasePath:
+		return PublicServiceTemplateKind
+	}
+	return ""
+}
+
+// Values returns the values the manifests are rendered with, the sample inputs
+// declared by the template are used if the inputs are empty.
+func (t *Template) Values(instanceName, inputs string) (string, error) {
+	if inputs == "" {
+		inputs = t.Inputs
+	}
+	if t.Kind == AppTemplateKind {
+		return utils.ConstructAppInstanceValues(&appv1alpha1.AppInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: instanceName},
+			Spec:       appv1alpha1.AppInstanceSpec{AppTemplate: t.Name, Inputs: inputs},
+		}, t.InputSchema)
+	}
+	return utils.ConstructPublicServiceInstanceValues(&servicev1alpha1.PublicServiceInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: instanceName},
+		Spec:       servicev1alpha1.PublicServiceInstanceSpec{PublicServiceTemplate: t.Name, Inputs: inputs},
+	}, t.InputSchema)
+}
+
+// Render renders all the manifests, the Helm chart or the compose file of the
+// template with the values.
+func (t *Template) Render(values string) ([]Manifest, error) {
+	ret := []Manifest{}
+	if t.Chart != nil {
+		rendered, err := utils.RenderHelmChart(t.Dir, t.Chart, values)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render helm chart: %v", err)
+		}
+		for _, m := range rendered {
+			ret = append(ret, Manifest{File: m.Name, Content: m.Content})
+		}
+		return ret, nil
+	}
+	if t.Compose != "" {
+		rendered, err := utils.RenderComposeFile(t.Compose, values, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render compose file: %v", err)
+		}
+		for _, m := range rendered {
+			ret = append(ret, Manifest{File: m.Name, Content: m.Content})
+		}
+		return ret, nil
+	}
+	for _, f := range utils.GetTemplateManifests(t.Dir) {
+		content, err := utils.ConstructTemplateWithValues(f, values, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s: %v", f, err)
+		}
+		ret = append(ret, Manifest{File: f, Content: content})
+	}
+	return ret, nil
+}
+
+// Lint checks the template in the directory: the template file is valid, all
+// the manifests are rendered with the sample inputs into valid and distinct
+// kubernetes objects, and there is no file in the manifests directory that is
+// ignored.
+func Lint(dir string) []Problem {
+	t, problems := LoadTemplate(dir)
+	if t == nil {
+		return problems
+	}
+	templateFile := path.Join(dir, utils.TemplateFileName)
+	report := func(file, format string, a ...interface{}) {
+		problems = append(problems, Problem{File: file, Message: fmt.Sprintf(format, a...)})
+	}
+
+	if t.Version != "" {
+		if _, err := utilversion.ParseSemantic(t.Version); err != nil {
+			report(templateFile, "invalid spec.version: %v", err)
+		}
+	}
+	for _, err := range utils.ValidateInputSchema(t.InputSchema, field.NewPath("spec", "inputSchema")) {
+		report(templateFile, "%v", err)
+	}
+
+	values, err := t.Values(t.Name, "")
+	if err != nil {
+		report(templateFile, "invalid sample spec.inputs: %v", err)
+		return problems
+	}
+	var manifests []Manifest
+	switch {
+	case t.Chart != nil:
+		if manifests, err = t.Render(values); err != nil {
+			report(templateFile, "%v", err)
+			return problems
+		}
+	case t.Compose != "":
+		if manifests, err = t.Render(values); err != nil {
+			report(t.Compose, "%v", err)
+			return problems
+		}
+	default:
+		manifests = lintManifests(dir, values, report)
+	}
+
+	rendered := map[string]string{}
+	for _, m := range manifests {
+		objs, err := utils.DecodeManifests(m.Content)
+		if err != nil {
+			report(m.File, "rendered manifest is invalid: %v", err)
+			continue
+		}
+		for _, obj := range objs {
+			msgs := checkObject(obj)
+			for _, msg := range msgs {
+				report(m.File, "%s", msg)
+			}
+			if len(msgs) != 0 {
+				continue
+			}
+			// The objects are applied by kind and name, the latter one overwrites the former
+			key := fmt.Sprintf("%s(%s)", obj.GetKind(), obj.GetName())
+			if other, ok := rendered[key]; ok {
+				report(m.File, "%s is also rendered from %s", key, other)
+			}
+			rendered[key] = m.File
+		}
+	}
+	return problems
+}
+
+// lintManifests checks the files of the manifests directory and renders the
+// manifests one by one, so all the manifests failed to render are reported.
+func lintManifests(dir, values string, report func(file, format string, a ...interface{})) []Manifest {
+	manifestsDir := path.Join(dir, utils.TemplateManifestsDirName)
+	err := filepath.WalkDir(manifestsDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && !utils.IsManifestFile(p) {
+			report(p, "unknown manifest file, only .yaml and .yml files are rendered")
+		}
+		return nil
+	})
+	if err != nil {
+		report(manifestsDir, "failed to read manifests: %v", err)
+		return nil
+	}
+
+	files := utils.GetTemplateManifests(dir)
+	if len(files) == 0 {
+		report(manifestsDir, "no manifest found")
+	}
+	ret := []Manifest{}
+	for _, f := range files {
+		content, err := utils.ConstructTemplateWithValues(f, values, nil)
+		if err != nil {
+			report(f, "failed to render: %v", err)
+			continue
+		}
+		ret = append(ret, Manifest{File: f, Content: content})
+	}
+	return ret
+}
+
+// checkObject checks the rendered object is a valid kubernetes object.
+func checkObject(obj *unstructured.Unstructured) []string {
+	ret := []string{}
+	if obj.GetAPIVersion() == "" {
+		ret = append(ret, "apiVersion is required")
+	}
+	if obj.GetKind() == "" {
+		ret = append(ret, "kind is required")
+	}
+	if obj.GetName() == "" {
+		ret = append(ret, "metadata.name is required")
+	}
+	if len(ret) != 0 {
+		return ret
+	}
+
+	jsonContent, err := obj.MarshalJSON()
+	if err != nil {
+		return []string{err.Error()}
+	}
+	// The custom resources are not known without a cluster
+	if _, _, err := strictDecoder.Decode(jsonContent, nil, nil); err != nil && !runtime.IsNotRegisteredError(err) {
+		ret = append(ret, fmt.Sprintf("invalid %s(%s): %v", obj.GetKind(), obj.GetName(), err))
+	}
+	return ret
+}
@@ -0,0 +1,197 @@
+package lint
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testTemplate = `apiVersion: app.openapp.dev/v1alpha1
+kind: AppTemplate
+metadata:
+  name: nginx
+spec:
+  title: Nginx
+  description: Nginx web server
+  author: openapp
+  icon: https://nginx.org/favicon.ico
+  url: https://nginx.org
+  exposeType: Layer7
+  version: 1.0.0
+  inputs: |
+    replicas: 2
+  inputSchema:
+    properties:
+      replicas:
+        type: integer
+        default: 1
+      image:
+        type: string
+        default: nginx:latest
+`
+
+const testDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .openapp.instance_name }}
+spec:
+  replicas: {{ .inputs.replicas }}
+  selector:
+    matchLabels:
+      app: {{ .openapp.instance_name }}
+  template:
+    metadata:
+      labels:
+        app: {{ .openapp.instance_name }}
+    spec:
+      containers:
+      - name: nginx
+        image: {{ .inputs.image }}
+`
+
+func writeTemplate(t *testing.T, files map[string]string) string {
+	dir := path.Join(t.TempDir(), "app-template", "nginx")
+	for name, content := range files {
+		p := path.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(path.Dir(p), 0755))
+		assert.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	}
+	return dir
+}
+
+func problemMessages(problems []Problem) string {
+	ret := []string{}
+	for _, p := range problems {
+		ret = append(ret, p.String())
+	}
+	return strings.Join(ret, "\n")
+}
+
+func TestLint(t *testing.T) {
+	dir := writeTemplate(t, map[string]string{
+		"template.yaml":             testTemplate,
+		"manifests/deployment.yaml": testDeployment,
+	})
+	assert.Empty(t, problemMessages(Lint(dir)))
+
+	tmpl, problems := LoadTemplate(dir)
+	assert.Empty(t, problems)
+	values, err := tmpl.Values("test", "")
+	assert.NoError(t, err)
+	manifests, err := tmpl.Render(values)
+	assert.NoError(t, err)
+	assert.Len(t, manifests, 1)
+	assert.Contains(t, string(manifests[0].Content), "replicas: 2")
+	assert.Contains(t, string(manifests[0].Content), "image: nginx:latest")
+
+	cases := []struct {
+		name     string
+		files    map[string]string
+		expected string
+	}{
+		{
+			name: "schema violation",
+			files: map[string]string{
+				"template.yaml":             strings.Replace(testTemplate, "  title: Nginx\n", "", 1),
+				"manifests/deployment.yaml": testDeployment,
+			},
+			expected: "spec.title in body is required",
+		},
+		{
+			name: "unknown template field",
+			files: map[string]string{
+				"template.yaml":             strings.Replace(testTemplate, "  title: Nginx\n", "  title: Nginx\n  tittle: Nginx\n", 1),
+				"manifests/deployment.yaml": testDeployment,
+			},
+			expected: `unknown field "tittle"`,
+		},
+		{
+			name: "invalid sample inputs",
+			files: map[string]string{
+				"template.yaml":             strings.Replace(testTemplate, "replicas: 2", "replicas: two", 1),
+				"manifests/deployment.yaml": testDeployment,
+			},
+			expected: "invalid sample spec.inputs",
+		},
+		{
+			name: "unknown manifest file",
+			files: map[string]string{
+				"template.yaml":             testTemplate,
+				"manifests/deployment.yaml": testDeployment,
+				"manifests/service.yaml.j2": "kind: Service",
+			},
+			expected: "unknown manifest file",
+		},
+		{
+			name: "invalid rendered object",
+			files: map[string]string{
+				"template.yaml":             testTemplate,
+				"manifests/deployment.yaml": strings.Replace(testDeployment, "  replicas:", "  replica:", 1),
+			},
+			expected: `unknown field "spec.replica"`,
+		},
+		{
+			name: "missing name",
+			files: map[string]string{
+				"template.yaml":          testTemplate,
+				"manifests/service.yaml": "apiVersion: v1\nkind: Service\n",
+			},
+			expected: "metadata.name is required",
+		},
+		{
+			name: "duplicated object",
+			files: map[string]string{
+				"template.yaml":                  testTemplate,
+				"manifests/deployment.yaml":      testDeployment,
+				"manifests/services/web.yaml":    "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n",
+				"manifests/services/others.yaml": "apiVersion: v1\nkind: Service\nmetadata:\n  name: api\n---\napiVersion: v1\nkind: Service\nmetadata:\n  name: web\n",
+			},
+			expected: "Service(web) is also rendered from",
+		},
+		{
+			name: "template error",
+			files: map[string]string{
+				"template.yaml":          testTemplate,
+				"manifests/service.yaml": "name: {{ .inputs.image ",
+			},
+			expected: "failed to render",
+		},
+		{
+			name: "invalid compose file",
+			files: map[string]string{
+				"template.yaml":       strings.Replace(testTemplate, "  version: 1.0.0\n", "  version: 1.0.0\n  type: compose\n", 1),
+				"docker-compose.yaml": "services:\n  web:\n    build: .\n",
+			},
+			expected: "build is not supported",
+		},
+	}
+	for _, c := range cases {
+		dir := writeTemplate(t, c.files)
+		assert.Contains(t, problemMessages(Lint(dir)), c.expected, c.name)
+	}
+}
+
+func TestFindTemplateDirs(t *testing.T) {
+	registry := t.TempDir()
+	for _, dir := range []string{"app-template/nginx", "app-template/redis", "publicservice-template/frp"} {
+		assert.NoError(t, os.MkdirAll(path.Join(registry, dir), 0755))
+	}
+	dirs, err := FindTemplateDirs(registry)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		path.Join(registry, "app-template/nginx"),
+		path.Join(registry, "app-template/redis"),
+		path.Join(registry, "publicservice-template/frp"),
+	}, dirs)
+
+	dir := writeTemplate(t, map[string]string{"template.yaml": testTemplate})
+	dirs, err = FindTemplateDirs(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{dir}, dirs)
+
+	_, err = FindTemplateDirs(t.TempDir())
+	assert.Error(t, err)
+}
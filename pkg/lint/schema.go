@@ -0,0 +1,87 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"sync"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/kube-openapi/pkg/validation/strfmt"
+	"k8s.io/kube-openapi/pkg/validation/validate"
+
+	"github.com/openapp-dev/openapp/config/crds"
+)
+
+type crdDefinition struct {
+	Spec struct {
+		Names struct {
+			Kind string `json:"kind"`
+		} `json:"names"`
+		Versions []struct {
+			Name   string `json:"name"`
+			Schema struct {
+				OpenAPIV3Schema json.RawMessage `json:"openAPIV3Schema"`
+			} `json:"schema"`
+		} `json:"versions"`
+	} `json:"spec"`
+}
+
+var (
+	loadSchemasOnce sync.Once
+	schemas         map[string]*spec.Schema
+	loadSchemasErr  error
+)
+
+// getSchema returns the openAPI schema of the kind in the embedded CRDs.
+func getSchema(kind string) (*spec.Schema, error) {
+	loadSchemasOnce.Do(func() {
+		schemas, loadSchemasErr = loadSchemas(crds.FS)
+	})
+	if loadSchemasErr != nil {
+		return nil, loadSchemasErr
+	}
+	schema, ok := schemas[kind]
+	if !ok {
+		return nil, fmt.Errorf("schema of %s not found", kind)
+	}
+	return schema, nil
+}
+
+func loadSchemas(fsys fs.FS) (map[string]*spec.Schema, error) {
+	files, err := fs.Glob(fsys, "*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	ret := map[string]*spec.Schema{}
+	for _, f := range files {
+		d, err := fs.ReadFile(fsys, f)
+		if err != nil {
+			return nil, err
+		}
+		crd := &crdDefinition{}
+		if err := yaml.Unmarshal(d, crd); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s: %v", f, err)
+		}
+		// The CRDs have a single version for now
+		for _, v := range crd.Spec.Versions {
+			schema := &spec.Schema{}
+			if err := json.Unmarshal(v.Schema.OpenAPIV3Schema, schema); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal schema of %s: %v", f, err)
+			}
+			ret[crd.Spec.Names.Kind] = schema
+		}
+	}
+	return ret, nil
+}
+
+// validateSchema validates the object against the CRD schema of the kind.
+func validateSchema(kind string, obj interface{}) []error {
+	schema, err := getSchema(kind)
+	if err != nil {
+		return []error{err}
+	}
+	result := validate.NewSchemaValidator(schema, nil, "", strfmt.Default).Validate(obj)
+	return result.Errors
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+
+	"k8s.io/klog"
+
+	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
+	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
+	"github.com/openapp-dev/openapp/pkg/compose"
+)
+
+// DefaultComposeFileName is the compose file of the compose template if it's
+// not specified.
+const DefaultComposeFileName = "docker-compose.yaml"
+
+// LoadTemplateCompose returns the path of the compose file of the template in
+// the directory, it's empty if the template isn't a compose template.
+func LoadTemplateCompose(templateDir string) (string, error) {
+	meta, err := readTemplateVersionMeta(templateDir)
+	if err != nil {
+		return "", err
+	}
+	if meta.Spec.Type != appv1alpha1.AppTemplateCompose {
+		return "", nil
+	}
+	composeFile := meta.Spec.Compose
+	if composeFile == "" {
+		composeFile = DefaultComposeFileName
+	}
+	// The compose file can't be out of the template directory
+	return filepath.Join(templateDir, filepath.Clean("/"+composeFile)), nil
+}
+
+// LoadComposeInputSchema returns the input schema converted from the variables
+// of the compose file, it's used if the compose template declares no inputs so
+// the defaults of the variables still apply.
+func LoadComposeInputSchema(composeFile string) (*commonv1alpha1.InputSchema, error) {
+	result, err := convertComposeFile(composeFile)
+	if err != nil {
+		return nil, err
+	}
+	return result.InputSchema, nil
+}
+
+func convertComposeFile(composeFile string) (*compose.Result, error) {
+	content, err := os.ReadFile(composeFile)
+	if err != nil {
+		klog.Errorf("Failed to read compose file: %v", err)
+		return nil, err
+	}
+	project, err := compose.Load(content)
+	if err != nil {
+		return nil, err
+	}
+	return compose.Convert(project)
+}
+
+// RenderComposeFile converts the compose file into the template manifests and
+// renders them with the instance values, see compose.Convert for how the
+// compose file is converted.
+func RenderComposeFile(composeFile, instanceValues string, lookup InstanceLookupFunc) ([]RenderedManifest, error) {
+	result, err := convertComposeFile(composeFile)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []RenderedManifest{}
+	for _, m := range result.Manifests {
+		manifestContent, err := constructTemplateContentWithValues(m.Name, m.Content, instanceValues, lookup)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, RenderedManifest{Name: m.Name, Content: manifestContent})
+	}
+	return ret, nil
+}
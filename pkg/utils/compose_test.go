@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
+)
+
+func TestRenderComposeFileDefaults(t *testing.T) {
+	composeFile := path.Join(t.TempDir(), DefaultComposeFileName)
+	assert.NoError(t, os.WriteFile(composeFile, []byte(`
+services:
+  web:
+    image: nginx:${TAG:-1.25}
+    environment:
+      GREETING: ${GREETING:-hello}
+      NAME: ${NAME}
+`), 0644))
+	appIns := &appv1alpha1.AppInstance{ObjectMeta: metav1.ObjectMeta{Name: "web"}}
+
+	// The defaults apply even if the template declares no inputs
+	for _, schemaFromCompose := range []bool{true, false} {
+		schema, err := LoadComposeInputSchema(composeFile)
+		assert.NoError(t, err)
+		if !schemaFromCompose {
+			schema = nil
+		}
+		values, err := ConstructAppInstanceValues(appIns, schema)
+		assert.NoError(t, err)
+		rendered, err := RenderComposeFile(composeFile, values, nil)
+		assert.NoError(t, err)
+
+		manifests := map[string]string{}
+		for _, m := range rendered {
+			manifests[m.Name] = string(m.Content)
+		}
+		assert.Contains(t, manifests["statefulset.yaml"], `image: "nginx:1.25"`)
+		assert.Contains(t, manifests["configmap.yaml"], `GREETING: "hello"`)
+		assert.Contains(t, manifests["configmap.yaml"], `NAME: ""`)
+		assert.NotContains(t, manifests["configmap.yaml"], "<nil>")
+	}
+}
@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SetInstanceCondition sets the condition of the instance, the last transition
+// time will only be changed if the status of the condition is changed.
+func SetInstanceCondition(conditions *[]metav1.Condition,
+	generation int64,
+	conditionType string,
+	ok bool,
+	reason, message string) {
+	status := metav1.ConditionFalse
+	if ok {
+		status = metav1.ConditionTrue
+	}
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
+)
+
+func TestSetInstanceCondition(t *testing.T) {
+	lastTransitionTime := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	exposed := metav1.Condition{
+		Type:               commonv1alpha1.ConditionExposed,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: 1,
+		Reason:             commonv1alpha1.ReasonServiceNotFound,
+		Message:            "No service is exposed",
+		LastTransitionTime: lastTransitionTime,
+	}
+	type update struct {
+		conditionType   string
+		ok              bool
+		reason, message string
+	}
+
+	cases := []struct {
+		name       string
+		conditions []metav1.Condition
+		generation int64
+		updates    []update
+		// expected conditions without the last transition time
+		expected []metav1.Condition
+		// whether the last transition time of the exposed condition is kept
+		keepTransitionTime bool
+	}{
+		{
+			name:       "reason and message changed",
+			conditions: []metav1.Condition{exposed},
+			generation: 1,
+			updates: []update{{commonv1alpha1.ConditionExposed, false,
+				commonv1alpha1.ReasonPublicURLPending, "Waiting for the public URL"}},
+			expected: []metav1.Condition{{
+				Type:               commonv1alpha1.ConditionExposed,
+				Status:             metav1.ConditionFalse,
+				ObservedGeneration: 1,
+				Reason:             commonv1alpha1.ReasonPublicURLPending,
+				Message:            "Waiting for the public URL",
+			}},
+			keepTransitionTime: true,
+		},
+		{
+			name:       "observed generation updated",
+			conditions: []metav1.Condition{exposed},
+			generation: 2,
+			updates: []update{{commonv1alpha1.ConditionExposed, false,
+				commonv1alpha1.ReasonServiceNotFound, "No service is exposed"}},
+			expected: []metav1.Condition{{
+				Type:               commonv1alpha1.ConditionExposed,
+				Status:             metav1.ConditionFalse,
+				ObservedGeneration: 2,
+				Reason:             commonv1alpha1.ReasonServiceNotFound,
+				Message:            "No service is exposed",
+			}},
+			keepTransitionTime: true,
+		},
+		{
+			name:       "status changed",
+			conditions: []metav1.Condition{exposed},
+			generation: 1,
+			updates: []update{{commonv1alpha1.ConditionExposed, true,
+				commonv1alpha1.ReasonServiceExposed, ""}},
+			expected: []metav1.Condition{{
+				Type:               commonv1alpha1.ConditionExposed,
+				Status:             metav1.ConditionTrue,
+				ObservedGeneration: 1,
+				Reason:             commonv1alpha1.ReasonServiceExposed,
+			}},
+		},
+		{
+			name:       "types added in a different order",
+			conditions: []metav1.Condition{exposed},
+			generation: 3,
+			updates: []update{
+				{commonv1alpha1.ConditionWorkloadReady, true, commonv1alpha1.ReasonReplicasReady, ""},
+				{commonv1alpha1.ConditionTemplateResolved, true, commonv1alpha1.ReasonTemplateFound, ""},
+			},
+			expected: []metav1.Condition{
+				{
+					Type:               commonv1alpha1.ConditionExposed,
+					Status:             metav1.ConditionFalse,
+					ObservedGeneration: 1,
+					Reason:             commonv1alpha1.ReasonServiceNotFound,
+					Message:            "No service is exposed",
+				},
+				{
+					Type:               commonv1alpha1.ConditionWorkloadReady,
+					Status:             metav1.ConditionTrue,
+					ObservedGeneration: 3,
+					Reason:             commonv1alpha1.ReasonReplicasReady,
+				},
+				{
+					Type:               commonv1alpha1.ConditionTemplateResolved,
+					Status:             metav1.ConditionTrue,
+					ObservedGeneration: 3,
+					Reason:             commonv1alpha1.ReasonTemplateFound,
+				},
+			},
+			keepTransitionTime: true,
+		},
+	}
+
+	for _, c := range cases {
+		conditions := append([]metav1.Condition{}, c.conditions...)
+		for _, u := range c.updates {
+			SetInstanceCondition(&conditions, c.generation, u.conditionType, u.ok, u.reason, u.message)
+		}
+
+		got := []metav1.Condition{}
+		for _, condition := range conditions {
+			assert.False(t, condition.LastTransitionTime.IsZero(), c.name)
+			if condition.Type == commonv1alpha1.ConditionExposed {
+				assert.Equal(t, c.keepTransitionTime, condition.LastTransitionTime.Equal(&lastTransitionTime), c.name)
+			}
+			condition.LastTransitionTime = metav1.Time{}
+			got = append(got, condition)
+		}
+		assert.Equal(t, c.expected, got, c.name)
+	}
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"context"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+)
+
+// ListInstanceEvents returns the events of the instance merged with the events
+// of its pods, the pods are selected by the podSelector, the newest comes first.
+func ListInstanceEvents(k8sClient kubernetes.Interface,
+	kind, insName, podSelector string) ([]corev1.Event, error) {
+	involvedObjects := []fields.Set{{
+		"involvedObject.kind": kind,
+		"involvedObject.name": insName,
+	}}
+
+	pods, err := k8sClient.CoreV1().Pods(InstanceNamespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: podSelector,
+	})
+	if err != nil {
+		klog.Errorf("Failed to list pods of instance(%s): %v", insName, err)
+		return nil, err
+	}
+	for _, pod := range pods.Items {
+		involvedObjects = append(involvedObjects, fields.Set{
+			"involvedObject.kind": "Pod",
+			"involvedObject.name": pod.Name,
+		})
+	}
+
+	ret := []corev1.Event{}
+	for _, involvedObject := range involvedObjects {
+		events, err := k8sClient.CoreV1().Events(InstanceNamespace).List(context.Background(), metav1.ListOptions{
+			FieldSelector: fields.SelectorFromSet(involvedObject).String(),
+		})
+		if err != nil {
+			klog.Errorf("Failed to list events of %s(%s): %v", involvedObject["involvedObject.kind"],
+				involvedObject["involvedObject.name"], err)
+			return nil, err
+		}
+		ret = append(ret, events.Items...)
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		return eventTime(&ret[i]).After(eventTime(&ret[j]).Time)
+	})
+	return ret, nil
+}
+
+func eventTime(event *corev1.Event) metav1.Time {
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp
+	}
+	if !event.EventTime.IsZero() {
+		return metav1.NewTime(event.EventTime.Time)
+	}
+	return event.CreationTimestamp
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newTestEvent(name, kind, objName string, lastTimestamp time.Time) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: InstanceNamespace},
+		InvolvedObject: corev1.ObjectReference{
+			Kind: kind,
+			Name: objName,
+		},
+		LastTimestamp: metav1.NewTime(lastTimestamp),
+	}
+}
+
+func TestListInstanceEvents(t *testing.T) {
+	now := time.Now()
+	k8sClient := fake.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      "app-0",
+			Namespace: InstanceNamespace,
+			Labels:    map[string]string{AppInstanceLabelKey: "app"},
+		}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      "other-0",
+			Namespace: InstanceNamespace,
+			Labels:    map[string]string{AppInstanceLabelKey: "other"},
+		}},
+		newTestEvent("instance-old", "AppInstance", "app", now.Add(-3*time.Minute)),
+		newTestEvent("pod", "Pod", "app-0", now.Add(-2*time.Minute)),
+		newTestEvent("instance-new", "AppInstance", "app", now.Add(-time.Minute)),
+		newTestEvent("other-instance", "AppInstance", "other", now),
+		newTestEvent("other-pod", "Pod", "other-0", now),
+	)
+	// The fake clientset doesn't filter the events by the field selector
+	k8sClient.PrependReactor("list", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		selector := action.(k8stesting.ListAction).GetListRestrictions().Fields
+		obj, err := k8sClient.Tracker().List(corev1.SchemeGroupVersion.WithResource("events"),
+			corev1.SchemeGroupVersion.WithKind("Event"), action.GetNamespace())
+		if err != nil {
+			return true, nil, err
+		}
+		list := obj.(*corev1.EventList)
+		items := []corev1.Event{}
+		for _, event := range list.Items {
+			if selector.Matches(fields.Set{
+				"involvedObject.kind": event.InvolvedObject.Kind,
+				"involvedObject.name": event.InvolvedObject.Name,
+			}) {
+				items = append(items, event)
+			}
+		}
+		list.Items = items
+		return true, list, nil
+	})
+
+	events, err := ListInstanceEvents(k8sClient, "AppInstance", "app", AppInstanceLabelKey+"=app")
+	assert.NoError(t, err)
+	names := []string{}
+	for _, event := range events {
+		names = append(names, event.Name)
+	}
+	assert.Equal(t, []string{"instance-new", "pod", "instance-old"}, names)
+}
@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/repo"
+	"k8s.io/klog"
+
+	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
+)
+
+const helmNotesFileSuffix = "NOTES.txt"
+
+// RenderedManifest is a manifest rendered for the instance, the name is the
+// manifest file or the template of the Helm chart it's rendered from.
+type RenderedManifest struct {
+	Name    string
+	Content []byte
+}
+
+// LoadTemplateChart returns the Helm chart of the template in the directory,
+// it's nil if the template isn't a helm template.
+func LoadTemplateChart(templateDir string) (*appv1alpha1.HelmChart, error) {
+	meta, err := readTemplateVersionMeta(templateDir)
+	if err != nil {
+		return nil, err
+	}
+	if meta.Spec.Type != appv1alpha1.AppTemplateHelm {
+		return nil, nil
+	}
+	if meta.Spec.Chart == nil {
+		return nil, fmt.Errorf("chart is required by the helm template")
+	}
+	return meta.Spec.Chart, nil
+}
+
+// RenderHelmChart renders the Helm chart of the template, the inputs of the
+// instance values are the chart values and the instance name is the release
+// name. The hooks and notes of the chart are skipped, only the resources are
+// derived from the chart.
+func RenderHelmChart(templateDir string, helmChart *appv1alpha1.HelmChart, instanceValues string) ([]RenderedManifest, error) {
+	values := struct {
+		OpenAPP struct {
+			InstanceName string `json:"instance_name"`
+		} `json:"openapp"`
+		Inputs map[string]interface{} `json:"inputs"`
+	}{}
+	if err := json.Unmarshal([]byte(instanceValues), &values); err != nil {
+		klog.Errorf("Failed to unmarshal values: %v", err)
+		return nil, err
+	}
+	if values.Inputs == nil {
+		values.Inputs = map[string]interface{}{}
+	}
+
+	chrt, err := loadHelmChart(templateDir, helmChart)
+	if err != nil {
+		klog.Errorf("Failed to load helm chart: %v", err)
+		return nil, err
+	}
+	if err := chartutil.ProcessDependenciesWithMerge(chrt, values.Inputs); err != nil {
+		return nil, err
+	}
+	options := chartutil.ReleaseOptions{
+		Name:      values.OpenAPP.InstanceName,
+		Namespace: InstanceNamespace,
+		Revision:  1,
+		IsInstall: true,
+	}
+	renderValues, err := chartutil.ToRenderValues(chrt, values.Inputs, options, chartutil.DefaultCapabilities)
+	if err != nil {
+		return nil, err
+	}
+	files, err := engine.Render(chrt, renderValues)
+	if err != nil {
+		return nil, err
+	}
+	for name := range files {
+		if strings.HasSuffix(name, helmNotesFileSuffix) {
+			delete(files, name)
+		}
+	}
+	_, manifests, err := releaseutil.SortManifests(files, chartutil.DefaultCapabilities.APIVersions,
+		releaseutil.InstallOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []RenderedManifest{}
+	for _, crd := range chrt.CRDObjects() {
+		ret = append(ret, RenderedManifest{Name: crd.Filename, Content: crd.File.Data})
+	}
+	for _, m := range manifests {
+		ret = append(ret, RenderedManifest{Name: m.Name, Content: []byte(m.Content)})
+	}
+	return ret, nil
+}
+
+func loadHelmChart(templateDir string, helmChart *appv1alpha1.HelmChart) (*chart.Chart, error) {
+	if helmChart.Path != "" {
+		// The chart can't be out of the template directory
+		return loader.Load(filepath.Join(templateDir, filepath.Clean("/"+helmChart.Path)))
+	}
+	if helmChart.Repository == "" || helmChart.Name == "" || helmChart.Version == "" {
+		return nil, fmt.Errorf("either path, or repository, name and version of the chart are required")
+	}
+	archive, err := pullHelmChart(helmChart)
+	if err != nil {
+		return nil, err
+	}
+	return loader.Load(archive)
+}
+
+// pullHelmChart downloads the chart of the repository into the cache, the
+// cached chart is used once it's downloaded since a chart version is not
+// supposed to be changed after it's released.
+func pullHelmChart(helmChart *appv1alpha1.HelmChart) (string, error) {
+	repoHash := sha256.Sum256([]byte(helmChart.Repository))
+	archive := path.Join(HelmChartCachePath, hex.EncodeToString(repoHash[:])[:16],
+		fmt.Sprintf("%s-%s.tgz", helmChart.Name, helmChart.Version))
+	if _, err := os.Stat(archive); err == nil {
+		return archive, nil
+	}
+
+	klog.Infof("Pulling helm chart %s(%s) from %s...", helmChart.Name, helmChart.Version, helmChart.Repository)
+	providers := getter.Providers{{Schemes: []string{"http", "https"}, New: getter.NewHTTPGetter}}
+	chartURL, err := repo.FindChartInRepoURL(helmChart.Repository, helmChart.Name, helmChart.Version,
+		"", "", "", providers)
+	if err != nil {
+		klog.Errorf("Failed to find helm chart: %v", err)
+		return "", err
+	}
+	g, err := getter.NewHTTPGetter()
+	if err != nil {
+		return "", err
+	}
+	buf, err := g.Get(chartURL)
+	if err != nil {
+		klog.Errorf("Failed to download helm chart: %v", err)
+		return "", err
+	}
+
+	if err := os.MkdirAll(path.Dir(archive), 0755); err != nil {
+		return "", err
+	}
+	// Write to a temporary file first, so a partial download is never cached
+	tmpFile := archive + ".tmp"
+	if err := os.WriteFile(tmpFile, buf.Bytes(), 0644); err != nil {
+		return "", err
+	}
+	return archive, os.Rename(tmpFile, archive)
+}
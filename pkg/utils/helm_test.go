@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
+)
+
+func TestRenderHelmChart(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		TemplateFileName: `
+apiVersion: app.openapp.dev/v1alpha1
+kind: AppTemplate
+metadata:
+  name: demo
+spec:
+  type: helm
+  chart:
+    path: chart
+`,
+		"chart/Chart.yaml": `
+apiVersion: v2
+name: demo
+version: 0.1.0
+`,
+		"chart/values.yaml": `
+replicas: 1
+`,
+		"chart/templates/deployment.yaml": `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .Release.Name }}
+  namespace: {{ .Release.Namespace }}
+spec:
+  replicas: {{ .Values.replicas }}
+`,
+		"chart/templates/service.yaml": `
+apiVersion: v1
+kind: Service
+metadata:
+  name: {{ .Release.Name }}
+`,
+		"chart/templates/NOTES.txt": `Installed {{ .Release.Name }}`,
+	}
+	for name, content := range files {
+		assert.NoError(t, os.MkdirAll(path.Dir(path.Join(dir, name)), 0755))
+		assert.NoError(t, os.WriteFile(path.Join(dir, name), []byte(content), 0644))
+	}
+
+	helmChart, err := LoadTemplateChart(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, &appv1alpha1.HelmChart{Path: "chart"}, helmChart)
+
+	rendered, err := RenderHelmChart(dir, helmChart,
+		`{"openapp":{"instance_name":"demo-1"},"inputs":{"replicas":3}}`)
+	assert.NoError(t, err)
+	assert.Len(t, rendered, 2)
+
+	objs := []string{}
+	for _, m := range rendered {
+		decoded, err := DecodeManifests(m.Content)
+		assert.NoError(t, err)
+		for _, obj := range decoded {
+			objs = append(objs, obj.GetKind()+"/"+obj.GetName())
+			if obj.GetKind() == "Deployment" {
+				assert.Equal(t, InstanceNamespace, obj.GetNamespace())
+				assert.Equal(t, int64(3), obj.Object["spec"].(map[string]interface{})["replicas"])
+			}
+		}
+	}
+	// The resources are in the install order of Helm
+	assert.Equal(t, []string{"Service/demo-1", "Deployment/demo-1"}, objs)
+
+	_, err = RenderHelmChart(dir, &appv1alpha1.HelmChart{Repository: "https://charts.example.com"},
+		`{"openapp":{"instance_name":"demo-1"}}`)
+	assert.Error(t, err)
+}
+
+func TestLoadTemplateChartOfManifestsTemplate(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(path.Join(dir, TemplateFileName), []byte(`
+kind: AppTemplate
+spec:
+  title: demo
+`), 0644))
+	helmChart, err := LoadTemplateChart(dir)
+	assert.NoError(t, err)
+	assert.Nil(t, helmChart)
+}
@@ -5,7 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"html/template"
+	"io/fs"
 	"net"
 	"os"
 	"path"
@@ -13,45 +13,56 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"text/template"
 
-	"github.com/ghodss/yaml"
-	v1 "k8s.io/api/apps/v1"
 	apicorev1 "k8s.io/api/core/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	corev1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/restmapper"
 	cache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
 
 	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
 	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
 	servicev1alpha1 "github.com/openapp-dev/openapp/pkg/apis/service/v1alpha1"
 	"github.com/openapp-dev/openapp/pkg/generated/clientset/versioned"
+	openappscheme "github.com/openapp-dev/openapp/pkg/generated/clientset/versioned/scheme"
 	openappinformer "github.com/openapp-dev/openapp/pkg/generated/informers/externalversions"
 	listerappv1alpha1 "github.com/openapp-dev/openapp/pkg/generated/listers/app/v1alpha1"
+	listerregistryv1alpha1 "github.com/openapp-dev/openapp/pkg/generated/listers/registry/v1alpha1"
 	listerservicev1alpha1 "github.com/openapp-dev/openapp/pkg/generated/listers/service/v1alpha1"
 )
 
 type OpenAPPHelper struct {
 	K8sClient                     kubernetes.Interface
 	OpenAPPClient                 versioned.Interface
+	DynamicClient                 dynamic.Interface
+	RESTMapper                    meta.RESTMapper
+	EventRecorder                 record.EventRecorder
 	ConfigMapInformer             cache.SharedIndexInformer
 	ServiceInformer               cache.SharedIndexInformer
 	AppInstanceInformer           cache.SharedIndexInformer
 	PublicServiceInstanceInformer cache.SharedIndexInformer
 	StatefulSetInformer           cache.SharedIndexInformer
+	RegistryInformer              cache.SharedIndexInformer
 	ConfigMapLister               corev1.ConfigMapLister
 	AppInstanceLister             listerappv1alpha1.AppInstanceLister
 	AppTemplateLister             listerappv1alpha1.AppTemplateLister
 	PublicServiceInstanceLister   listerservicev1alpha1.PublicServiceInstanceLister
 	PublicServiceTemplateLister   listerservicev1alpha1.PublicServiceTemplateLister
+	RegistryLister                listerregistryv1alpha1.RegistryLister
 }
 
 func NewOpenAPPHelper(ctx context.Context,
 	k8sClient kubernetes.Interface,
-	openappClient versioned.Interface) *OpenAPPHelper {
+	openappClient versioned.Interface,
+	dynamicClient dynamic.Interface) *OpenAPPHelper {
 	k8sFactory := informers.NewSharedInformerFactory(k8sClient, 0)
 	openappFactory := openappinformer.NewSharedInformerFactory(openappClient, 0)
 
@@ -60,20 +71,34 @@ func NewOpenAPPHelper(ctx context.Context,
 	statefulSetInformer := k8sFactory.Apps().V1().StatefulSets().Informer()
 	appInstanceInformer := openappFactory.App().V1alpha1().AppInstances().Informer()
 	serviceInstanceInformer := openappFactory.Service().V1alpha1().PublicServiceInstances().Informer()
+	registryInformer := openappFactory.Registry().V1alpha1().Registries().Informer()
+
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
+		Interface: k8sClient.CoreV1().Events(""),
+	})
+	eventRecorder := eventBroadcaster.NewRecorder(openappscheme.Scheme, apicorev1.EventSource{
+		Component: EventSourceComponent,
+	})
 
 	helper := OpenAPPHelper{
 		K8sClient:                     k8sClient,
 		OpenAPPClient:                 openappClient,
+		DynamicClient:                 dynamicClient,
+		RESTMapper:                    restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(k8sClient.Discovery())),
+		EventRecorder:                 eventRecorder,
 		ConfigMapInformer:             configMapInformer,
 		ServiceInformer:               serviceInformer,
 		AppInstanceInformer:           appInstanceInformer,
 		PublicServiceInstanceInformer: serviceInstanceInformer,
 		StatefulSetInformer:           statefulSetInformer,
+		RegistryInformer:              registryInformer,
 		ConfigMapLister:               k8sFactory.Core().V1().ConfigMaps().Lister(),
 		AppInstanceLister:             openappFactory.App().V1alpha1().AppInstances().Lister(),
 		AppTemplateLister:             openappFactory.App().V1alpha1().AppTemplates().Lister(),
 		PublicServiceInstanceLister:   openappFactory.Service().V1alpha1().PublicServiceInstances().Lister(),
 		PublicServiceTemplateLister:   openappFactory.Service().V1alpha1().PublicServiceTemplates().Lister(),
+		RegistryLister:                openappFactory.Registry().V1alpha1().Registries().Lister(),
 	}
 
 	k8sFactory.Start(ctx.Done())
@@ -82,6 +107,8 @@ func NewOpenAPPHelper(ctx context.Context,
 	return &helper
 }
 
+// GetRegistryPaths returns the cached registries, the registries in the index
+// come first in the order of their priorities.
 func GetRegistryPaths() []string {
 	ret := []string{}
 	dirs, err := os.ReadDir(RegistryCachePath)
@@ -89,11 +116,52 @@ func GetRegistryPaths() []string {
 		klog.Errorf("Failed to read registry cache path: %v", err)
 		return ret
 	}
+	names := []string{}
 	for _, dir := range dirs {
 		if dir.IsDir() {
-			ret = append(ret, filepath.Join(RegistryCachePath, dir.Name()))
+			names = append(names, dir.Name())
 		}
 	}
+
+	order := map[string]int{}
+	for i, name := range readRegistryIndex() {
+		order[name] = i
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		oi, iok := order[names[i]]
+		oj, jok := order[names[j]]
+		if iok != jok {
+			return iok
+		}
+		return oi < oj
+	})
+	for _, name := range names {
+		ret = append(ret, filepath.Join(RegistryCachePath, name))
+	}
+	return ret
+}
+
+// WriteRegistryIndex records the order to look up the templates in registries.
+func WriteRegistryIndex(names []string) error {
+	data, err := json.Marshal(names)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(RegistryCachePath, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path.Join(RegistryCachePath, RegistryIndexFileName), data, 0644)
+}
+
+func readRegistryIndex() []string {
+	ret := []string{}
+	data, err := os.ReadFile(path.Join(RegistryCachePath, RegistryIndexFileName))
+	if err != nil {
+		return ret
+	}
+	if err := json.Unmarshal(data, &ret); err != nil {
+		klog.Errorf("Failed to unmarshal registry index: %v", err)
+	}
 	return ret
 }
 
@@ -111,22 +179,16 @@ func GetAppTemplatePath(registryPath string) []string {
 	return ret
 }
 
-func FindAppTemplateResources(appTemplate string) []string {
-	return FindTemplateResources(appTemplate, AppTemplateBasePath)
+func FindPublicServiceTemplateResources(registry, publicServiceTemplate, version string) []string {
+	return FindTemplateResources(registry, publicServiceTemplate, version, PublicServiceTemplateBasePath)
 }
 
-func FindTemplateResources(tempName, tempBasePath string) []string {
-	registries := GetRegistryPaths()
-	for _, regiregistry := range registries {
-		templates := getTemplates(regiregistry, tempBasePath)
-		for _, t := range templates {
-			if t != tempName {
-				continue
-			}
-			return getTemplateManifests(regiregistry, tempName, tempBasePath)
-		}
+func FindTemplateResources(registry, tempName, version, tempBasePath string) []string {
+	templateDir := FindTemplateDir(registry, tempName, version, tempBasePath)
+	if templateDir == "" {
+		return nil
 	}
-	return nil
+	return GetTemplateManifests(templateDir)
 }
 
 func getTemplates(registryPath, tempBasePath string) []string {
@@ -143,23 +205,35 @@ func getTemplates(registryPath, tempBasePath string) []string {
 	return ret
 }
 
-func getTemplateManifests(registry, tempName, tempBasePath string) []string {
-	basicPath := path.Join(registry, tempBasePath, tempName, TemplateManifestsDirName)
-	files, err := os.ReadDir(basicPath)
+// GetTemplateManifests returns the manifest files in the manifests directory of
+// the template and its subdirectories, in lexical order. The objects in them
+// are applied in the order of their kinds, see SortManifestObjects.
+func GetTemplateManifests(templateDir string) []string {
+	basicPath := path.Join(templateDir, TemplateManifestsDirName)
+	ret := []string{}
+	err := filepath.WalkDir(basicPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !IsManifestFile(p) {
+			return nil
+		}
+		ret = append(ret, p)
+		return nil
+	})
 	if err != nil {
 		klog.Errorf("Failed to read registry template manifests path: %v", err)
 		return nil
 	}
-	ret := []string{}
-	for _, f := range files {
-		ret = append(ret, path.Join(basicPath, f.Name()))
-	}
-	sort.Slice(ret, func(i, j int) bool {
-		return strings.HasSuffix(ret[i], "statefulset.yaml")
-	})
 	return ret
 }
 
+// IsManifestFile reports whether the file in the manifests directory is rendered.
+func IsManifestFile(name string) bool {
+	ext := path.Ext(name)
+	return ext == ".yaml" || ext == ".yml"
+}
+
 func GetPublicServiceTemplatePath(registryPath string) []string {
 	ret := []string{}
 	basicPath := path.Join(registryPath, PublicServiceTemplatePath)
@@ -174,19 +248,51 @@ func GetPublicServiceTemplatePath(registryPath string) []string {
 	return ret
 }
 
-func ConstructTemplateWithValues(manifestFile, instanceValues string) ([]byte, error) {
+// ConstructTemplateWithValues renders the manifest with the instance values and
+// the functions of TemplateFuncs, the lookup may be nil without a cluster. The
+// values are not escaped, the templates should quote them where needed.
+func ConstructTemplateWithValues(manifestFile, instanceValues string, lookup InstanceLookupFunc) ([]byte, error) {
+	content, err := os.ReadFile(manifestFile)
+	if err != nil {
+		klog.Errorf("Failed to read manifest: %v", err)
+		return nil, err
+	}
+	return constructTemplateContentWithValues(TemplateManifestName(manifestFile), content, instanceValues, lookup)
+}
+
+// TemplateManifestName returns the path of the manifest relative to the
+// manifests directory of its template, so the manifests of the same name in
+// the subdirectories are told apart in the errors.
+func TemplateManifestName(manifestFile string) string {
+	for dir := path.Dir(manifestFile); dir != path.Dir(dir); dir = path.Dir(dir) {
+		if path.Base(dir) != TemplateManifestsDirName {
+			continue
+		}
+		if _, err := os.Stat(path.Join(path.Dir(dir), TemplateFileName)); err == nil {
+			return strings.TrimPrefix(manifestFile, dir+"/")
+		}
+	}
+	return path.Base(manifestFile)
+}
+
+func constructTemplateContentWithValues(name string, content []byte, instanceValues string,
+	lookup InstanceLookupFunc) ([]byte, error) {
 	var values map[string]interface{}
 	err := json.Unmarshal([]byte(instanceValues), &values)
 	if err != nil {
 		klog.Errorf("Failed to unmarshal values: %v", err)
 		return nil, err
 	}
-	// TDB: The parameter of New should be the file name, still don't know why
-	tmpl, err := template.New(path.Base(manifestFile)).ParseFiles(manifestFile)
+	// The errors are reported with the template name, which is the path in the
+	// manifests directory
+	funcs := TemplateFuncs(lookup)
+	funcs[missingValueFunc] = missingValue
+	tmpl, err := template.New(name).Funcs(funcs).Parse(string(content))
 	if err != nil {
 		klog.Errorf("Failed to parse template: %v", err)
 		return nil, err
 	}
+	printMissingValuesEmpty(tmpl)
 
 	var ret bytes.Buffer
 	err = tmpl.Execute(&ret, values)
@@ -197,12 +303,11 @@ func ConstructTemplateWithValues(manifestFile, instanceValues string) ([]byte, e
 	return ret.Bytes(), nil
 }
 
-func ConstructAppInstanceValues(instance *appv1alpha1.AppInstance) (string, error) {
-	var inputs map[string]interface{}
-	if err := yaml.Unmarshal([]byte(instance.Spec.Inputs), &inputs); err != nil {
-		klog.Errorf("Failed to unmarshal inputs: %v", err)
-		return "", err
-
+func ConstructAppInstanceValues(instance *appv1alpha1.AppInstance, schema *commonv1alpha1.InputSchema) (string, error) {
+	inputs, errs := ResolveInputs(instance.Spec.Inputs, schema)
+	if len(errs) != 0 {
+		klog.Errorf("Failed to resolve inputs: %v", errs.ToAggregate())
+		return "", errs.ToAggregate()
 	}
 	inputJson, err := json.Marshal(inputs)
 	if err != nil {
@@ -213,12 +318,12 @@ func ConstructAppInstanceValues(instance *appv1alpha1.AppInstance) (string, erro
 		instance.Spec.PublicServiceClass, inputJson), nil
 }
 
-func ConstructPublicServiceInstanceValues(instance *servicev1alpha1.PublicServiceInstance) (string, error) {
-	var inputs map[string]interface{}
-	if err := yaml.Unmarshal([]byte(instance.Spec.Inputs), &inputs); err != nil {
-		klog.Errorf("Failed to unmarshal inputs: %v", err)
-		return "", err
-
+func ConstructPublicServiceInstanceValues(instance *servicev1alpha1.PublicServiceInstance,
+	schema *commonv1alpha1.InputSchema) (string, error) {
+	inputs, errs := ResolveInputs(instance.Spec.Inputs, schema)
+	if len(errs) != 0 {
+		klog.Errorf("Failed to resolve inputs: %v", errs.ToAggregate())
+		return "", errs.ToAggregate()
 	}
 	inputJson, err := json.Marshal(inputs)
 	if err != nil {
@@ -264,191 +369,3 @@ func GetLocalServerIPAddress() (string, error) {
 
 	return "", fmt.Errorf("failed to find local server IP address")
 }
-
-func CleanInstanceDerivedServiceResource(client kubernetes.Interface, name, namespace string) error {
-	svc, err := client.CoreV1().Services(namespace).
-		Get(context.Background(), name, metav1.GetOptions{})
-	if err != nil {
-		if apierrors.IsNotFound(err) {
-			return nil
-		}
-		klog.Errorf("Failed to get service: %v", err)
-		return err
-	}
-	if svc.Finalizers != nil {
-		// K8s will add a finalizer to the service, we should delete it first
-		svc.Finalizers = nil
-		_, err = client.CoreV1().Services(namespace).
-			Update(context.Background(), svc, metav1.UpdateOptions{})
-		if err != nil {
-			klog.Errorf("Failed to delete service's finalizers: %v", err)
-			return err
-		}
-	}
-
-	err = client.CoreV1().Services(namespace).
-		Delete(context.Background(), name, metav1.DeleteOptions{})
-	if err != nil {
-		klog.Errorf("Failed to delete service: %v", err)
-		return err
-	}
-
-	return nil
-}
-
-func CleanInstanceDerivedConfigMapResource(client kubernetes.Interface, name, namespace string) error {
-	err := client.CoreV1().ConfigMaps(namespace).
-		Delete(context.Background(), name, metav1.DeleteOptions{})
-	if err != nil {
-		if apierrors.IsNotFound(err) {
-			return nil
-		}
-		klog.Errorf("Failed to delete configmap: %v", err)
-		return err
-	}
-	return nil
-}
-
-func CleanInstanceDerivedStatefulSetResource(client kubernetes.Interface, name, namespace string) error {
-	err := client.AppsV1().StatefulSets(namespace).
-		Delete(context.Background(), name, metav1.DeleteOptions{})
-	if err != nil {
-		if apierrors.IsNotFound(err) {
-			return nil
-		}
-		klog.Errorf("Failed to delete statefulset: %v", err)
-		return err
-	}
-	return nil
-}
-
-func CreateOrUpdateService(client kubernetes.Interface,
-	manifestContent []byte,
-	derivedResoruce *[]commonv1alpha1.DerivedResource,
-	labels map[string]string) error {
-	var svc apicorev1.Service
-	if err := yaml.Unmarshal(manifestContent, &svc); err != nil {
-		klog.Errorf("Failed to unmarshal service: %v", err)
-		return nil
-	}
-	svc.Labels = labels
-	*derivedResoruce = append(*derivedResoruce, commonv1alpha1.DerivedResource{
-		APIVersion: svc.APIVersion,
-		Kind:       svc.Kind,
-		Name:       svc.Name,
-	})
-
-	svcExist, err := client.CoreV1().Services(svc.Namespace).
-		Get(context.Background(), svc.Name, metav1.GetOptions{})
-	if err != nil {
-		if !apierrors.IsNotFound(err) {
-			klog.Errorf("Failed to get service: %v", err)
-			return err
-		}
-		_, err = client.CoreV1().Services(svc.Namespace).
-			Create(context.Background(), &svc, metav1.CreateOptions{})
-		if err != nil {
-			klog.Errorf("Failed to create service: %v", err)
-			return err
-		}
-		return nil
-	}
-	svcCopy := svcExist.DeepCopy()
-	svcCopy.Spec = svc.Spec
-	svcCopy.Labels = svc.Labels
-	_, err = client.CoreV1().Services(svcCopy.Namespace).
-		Update(context.Background(), svcCopy, metav1.UpdateOptions{})
-	return err
-}
-
-func CreateOrUpdateConfigmap(client kubernetes.Interface,
-	manifestContent []byte,
-	derivedResoruce *[]commonv1alpha1.DerivedResource,
-	labels map[string]string) error {
-	var cm apicorev1.ConfigMap
-	if err := yaml.Unmarshal(manifestContent, &cm); err != nil {
-		klog.Errorf("Failed to unmarshal configmap: %v", err)
-		return err
-	}
-	cm.Labels = labels
-	*derivedResoruce = append(*derivedResoruce, commonv1alpha1.DerivedResource{
-		APIVersion: cm.APIVersion,
-		Kind:       cm.Kind,
-		Name:       cm.Name,
-	})
-
-	cmExist, err := client.CoreV1().ConfigMaps(cm.Namespace).
-		Get(context.Background(), cm.Name, metav1.GetOptions{})
-	if err != nil {
-		if !apierrors.IsNotFound(err) {
-			klog.Errorf("Failed to get configmap: %v", err)
-			return err
-		}
-		_, err = client.CoreV1().ConfigMaps(cm.Namespace).
-			Create(context.Background(), &cm, metav1.CreateOptions{})
-		if err != nil {
-			klog.Errorf("Failed to create configmap: %v", err)
-			return err
-		}
-		return nil
-	}
-
-	cmCopy := cmExist.DeepCopy()
-	cmCopy.Data = cm.Data
-	cmCopy.Labels = cm.Labels
-	_, err = client.CoreV1().ConfigMaps(cmCopy.Namespace).
-		Update(context.Background(), cmCopy, metav1.UpdateOptions{})
-	return err
-}
-
-func CreateOrUpdateStatefulset(client kubernetes.Interface,
-	manifestContent []byte,
-	derivedResoruce *[]commonv1alpha1.DerivedResource,
-	labels map[string]string) error {
-	var sts v1.StatefulSet
-	if err := yaml.Unmarshal(manifestContent, &sts); err != nil {
-		klog.Errorf("Failed to unmarshal statefulset: %v", err)
-		return err
-	}
-	sts.Labels = labels
-	*derivedResoruce = append(*derivedResoruce, commonv1alpha1.DerivedResource{
-		APIVersion: sts.APIVersion,
-		Kind:       sts.Kind,
-		Name:       sts.Name,
-	})
-
-	stsExist, err := client.AppsV1().StatefulSets(sts.Namespace).
-		Get(context.Background(), sts.Name, metav1.GetOptions{})
-	if err != nil {
-		if !apierrors.IsNotFound(err) {
-			klog.Errorf("Failed to get statefulset: %v", err)
-			return err
-		}
-		_, err = client.AppsV1().StatefulSets(sts.Namespace).
-			Create(context.Background(), &sts, metav1.CreateOptions{})
-		if err != nil {
-			klog.Errorf("Failed to create statefulset: %v", err)
-			return err
-		}
-		return nil
-	}
-	if stsExist.Labels != nil &&
-		stsExist.Labels[InstanceGenerationLabelKey] == labels[InstanceGenerationLabelKey] {
-		return nil
-	}
-
-	// In order to make sure the sts will be restart, we should recreate it
-	err = client.AppsV1().StatefulSets(sts.Namespace).
-		Delete(context.Background(), stsExist.Name, metav1.DeleteOptions{})
-	if err != nil {
-		klog.Errorf("Failed to delete statefulset: %v", err)
-		return err
-	}
-	_, err = client.AppsV1().StatefulSets(sts.Namespace).
-		Create(context.Background(), &sts, metav1.CreateOptions{})
-	if err != nil {
-		klog.Errorf("Failed to create statefulset: %v", err)
-		return err
-	}
-	return nil
-}
@@ -0,0 +1,188 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
+)
+
+var inputsPath = field.NewPath("spec", "inputs")
+
+// ResolveInputs parses the YAML inputs of the instance, validates them against
+// the template schema and fills the defaults. The inputs are not validated if
+// the template doesn't declare a schema.
+func ResolveInputs(inputs string, schema *commonv1alpha1.InputSchema) (map[string]interface{}, field.ErrorList) {
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(inputs), &values); err != nil {
+		return nil, field.ErrorList{field.Invalid(inputsPath, field.OmitValueType{}, err.Error())}
+	}
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+
+	if errs := ValidateInputs(schema, values); len(errs) != 0 {
+		return nil, errs
+	}
+	return values, nil
+}
+
+// ValidateInputs validates the inputs against the schema and fills the
+// defaults into the inputs, the errors are reported with the input path.
+func ValidateInputs(schema *commonv1alpha1.InputSchema, inputs map[string]interface{}) field.ErrorList {
+	if schema == nil {
+		return nil
+	}
+
+	errs := field.ErrorList{}
+	for _, name := range sortedInputNames(inputs) {
+		if _, ok := schema.Properties[name]; !ok {
+			errs = append(errs, field.NotSupported(inputsPath.Child(name), name, sortedPropertyNames(schema)))
+		}
+	}
+
+	for _, name := range sortedPropertyNames(schema) {
+		property := schema.Properties[name]
+		if _, ok := inputs[name]; !ok && property.Default != nil && len(property.Default.Raw) != 0 {
+			var defaultValue interface{}
+			if err := json.Unmarshal(property.Default.Raw, &defaultValue); err != nil {
+				errs = append(errs, field.InternalError(inputsPath.Child(name),
+					fmt.Errorf("invalid default value: %v", err)))
+				continue
+			}
+			inputs[name] = defaultValue
+		}
+		value, ok := inputs[name]
+		if !ok {
+			continue
+		}
+		errs = append(errs, validateInput(inputsPath.Child(name), property, value)...)
+	}
+
+	for _, name := range schema.Required {
+		if _, ok := inputs[name]; !ok {
+			errs = append(errs, field.Required(inputsPath.Child(name), ""))
+		}
+	}
+	return errs
+}
+
+// ValidateInputSchema checks the schema declared by the template, the required
+// inputs must be declared and the defaults must be valid.
+func ValidateInputSchema(schema *commonv1alpha1.InputSchema, fldPath *field.Path) field.ErrorList {
+	if schema == nil {
+		return nil
+	}
+
+	errs := field.ErrorList{}
+	for _, name := range sortedPropertyNames(schema) {
+		property := schema.Properties[name]
+		propertyPath := fldPath.Child("properties").Key(name)
+		if !isSupportedInputType(property.Type) {
+			errs = append(errs, field.NotSupported(propertyPath.Child("type"), property.Type, supportedInputTypes))
+			continue
+		}
+		if property.Default == nil || len(property.Default.Raw) == 0 {
+			continue
+		}
+		var defaultValue interface{}
+		if err := json.Unmarshal(property.Default.Raw, &defaultValue); err != nil {
+			errs = append(errs, field.Invalid(propertyPath.Child("default"), string(property.Default.Raw), err.Error()))
+			continue
+		}
+		errs = append(errs, validateInput(propertyPath.Child("default"), property, defaultValue)...)
+	}
+	for i, name := range schema.Required {
+		if _, ok := schema.Properties[name]; !ok {
+			errs = append(errs, field.Invalid(fldPath.Child("required").Index(i), name, "input is not declared in properties"))
+		}
+	}
+	return errs
+}
+
+var supportedInputTypes = []string{
+	string(commonv1alpha1.InputTypeString),
+	string(commonv1alpha1.InputTypeInteger),
+	string(commonv1alpha1.InputTypeNumber),
+	string(commonv1alpha1.InputTypeBoolean),
+	string(commonv1alpha1.InputTypeObject),
+	string(commonv1alpha1.InputTypeArray),
+}
+
+func isSupportedInputType(inputType commonv1alpha1.InputType) bool {
+	for _, t := range supportedInputTypes {
+		if string(inputType) == t {
+			return true
+		}
+	}
+	return false
+}
+
+func validateInput(fldPath *field.Path, property commonv1alpha1.InputProperty, value interface{}) field.ErrorList {
+	strValue := fmt.Sprint(value)
+	// Secret inputs should never be shown in the status or events
+	var shownValue interface{} = value
+	if property.Secret {
+		shownValue = field.OmitValueType{}
+	}
+
+	if !isInputType(property.Type, value) {
+		return field.ErrorList{field.Invalid(fldPath, shownValue, fmt.Sprintf("must be of type %s", property.Type))}
+	}
+	if len(property.Enum) == 0 {
+		return nil
+	}
+	for _, e := range property.Enum {
+		if e == strValue {
+			return nil
+		}
+	}
+	return field.ErrorList{field.NotSupported(fldPath, shownValue, property.Enum)}
+}
+
+func isInputType(inputType commonv1alpha1.InputType, value interface{}) bool {
+	switch inputType {
+	case commonv1alpha1.InputTypeString:
+		_, ok := value.(string)
+		return ok
+	case commonv1alpha1.InputTypeInteger:
+		v, ok := value.(float64)
+		return ok && v == math.Trunc(v)
+	case commonv1alpha1.InputTypeNumber:
+		_, ok := value.(float64)
+		return ok
+	case commonv1alpha1.InputTypeBoolean:
+		_, ok := value.(bool)
+		return ok
+	case commonv1alpha1.InputTypeObject:
+		_, ok := value.(map[string]interface{})
+		return ok
+	case commonv1alpha1.InputTypeArray:
+		_, ok := value.([]interface{})
+		return ok
+	}
+	return false
+}
+
+func sortedInputNames(inputs map[string]interface{}) []string {
+	ret := []string{}
+	for name := range inputs {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func sortedPropertyNames(schema *commonv1alpha1.InputSchema) []string {
+	ret := []string{}
+	for name := range schema.Properties {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
+)
+
+func TestResolveInputs(t *testing.T) {
+	schema := &commonv1alpha1.InputSchema{
+		Properties: map[string]commonv1alpha1.InputProperty{
+			"port": {
+				Type:    commonv1alpha1.InputTypeInteger,
+				Default: &runtime.RawExtension{Raw: []byte("8080")},
+			},
+			"mode": {
+				Type: commonv1alpha1.InputTypeString,
+				Enum: []string{"dev", "prod"},
+			},
+			"password": {
+				Type:   commonv1alpha1.InputTypeString,
+				Secret: true,
+			},
+		},
+		Required: []string{"password"},
+	}
+
+	inputs, errs := ResolveInputs("mode: dev\npassword: foo\n", schema)
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{
+		"port":     float64(8080),
+		"mode":     "dev",
+		"password": "foo",
+	}, inputs)
+
+	_, errs = ResolveInputs("mode: test\nport: 80.5\npasword: foo\n", schema)
+	fields := []string{}
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	assert.ElementsMatch(t, []string{"spec.inputs.pasword", "spec.inputs.mode",
+		"spec.inputs.port", "spec.inputs.password"}, fields)
+
+	_, errs = ResolveInputs("password: 123\n", schema)
+	assert.Len(t, errs, 1)
+	assert.NotContains(t, errs.ToAggregate().Error(), "123")
+
+	inputs, errs = ResolveInputs("anything: 1\n", nil)
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"anything": float64(1)}, inputs)
+}
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// manifestKindOrder is the order the objects of the known kinds are applied,
+// the objects are applied after the ones they depend on, e.g. the configs and
+// secrets before the workloads. The other kinds are applied at last.
+var manifestKindOrder = []string{
+	"Namespace",
+	"NetworkPolicy",
+	"ResourceQuota",
+	"LimitRange",
+	"PodDisruptionBudget",
+	"ServiceAccount",
+	"Secret",
+	"ConfigMap",
+	"StorageClass",
+	"PersistentVolume",
+	"PersistentVolumeClaim",
+	"CustomResourceDefinition",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"Role",
+	"RoleBinding",
+	"Service",
+	"DaemonSet",
+	"Pod",
+	"ReplicaSet",
+	"Deployment",
+	"HorizontalPodAutoscaler",
+	"StatefulSet",
+	"Job",
+	"CronJob",
+	"Ingress",
+}
+
+// DecodeManifests splits the rendered manifest into YAML documents and converts
+// them into unstructured objects, the empty documents are skipped.
+func DecodeManifests(manifestContent []byte) ([]*unstructured.Unstructured, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(manifestContent)))
+	ret := []*unstructured.Unstructured{}
+	for i := 1; ; i++ {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return ret, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		obj, err := DecodeManifest(doc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid document %d: %v", i, err)
+		}
+		if obj != nil {
+			ret = append(ret, obj)
+		}
+	}
+}
+
+// SortManifestObjects sorts the objects in the order they should be applied,
+// the objects of the same kind are kept in the order they are rendered.
+func SortManifestObjects(objs []*unstructured.Unstructured) {
+	order := map[string]int{}
+	for i, kind := range manifestKindOrder {
+		order[kind] = i
+	}
+	rank := func(obj *unstructured.Unstructured) int {
+		if i, ok := order[obj.GetKind()]; ok {
+			return i
+		}
+		return len(manifestKindOrder)
+	}
+	sort.SliceStable(objs, func(i, j int) bool {
+		return rank(objs[i]) < rank(objs[j])
+	})
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeManifests(t *testing.T) {
+	objs, err := DecodeManifests([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+---
+# The empty documents are skipped
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: web
+`))
+	assert.NoError(t, err)
+	SortManifestObjects(objs)
+	names := []string{}
+	for _, obj := range objs {
+		names = append(names, obj.GetKind()+"/"+obj.GetName())
+	}
+	assert.Equal(t, []string{"Secret/web", "Service/web", "Service/api", "Deployment/web"}, names)
+
+	_, err = DecodeManifests([]byte("kind: Service\n---\nkind: [Service\n"))
+	assert.ErrorContains(t, err, "invalid document 2")
+}
+
+func TestGetTemplateManifests(t *testing.T) {
+	templateDir := t.TempDir()
+	for _, f := range []string{"statefulset.yaml", "config/configmap.yml", "service.yaml", "README.md"} {
+		p := path.Join(templateDir, TemplateManifestsDirName, f)
+		assert.NoError(t, os.MkdirAll(path.Dir(p), 0755))
+		assert.NoError(t, os.WriteFile(p, nil, 0644))
+	}
+	manifestsDir := path.Join(templateDir, TemplateManifestsDirName)
+	assert.Equal(t, []string{
+		path.Join(manifestsDir, "config/configmap.yml"),
+		path.Join(manifestsDir, "service.yaml"),
+		path.Join(manifestsDir, "statefulset.yaml"),
+	}, GetTemplateManifests(templateDir))
+}
@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/ghodss/yaml"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
+)
+
+type instancePatch struct {
+	// json6902 is set if it's a JSON6902 patch, otherwise mergePatch is the
+	// strategic merge patch
+	json6902   jsonpatch.Patch
+	mergePatch []byte
+	target     appv1alpha1.PatchTarget
+	selector   labels.Selector
+}
+
+// ApplyInstancePatches applies the patches of the instance to the rendered
+// objects in order. It's an error if a patch matches no object, so the typos of
+// the targets are not ignored silently.
+func ApplyInstancePatches(objs []*unstructured.Unstructured, patches []appv1alpha1.InstancePatch) error {
+	for i := range patches {
+		p, err := parseInstancePatch(&patches[i])
+		if err != nil {
+			return fmt.Errorf("spec.patches[%d]: %v", i, err)
+		}
+		matched := false
+		for _, obj := range objs {
+			if !p.matches(obj) {
+				continue
+			}
+			if err := p.apply(obj); err != nil {
+				return fmt.Errorf("spec.patches[%d]: failed to patch %s(%s): %v", i, obj.GetKind(), obj.GetName(), err)
+			}
+			matched = true
+		}
+		if !matched {
+			return fmt.Errorf("spec.patches[%d]: no rendered object matches the target", i)
+		}
+	}
+	return nil
+}
+
+// ValidateInstancePatches checks the patches can be parsed, the targets are
+// only known once the template is rendered.
+func ValidateInstancePatches(patches []appv1alpha1.InstancePatch, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	for i := range patches {
+		if _, err := parseInstancePatch(&patches[i]); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Index(i), field.OmitValueType{}, err.Error()))
+		}
+	}
+	return errs
+}
+
+func parseInstancePatch(patch *appv1alpha1.InstancePatch) (*instancePatch, error) {
+	d, err := yaml.YAMLToJSON([]byte(patch.Patch))
+	if err != nil {
+		return nil, fmt.Errorf("invalid patch: %v", err)
+	}
+	ret := &instancePatch{selector: labels.Everything()}
+	if patch.Target != nil {
+		ret.target = *patch.Target
+		if ret.selector, err = labels.Parse(patch.Target.LabelSelector); err != nil {
+			return nil, fmt.Errorf("invalid target.labelSelector: %v", err)
+		}
+	}
+
+	d = bytes.TrimSpace(d)
+	switch {
+	case bytes.HasPrefix(d, []byte("[")):
+		if patch.Target == nil {
+			return nil, fmt.Errorf("target is required by the JSON6902 patch")
+		}
+		if ret.json6902, err = jsonpatch.DecodePatch(d); err != nil {
+			return nil, fmt.Errorf("invalid JSON6902 patch: %v", err)
+		}
+	case bytes.HasPrefix(d, []byte("{")):
+		mergePatch := map[string]interface{}{}
+		if err := json.Unmarshal(d, &mergePatch); err != nil {
+			return nil, fmt.Errorf("invalid strategic merge patch: %v", err)
+		}
+		obj := &unstructured.Unstructured{Object: mergePatch}
+		if patch.Target == nil {
+			gv, err := schema.ParseGroupVersion(obj.GetAPIVersion())
+			if err != nil {
+				return nil, fmt.Errorf("invalid apiVersion: %v", err)
+			}
+			ret.target = appv1alpha1.PatchTarget{Group: gv.Group, Version: gv.Version,
+				Kind: obj.GetKind(), Name: obj.GetName()}
+			if ret.target.Kind == "" || ret.target.Name == "" {
+				return nil, fmt.Errorf("kind and metadata.name of the strategic merge patch are required without target")
+			}
+		}
+		// The patch selects the objects but never renames them
+		delete(mergePatch, "apiVersion")
+		delete(mergePatch, "kind")
+		unstructured.RemoveNestedField(mergePatch, "metadata", "name")
+		if ret.mergePatch, err = json.Marshal(mergePatch); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("patch must be an object or a list of JSON6902 operations")
+	}
+	return ret, nil
+}
+
+func (p *instancePatch) matches(obj *unstructured.Unstructured) bool {
+	gvk := obj.GroupVersionKind()
+	return (p.target.Group == "" || p.target.Group == gvk.Group) &&
+		(p.target.Version == "" || p.target.Version == gvk.Version) &&
+		(p.target.Kind == "" || p.target.Kind == gvk.Kind) &&
+		(p.target.Name == "" || p.target.Name == obj.GetName()) &&
+		p.selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+func (p *instancePatch) apply(obj *unstructured.Unstructured) error {
+	original, err := obj.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	var patched []byte
+	if p.json6902 != nil {
+		patched, err = p.json6902.Apply(original)
+	} else {
+		var dataStruct runtime.Object
+		dataStruct, err = scheme.Scheme.New(obj.GroupVersionKind())
+		switch {
+		case err == nil:
+			patched, err = strategicpatch.StrategicMergePatch(original, p.mergePatch, dataStruct)
+		case runtime.IsNotRegisteredError(err):
+			// The custom resources have no patch strategy, they are merged like kustomize does
+			patched, err = jsonpatch.MergePatch(original, p.mergePatch)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	ret := &unstructured.Unstructured{}
+	if err := ret.UnmarshalJSON(patched); err != nil {
+		return err
+	}
+	obj.Object = ret.Object
+	return nil
+}
@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
+)
+
+const testPatchManifests = `
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: demo
+  labels:
+    app: demo
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx
+        resources:
+          limits:
+            cpu: 100m
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: demo
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: demo
+spec:
+  size: 1
+  color: red
+`
+
+func TestApplyInstancePatches(t *testing.T) {
+	objs, err := DecodeManifests([]byte(testPatchManifests))
+	assert.NoError(t, err)
+
+	err = ApplyInstancePatches(objs, []appv1alpha1.InstancePatch{
+		{
+			Patch: `
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: demo
+spec:
+  template:
+    spec:
+      nodeSelector:
+        disk: ssd
+      containers:
+      - name: web
+        resources:
+          limits:
+            cpu: 500m
+      - name: sidecar
+        image: busybox
+`,
+		},
+		{
+			Patch:  `[{"op": "replace", "path": "/spec/size", "value": 3}]`,
+			Target: &appv1alpha1.PatchTarget{Kind: "Widget"},
+		},
+		{
+			Patch:  "metadata:\n  annotations:\n    patched: \"true\"\nspec:\n  color: null\n",
+			Target: &appv1alpha1.PatchTarget{Group: "example.com", Name: "demo"},
+		},
+	})
+	assert.NoError(t, err)
+
+	sts := objs[0].Object
+	containers, _, _ := unstructured.NestedSlice(sts, "spec", "template", "spec", "containers")
+	assert.Len(t, containers, 2)
+	cpu, _, _ := unstructured.NestedString(containers[0].(map[string]interface{}), "resources", "limits", "cpu")
+	assert.Equal(t, "500m", cpu)
+	image, _, _ := unstructured.NestedString(containers[0].(map[string]interface{}), "image")
+	assert.Equal(t, "nginx", image)
+	disk, _, _ := unstructured.NestedString(sts, "spec", "template", "spec", "nodeSelector", "disk")
+	assert.Equal(t, "ssd", disk)
+	assert.Equal(t, "demo", objs[0].GetName())
+
+	size, _, _ := unstructured.NestedInt64(objs[2].Object, "spec", "size")
+	assert.Equal(t, int64(3), size)
+	_, found, _ := unstructured.NestedFieldNoCopy(objs[2].Object, "spec", "color")
+	assert.False(t, found)
+	assert.Equal(t, "true", objs[2].GetAnnotations()["patched"])
+	assert.Empty(t, objs[1].GetAnnotations())
+
+	cases := []struct {
+		name     string
+		patch    appv1alpha1.InstancePatch
+		expected string
+	}{
+		{
+			name:     "no matched object",
+			patch:    appv1alpha1.InstancePatch{Patch: "kind: Deployment\nmetadata:\n  name: demo\n"},
+			expected: "spec.patches[0]: no rendered object matches the target",
+		},
+		{
+			name:     "label selector",
+			patch:    appv1alpha1.InstancePatch{Patch: "{}", Target: &appv1alpha1.PatchTarget{LabelSelector: "app=web"}},
+			expected: "no rendered object matches the target",
+		},
+		{
+			name: "failed operation",
+			patch: appv1alpha1.InstancePatch{Patch: `[{"op": "remove", "path": "/spec/missing"}]`,
+				Target: &appv1alpha1.PatchTarget{Kind: "Service"}},
+			expected: "failed to patch Service(demo)",
+		},
+	}
+	for _, c := range cases {
+		err := ApplyInstancePatches(objs, []appv1alpha1.InstancePatch{c.patch})
+		assert.ErrorContains(t, err, c.expected, c.name)
+	}
+}
+
+func TestValidateInstancePatches(t *testing.T) {
+	errs := ValidateInstancePatches([]appv1alpha1.InstancePatch{
+		{Patch: "kind: Service\nmetadata:\n  name: demo\n"},
+		{Patch: `[{"op": "add", "path": "/metadata/labels/a", "value": "b"}]`},
+		{Patch: "spec: {}"},
+		{Patch: "{}", Target: &appv1alpha1.PatchTarget{LabelSelector: "a in (b"}},
+		{Patch: "just a string"},
+	}, field.NewPath("spec", "patches"))
+	assert.Len(t, errs, 4)
+	assert.Contains(t, errs.ToAggregate().Error(), "spec.patches[1]: Invalid value: target is required by the JSON6902 patch")
+	assert.Contains(t, errs.ToAggregate().Error(), "spec.patches[2]")
+	assert.Contains(t, errs.ToAggregate().Error(), "target.labelSelector")
+	assert.Contains(t, errs.ToAggregate().Error(), "must be an object or a list")
+}
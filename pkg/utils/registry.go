@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"os"
+	"path"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
+	registryv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/registry/v1alpha1"
+	servicev1alpha1 "github.com/openapp-dev/openapp/pkg/apis/service/v1alpha1"
+	listerregistryv1alpha1 "github.com/openapp-dev/openapp/pkg/generated/listers/registry/v1alpha1"
+)
+
+// NewRegistrySyncedHandler calls the sync func when a registry is synced or
+// deleted, so the templates can be refreshed from the registry cache.
+func NewRegistrySyncedHandler(sync func()) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(_ interface{}) {
+			sync()
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldRegistry, ok := oldObj.(*registryv1alpha1.Registry)
+			if !ok {
+				return
+			}
+			newRegistry, ok := newObj.(*registryv1alpha1.Registry)
+			if !ok {
+				return
+			}
+			if newRegistry.Status.LastError != "" ||
+				newRegistry.Status.LastSyncTime.Equal(oldRegistry.Status.LastSyncTime) {
+				return
+			}
+			sync()
+		},
+		DeleteFunc: func(_ interface{}) {
+			sync()
+		},
+	}
+}
+
+// ParseTemplateReference splits the template reference of the instances, it's
+// either `template` or `registry/template`. The registry is empty for the
+// former, the template is looked up in the registries by priority.
+func ParseTemplateReference(ref string) (string, string) {
+	registry, name, found := strings.Cut(ref, "/")
+	if !found {
+		return "", ref
+	}
+	return registry, name
+}
+
+// ShadowedTemplateName returns the object name of the template which has the
+// same name as a template of a higher priority registry. The object name can't
+// have a separator out of the template names, so it may collide with a dotted
+// template name, the template synced later is skipped then.
+func ShadowedTemplateName(registry, tempName string) string {
+	return registry + "." + tempName
+}
+
+// SetTemplateSource labels the template object with its registry and its name
+// in the registry.
+func SetTemplateSource(obj metav1.Object, registry, tempName string) {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[RegistryLabelKey] = registry
+	labels[TemplateNameLabelKey] = tempName
+	obj.SetLabels(labels)
+}
+
+// GetTemplateName returns the name of the template in its registry, which is
+// different from the object name if the template is shadowed.
+func GetTemplateName(obj metav1.Object) string {
+	if name := obj.GetLabels()[TemplateNameLabelKey]; name != "" {
+		return name
+	}
+	return obj.GetName()
+}
+
+// IsTemplateReferenced reports whether the template reference of an instance
+// points to the template object.
+func IsTemplateReferenced(ref string, obj metav1.Object) bool {
+	if ref == obj.GetName() {
+		return true
+	}
+	registry, tempName := ParseTemplateReference(ref)
+	return registry != "" && registry == obj.GetLabels()[RegistryLabelKey] && tempName == GetTemplateName(obj)
+}
+
+// IsTemplateDeprecated reports whether the template is removed from its
+// registry but kept for the instances still using it.
+func IsTemplateDeprecated(obj metav1.Object) bool {
+	_, ok := obj.GetAnnotations()[TemplateDeprecatedAnnotationKey]
+	return ok
+}
+
+// IsRegistryPruneable reports whether the templates missing in the registry
+// cache are really removed, it's false if the registry exists but isn't
+// cached yet, e.g. the first sync after restart is not done.
+func IsRegistryPruneable(registryLister listerregistryv1alpha1.RegistryLister, registry string) bool {
+	if _, err := registryLister.Get(registry); err != nil {
+		return apierrors.IsNotFound(err)
+	}
+	_, err := os.Stat(path.Join(RegistryCachePath, registry))
+	return err == nil
+}
+
+// GetAppTemplate returns the app template of the reference with the get func,
+// e.g. the Get of the lister or the client.
+func GetAppTemplate(get func(name string) (*appv1alpha1.AppTemplate, error),
+	ref string) (*appv1alpha1.AppTemplate, error) {
+	registry, tempName := ParseTemplateReference(ref)
+	appTemp, err := get(tempName)
+	if registry == "" || (err == nil && appTemp.Spec.Registry == registry) {
+		return appTemp, err
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	appTemp, err = get(ShadowedTemplateName(registry, tempName))
+	if err != nil {
+		return nil, err
+	}
+	if appTemp.Spec.Registry != registry || GetTemplateName(appTemp) != tempName {
+		return nil, apierrors.NewNotFound(appv1alpha1.Resource("apptemplates"), ref)
+	}
+	return appTemp, nil
+}
+
+// GetPublicServiceTemplate returns the publicservice template of the reference
+// with the get func, e.g. the Get of the lister or the client.
+func GetPublicServiceTemplate(get func(name string) (*servicev1alpha1.PublicServiceTemplate, error),
+	ref string) (*servicev1alpha1.PublicServiceTemplate, error) {
+	registry, tempName := ParseTemplateReference(ref)
+	temp, err := get(tempName)
+	if registry == "" || (err == nil && temp.Spec.Registry == registry) {
+		return temp, err
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	temp, err = get(ShadowedTemplateName(registry, tempName))
+	if err != nil {
+		return nil, err
+	}
+	if temp.Spec.Registry != registry || GetTemplateName(temp) != tempName {
+		return nil, apierrors.NewNotFound(servicev1alpha1.Resource("publicservicetemplates"), ref)
+	}
+	return temp, nil
+}
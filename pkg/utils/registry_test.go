@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
+)
+
+func TestGetAppTemplate(t *testing.T) {
+	templates := map[string]*appv1alpha1.AppTemplate{}
+	for _, temp := range []struct{ registry, name, objName string }{
+		{"official", "nginx", "nginx"},
+		{"community", "nginx", ShadowedTemplateName("community", "nginx")},
+		{"community", "redis", "redis"},
+	} {
+		appTemp := &appv1alpha1.AppTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: temp.objName},
+			Spec:       appv1alpha1.AppTemplateSpec{Registry: temp.registry},
+		}
+		SetTemplateSource(appTemp, temp.registry, temp.name)
+		templates[temp.objName] = appTemp
+	}
+	get := func(name string) (*appv1alpha1.AppTemplate, error) {
+		if appTemp, ok := templates[name]; ok {
+			return appTemp, nil
+		}
+		return nil, apierrors.NewNotFound(appv1alpha1.Resource("apptemplates"), name)
+	}
+
+	for ref, expected := range map[string]string{
+		"nginx":           "official",
+		"official/nginx":  "official",
+		"community/nginx": "community",
+		"community/redis": "community",
+	} {
+		appTemp, err := GetAppTemplate(get, ref)
+		assert.NoError(t, err, ref)
+		assert.Equal(t, expected, appTemp.Spec.Registry, ref)
+		_, tempName := ParseTemplateReference(ref)
+		assert.Equal(t, tempName, GetTemplateName(appTemp), ref)
+	}
+
+	for _, ref := range []string{"official/redis", "unknown/nginx", "mysql"} {
+		_, err := GetAppTemplate(get, ref)
+		assert.True(t, apierrors.IsNotFound(err), ref)
+	}
+}
+
+func TestIsTemplateReferenced(t *testing.T) {
+	appTemp := &appv1alpha1.AppTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: ShadowedTemplateName("community", "nginx")},
+	}
+	SetTemplateSource(appTemp, "community", "nginx")
+
+	assert.True(t, IsTemplateReferenced("community.nginx", appTemp))
+	assert.True(t, IsTemplateReferenced("community/nginx", appTemp))
+	assert.False(t, IsTemplateReferenced("nginx", appTemp))
+	assert.False(t, IsTemplateReferenced("official/nginx", appTemp))
+}
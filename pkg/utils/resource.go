@@ -0,0 +1,256 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog"
+
+	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
+)
+
+// DecodeManifest converts the rendered manifest into an unstructured object,
+// a nil object is returned if the manifest is empty after rendering.
+func DecodeManifest(manifestContent []byte) (*unstructured.Unstructured, error) {
+	jsonContent, err := yaml.YAMLToJSON(manifestContent)
+	if err != nil {
+		klog.Errorf("Failed to convert manifest to json: %v", err)
+		return nil, err
+	}
+	if string(jsonContent) == "null" {
+		return nil, nil
+	}
+
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(jsonContent); err != nil {
+		klog.Errorf("Failed to unmarshal manifest: %v", err)
+		return nil, err
+	}
+	return obj, nil
+}
+
+// GetDerivedResourceInterface returns the dynamic resource client of the given kind,
+// namespace will be ignored if the kind is cluster scoped.
+func GetDerivedResourceInterface(dynamicClient dynamic.Interface,
+	mapper meta.RESTMapper,
+	apiVersion, kind, namespace string) (dynamic.ResourceInterface, error) {
+	mapping, err := getRESTMapping(mapper, apiVersion, kind)
+	if err != nil {
+		return nil, err
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		return dynamicClient.Resource(mapping.Resource).Namespace(namespace), nil
+	}
+	return dynamicClient.Resource(mapping.Resource), nil
+}
+
+func getRESTMapping(mapper meta.RESTMapper, apiVersion, kind string) (*meta.RESTMapping, error) {
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		klog.Errorf("Failed to parse apiVersion(%s): %v", apiVersion, err)
+		return nil, err
+	}
+	mapping, err := mapper.RESTMapping(gv.WithKind(kind).GroupKind(), gv.Version)
+	if err != nil {
+		klog.Errorf("Failed to get rest mapping of %s/%s: %v", apiVersion, kind, err)
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// ApplyDerivedResource applies the rendered object with server-side apply, the
+// fields set by other managers are kept, and a conflict error will be returned
+// if the manifest tries to take over the fields owned by others.
+func ApplyDerivedResource(dynamicClient dynamic.Interface,
+	mapper meta.RESTMapper,
+	obj *unstructured.Unstructured,
+	derivedResource *[]commonv1alpha1.DerivedResource,
+	labels map[string]string,
+	owner *metav1.OwnerReference) error {
+	if obj.GetKind() == "" || obj.GetName() == "" {
+		return fmt.Errorf("kind and name are required in manifest")
+	}
+	if obj.GetNamespace() == "" {
+		obj.SetNamespace(InstanceNamespace)
+	}
+	objLabels := obj.GetLabels()
+	if objLabels == nil {
+		objLabels = map[string]string{}
+	}
+	for k, v := range labels {
+		objLabels[k] = v
+	}
+	obj.SetLabels(objLabels)
+
+	mapping, err := getRESTMapping(mapper, obj.GetAPIVersion(), obj.GetKind())
+	if err != nil {
+		return err
+	}
+	d := commonv1alpha1.DerivedResource{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Name:       obj.GetName(),
+	}
+	// The namespace is only recorded if it's not the instance namespace, so the
+	// resources derived before are still the same ones
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace && obj.GetNamespace() != InstanceNamespace {
+		d.Namespace = obj.GetNamespace()
+	}
+	*derivedResource = append(*derivedResource, d)
+	var client dynamic.ResourceInterface = dynamicClient.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		client = dynamicClient.Resource(mapping.Resource).Namespace(obj.GetNamespace())
+		// Owner reference can't cross namespaces, the cluster scoped resources
+		// or the ones in other namespaces still rely on the instance finalizer.
+		if owner != nil && obj.GetNamespace() == InstanceNamespace {
+			obj.SetOwnerReferences([]metav1.OwnerReference{*owner})
+		}
+	}
+
+	_, err = client.Apply(context.Background(), obj.GetName(), obj, metav1.ApplyOptions{
+		FieldManager: DerivedResourceFieldManager,
+	})
+	if err != nil && obj.GetKind() == InstanceDerivedResourceStatefulSetKind &&
+		isStatefulSetImmutableFieldError(err) {
+		// The statefulset is rolled out in place, it will be recreated only if
+		// immutable fields such as selector or volumeClaimTemplates are changed.
+		klog.Infof("Recreating statefulset(%s) since immutable fields changed: %v", obj.GetName(), err)
+		err = recreateStatefulSet(client, obj)
+	}
+	if err != nil {
+		klog.Errorf("Failed to apply %s(%s): %v", obj.GetKind(), obj.GetName(), err)
+		return err
+	}
+	return nil
+}
+
+// statefulSetImmutableFieldMessage is the message of the error returned by the
+// apiserver when the forbidden fields of the statefulset spec are updated.
+const statefulSetImmutableFieldMessage = "updates to statefulset spec for fields other than"
+
+// isStatefulSetImmutableFieldError checks whether the update of statefulset is
+// rejected because the immutable fields are changed, other invalid errors are
+// not fixed by recreating it.
+func isStatefulSetImmutableFieldError(err error) bool {
+	if !apierrors.IsInvalid(err) {
+		return false
+	}
+	statusErr, ok := err.(apierrors.APIStatus)
+	if !ok || statusErr.Status().Details == nil {
+		return false
+	}
+	for _, cause := range statusErr.Status().Details.Causes {
+		if cause.Type == metav1.CauseTypeForbidden &&
+			strings.Contains(cause.Message, statefulSetImmutableFieldMessage) {
+			return true
+		}
+	}
+	return false
+}
+
+func recreateStatefulSet(client dynamic.ResourceInterface, sts *unstructured.Unstructured) error {
+	err := client.Delete(context.Background(), sts.GetName(), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.Errorf("Failed to delete statefulset: %v", err)
+		return err
+	}
+	_, err = client.Apply(context.Background(), sts.GetName(), sts, metav1.ApplyOptions{
+		FieldManager: DerivedResourceFieldManager,
+	})
+	return err
+}
+
+// DerivedResourceConflictMessage returns the message shown on the instance
+// status if the error is caused by a server-side apply conflict.
+func DerivedResourceConflictMessage(err error) (string, bool) {
+	if !apierrors.IsConflict(err) {
+		return "", false
+	}
+	return fmt.Sprintf("Derived resources are conflicted with other field managers: %v", err), true
+}
+
+// CleanInstanceDerivedResource deletes the derived resource, it's in the given
+// namespace unless the namespace of the resource is recorded.
+func CleanInstanceDerivedResource(dynamicClient dynamic.Interface,
+	mapper meta.RESTMapper,
+	derivedResource commonv1alpha1.DerivedResource,
+	namespace string) error {
+	if derivedResource.Namespace != "" {
+		namespace = derivedResource.Namespace
+	}
+	client, err := GetDerivedResourceInterface(dynamicClient, mapper,
+		derivedResource.APIVersion, derivedResource.Kind, namespace)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil
+		}
+		return err
+	}
+
+	if derivedResource.Kind == InstanceDerivedResourceServiceKind {
+		svc, err := client.Get(context.Background(), derivedResource.Name, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			klog.Errorf("Failed to get service: %v", err)
+			return err
+		}
+		if len(svc.GetFinalizers()) != 0 {
+			// K8s will add a finalizer to the service, we should delete it first
+			svc.SetFinalizers(nil)
+			_, err = client.Update(context.Background(), svc, metav1.UpdateOptions{})
+			if err != nil {
+				klog.Errorf("Failed to delete service's finalizers: %v", err)
+				return err
+			}
+		}
+	}
+
+	err = client.Delete(context.Background(), derivedResource.Name, metav1.DeleteOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		klog.Errorf("Failed to delete %s(%s): %v", derivedResource.Kind, derivedResource.Name, err)
+		return err
+	}
+	return nil
+}
+
+// CleanStaleDerivedResources deletes the resources which were derived before
+// but are no longer rendered from the template.
+func CleanStaleDerivedResources(dynamicClient dynamic.Interface,
+	mapper meta.RESTMapper,
+	oldDerivedResource, newDerivedResource []commonv1alpha1.DerivedResource,
+	namespace string) error {
+	for _, old := range oldDerivedResource {
+		stale := true
+		for _, d := range newDerivedResource {
+			if d == old {
+				stale = false
+				break
+			}
+		}
+		if !stale {
+			continue
+		}
+		ns := namespace
+		if old.Namespace != "" {
+			ns = old.Namespace
+		}
+		klog.Infof("Deleting stale %s(%s/%s)...", old.Kind, ns, old.Name)
+		if err := CleanInstanceDerivedResource(dynamicClient, mapper, old, ns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
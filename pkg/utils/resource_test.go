@@ -0,0 +1,239 @@
+package utils
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
+)
+
+// newFakeApplyDynamicClient returns a fake dynamic client which treats the
+// apply patch as create-or-replace, the fake tracker can't handle it by itself.
+func newFakeApplyDynamicClient(gvr schema.GroupVersionResource) *dynamicfake.FakeDynamicClient {
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{gvr: "DeploymentList"})
+	dynamicClient.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patchAction := action.(k8stesting.PatchAction)
+		if patchAction.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(patchAction.GetPatch()); err != nil {
+			return true, nil, err
+		}
+		tracker := dynamicClient.Tracker()
+		_, err := tracker.Get(gvr, patchAction.GetNamespace(), patchAction.GetName())
+		if apierrors.IsNotFound(err) {
+			return true, obj, tracker.Create(gvr, obj, patchAction.GetNamespace())
+		}
+		return true, obj, tracker.Update(gvr, obj, patchAction.GetNamespace())
+	})
+	return dynamicClient
+}
+
+func decodeTestManifest(t *testing.T, manifest string) *unstructured.Unstructured {
+	obj, err := DecodeManifest([]byte(manifest))
+	assert.NoError(t, err)
+	return obj
+}
+
+func TestApplyDerivedResource(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(gvr.GroupVersion().WithKind("Deployment"), meta.RESTScopeNamespace)
+	dynamicClient := newFakeApplyDynamicClient(gvr)
+	labels := map[string]string{AppInstanceLabelKey: "demo"}
+
+	derivedResource := []commonv1alpha1.DerivedResource{}
+	err := ApplyDerivedResource(dynamicClient, mapper,
+		decodeTestManifest(t, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: demo
+spec:
+  replicas: 1
+`), &derivedResource, labels, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []commonv1alpha1.DerivedResource{
+		{APIVersion: "apps/v1", Kind: "Deployment", Name: "demo"},
+	}, derivedResource)
+
+	derivedResource = []commonv1alpha1.DerivedResource{}
+	err = ApplyDerivedResource(dynamicClient, mapper,
+		decodeTestManifest(t, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: demo
+spec:
+  replicas: 2
+`), &derivedResource, labels, nil)
+	assert.NoError(t, err)
+
+	obj, err := dynamicClient.Resource(gvr).Namespace(InstanceNamespace).
+		Get(context.Background(), "demo", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "demo", obj.GetLabels()[AppInstanceLabelKey])
+	assert.Equal(t, int64(2), obj.Object["spec"].(map[string]interface{})["replicas"])
+
+	err = CleanStaleDerivedResources(dynamicClient, mapper,
+		derivedResource, nil, InstanceNamespace)
+	assert.NoError(t, err)
+	_, err = dynamicClient.Resource(gvr).Namespace(InstanceNamespace).
+		Get(context.Background(), "demo", metav1.GetOptions{})
+	assert.Error(t, err)
+
+	// The resources in other namespaces are cleaned in their own namespace
+	derivedResource = []commonv1alpha1.DerivedResource{}
+	err = ApplyDerivedResource(dynamicClient, mapper,
+		decodeTestManifest(t, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: demo
+  namespace: other
+`), &derivedResource, labels, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []commonv1alpha1.DerivedResource{
+		{APIVersion: "apps/v1", Kind: "Deployment", Name: "demo", Namespace: "other"},
+	}, derivedResource)
+	err = CleanStaleDerivedResources(dynamicClient, mapper,
+		derivedResource, nil, InstanceNamespace)
+	assert.NoError(t, err)
+	_, err = dynamicClient.Resource(gvr).Namespace("other").
+		Get(context.Background(), "demo", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+// newFakeStatefulSetClient returns a fake dynamic client which rejects the
+// changes of the statefulset selector like the apiserver does.
+func newFakeStatefulSetClient() (*dynamicfake.FakeDynamicClient, schema.GroupVersionResource) {
+	gvr := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "statefulsets"}
+	dynamicClient := newFakeApplyDynamicClient(gvr)
+	dynamicClient.PrependReactor("patch", "statefulsets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patchAction := action.(k8stesting.PatchAction)
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(patchAction.GetPatch()); err != nil {
+			return true, nil, err
+		}
+		live, err := dynamicClient.Tracker().Get(gvr, patchAction.GetNamespace(), patchAction.GetName())
+		if err != nil {
+			return false, nil, nil
+		}
+		liveSelector, _, _ := unstructured.NestedFieldCopy(live.(*unstructured.Unstructured).Object, "spec", "selector")
+		selector, _, _ := unstructured.NestedFieldCopy(obj.Object, "spec", "selector")
+		if assert.ObjectsAreEqual(liveSelector, selector) {
+			return false, nil, nil
+		}
+		return true, nil, apierrors.NewInvalid(schema.GroupKind{Group: "apps", Kind: "StatefulSet"}, obj.GetName(),
+			field.ErrorList{field.Forbidden(field.NewPath("spec"),
+				"updates to statefulset spec for fields other than 'replicas', 'ordinals', 'template', "+
+					"'updateStrategy', 'persistentVolumeClaimRetentionPolicy' and 'minReadySeconds' are forbidden")})
+	})
+	return dynamicClient, gvr
+}
+
+func testStatefulSet(app, image string) string {
+	return `
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: demo
+spec:
+  selector:
+    matchLabels:
+      app: ` + app + `
+  template:
+    spec:
+      containers:
+      - name: demo
+        image: ` + image + `
+`
+}
+
+func countDeleteActions(dynamicClient *dynamicfake.FakeDynamicClient) int {
+	ret := 0
+	for _, action := range dynamicClient.Actions() {
+		if action.GetVerb() == "delete" {
+			ret++
+		}
+	}
+	return ret
+}
+
+func TestApplyStatefulSetInPlace(t *testing.T) {
+	dynamicClient, gvr := newFakeStatefulSetClient()
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(gvr.GroupVersion().WithKind("StatefulSet"), meta.RESTScopeNamespace)
+
+	for _, image := range []string{"nginx:1.25", "nginx:1.26"} {
+		derivedResource := []commonv1alpha1.DerivedResource{}
+		err := ApplyDerivedResource(dynamicClient, mapper,
+			decodeTestManifest(t, testStatefulSet("demo", image)), &derivedResource, nil, nil)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 0, countDeleteActions(dynamicClient))
+
+	obj, err := dynamicClient.Resource(gvr).Namespace(InstanceNamespace).
+		Get(context.Background(), "demo", metav1.GetOptions{})
+	assert.NoError(t, err)
+	containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	assert.Equal(t, "nginx:1.26", containers[0].(map[string]interface{})["image"])
+}
+
+func TestApplyStatefulSetRecreate(t *testing.T) {
+	dynamicClient, gvr := newFakeStatefulSetClient()
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(gvr.GroupVersion().WithKind("StatefulSet"), meta.RESTScopeNamespace)
+
+	for _, app := range []string{"demo", "demo-v2"} {
+		derivedResource := []commonv1alpha1.DerivedResource{}
+		err := ApplyDerivedResource(dynamicClient, mapper,
+			decodeTestManifest(t, testStatefulSet(app, "nginx:1.25")), &derivedResource, nil, nil)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, countDeleteActions(dynamicClient))
+
+	obj, err := dynamicClient.Resource(gvr).Namespace(InstanceNamespace).
+		Get(context.Background(), "demo", metav1.GetOptions{})
+	assert.NoError(t, err)
+	app, _, _ := unstructured.NestedString(obj.Object, "spec", "selector", "matchLabels", "app")
+	assert.Equal(t, "demo-v2", app)
+}
+
+func TestIsStatefulSetImmutableFieldError(t *testing.T) {
+	gk := schema.GroupKind{Group: "apps", Kind: "StatefulSet"}
+	assert.False(t, isStatefulSetImmutableFieldError(apierrors.NewInvalid(gk, "demo",
+		field.ErrorList{field.Invalid(field.NewPath("spec", "replicas"), -1, "must be greater than or equal to 0")})))
+	assert.False(t, isStatefulSetImmutableFieldError(apierrors.NewBadRequest("bad request")))
+	assert.True(t, isStatefulSetImmutableFieldError(apierrors.NewInvalid(gk, "demo",
+		field.ErrorList{field.Forbidden(field.NewPath("spec"), "updates to statefulset spec for fields other than 'replicas' are forbidden")})))
+}
+
+func TestDecodeEmptyManifest(t *testing.T) {
+	obj, err := DecodeManifest([]byte("# nothing rendered\n"))
+	assert.NoError(t, err)
+	assert.Nil(t, obj)
+}
+
+func TestDerivedResourceConflictMessage(t *testing.T) {
+	_, ok := DerivedResourceConflictMessage(apierrors.NewBadRequest("bad request"))
+	assert.False(t, ok)
+
+	msg, ok := DerivedResourceConflictMessage(apierrors.NewApplyConflict(nil, "conflicts with \"kubectl\""))
+	assert.True(t, ok)
+	assert.Contains(t, msg, "kubectl")
+}
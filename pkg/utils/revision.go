@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+
+	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
+)
+
+// InstanceRevisionData is the content of an applied revision, it's stored in
+// the data of the ControllerRevision.
+type InstanceRevisionData struct {
+	Inputs          string                      `json:"inputs"`
+	TemplateVersion string                      `json:"templateVersion"`
+	ManifestsHash   string                      `json:"manifestsHash,omitempty"`
+	Patches         []appv1alpha1.InstancePatch `json:"patches,omitempty"`
+}
+
+type InstanceRevision struct {
+	InstanceRevisionData
+	Revision     int64       `json:"revision"`
+	CreationTime metav1.Time `json:"creationTime"`
+}
+
+// HashManifests returns the hash of the rendered manifests, it tells what was
+// applied by the revision but it's not part of the revision name, since the
+// manifests may be rendered differently each time, e.g. the random values.
+func HashManifests(manifestContents [][]byte) string {
+	h := sha256.New()
+	for _, content := range manifestContents {
+		h.Write(content)
+		h.Write([]byte("\n---\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ListAppInstanceRevisions returns the revisions of the app instance, the newest
+// comes first.
+func ListAppInstanceRevisions(k8sClient kubernetes.Interface, insName string) ([]appsv1.ControllerRevision, error) {
+	revisions, err := k8sClient.AppsV1().ControllerRevisions(InstanceNamespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: AppInstanceLabelKey + "=" + insName,
+	})
+	if err != nil {
+		klog.Errorf("Failed to list revisions of app instance(%s): %v", insName, err)
+		return nil, err
+	}
+	ret := revisions.Items
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Revision > ret[j].Revision
+	})
+	return ret, nil
+}
+
+// DecodeInstanceRevision returns the inputs, template version and patches
+// stored in the controller revision.
+func DecodeInstanceRevision(revision *appsv1.ControllerRevision) (*InstanceRevision, error) {
+	ret := &InstanceRevision{}
+	if err := json.Unmarshal(revision.Data.Raw, &ret.InstanceRevisionData); err != nil {
+		klog.Errorf("Failed to unmarshal revision(%s): %v", revision.Name, err)
+		return nil, err
+	}
+	ret.Revision = revision.Revision
+	ret.CreationTime = revision.CreationTimestamp
+	return ret, nil
+}
+
+// NewInstanceRevisionName returns a stable name for the revision content, so
+// re-applying the same content reuses the revision. The rendered manifests are
+// not hashed since they may be random, e.g. the generated passwords.
+func NewInstanceRevisionName(insName string, data *InstanceRevisionData) string {
+	h := sha256.New()
+	h.Write([]byte(data.Inputs))
+	h.Write([]byte(data.TemplateVersion))
+	// The revisions without patches keep the names before the patches are supported
+	if len(data.Patches) != 0 {
+		d, _ := json.Marshal(data.Patches)
+		h.Write(d)
+	}
+	return fmt.Sprintf("%s-%s", insName, hex.EncodeToString(h.Sum(nil))[:10])
+}
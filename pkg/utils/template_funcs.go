@@ -0,0 +1,310 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/ghodss/yaml"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	listerappv1alpha1 "github.com/openapp-dev/openapp/pkg/generated/listers/app/v1alpha1"
+	listerservicev1alpha1 "github.com/openapp-dev/openapp/pkg/generated/listers/service/v1alpha1"
+)
+
+// InstanceLookupFunc returns the status of another instance for the lookup
+// template function, the status is empty if the instance doesn't exist.
+type InstanceLookupFunc func(kind, name string) (map[string]interface{}, error)
+
+// NewInstanceLookupFunc looks up the instances with the listers.
+func NewInstanceLookupFunc(appInstanceLister listerappv1alpha1.AppInstanceLister,
+	publicServiceInstanceLister listerservicev1alpha1.PublicServiceInstanceLister) InstanceLookupFunc {
+	return func(kind, name string) (map[string]interface{}, error) {
+		switch kind {
+		case "AppInstance":
+			ins, err := appInstanceLister.AppInstances(InstanceNamespace).Get(name)
+			if apierrors.IsNotFound(err) {
+				return map[string]interface{}{}, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{
+				"name":               ins.Name,
+				"ready":              ins.Status.AppReady,
+				"localServiceURL":    ins.Status.LocalServiceURL,
+				"externalServiceURL": ins.Status.ExternalServiceURL,
+			}, nil
+		case "PublicServiceInstance":
+			ins, err := publicServiceInstanceLister.PublicServiceInstances(InstanceNamespace).Get(name)
+			if apierrors.IsNotFound(err) {
+				return map[string]interface{}{}, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{
+				"name":            ins.Name,
+				"ready":           ins.Status.PublicServiceReady,
+				"localServiceURL": ins.Status.LocalServiceURL,
+			}, nil
+		}
+		return nil, fmt.Errorf("unsupported kind %s, must be AppInstance or PublicServiceInstance", kind)
+	}
+}
+
+// TemplateFuncs returns the functions of the template manifests. They are a
+// subset of Sprig (https://masterminds.github.io/sprig/) with the same names
+// and argument order, so the snippets of Helm charts work as is:
+//
+//	default, empty, coalesce, ternary, required, fail,
+//	toString, quote, squote, upper, lower, trim, trimPrefix, trimSuffix,
+//	replace, contains, hasPrefix, hasSuffix, indent, nindent, join,
+//	toYaml, toJson, b64enc, b64dec, sha256sum, randAlphaNum
+//
+// And the OpenAPP helpers:
+//
+//	lookup KIND NAME: the status of the AppInstance or PublicServiceInstance,
+//	  with the fields name, ready, localServiceURL and externalServiceURL. It's
+//	  empty if the instance doesn't exist or the lookup is nil, e.g. the
+//	  template is rendered without a cluster.
+//
+// Note that randAlphaNum returns a different value each time the manifest is
+// rendered, i.e. whenever the instance is reconciled, so it shouldn't be used
+// for the values that must be stable, e.g. the passwords of the databases.
+func TemplateFuncs(lookup InstanceLookupFunc) template.FuncMap {
+	return template.FuncMap{
+		"default":  defaultValue,
+		"empty":    empty,
+		"coalesce": coalesce,
+		"ternary": func(vt, vf interface{}, cond bool) interface{} {
+			if cond {
+				return vt
+			}
+			return vf
+		},
+		"required": required,
+		"fail": func(msg string) (string, error) {
+			return "", errors.New(msg)
+		},
+
+		"toString": toString,
+		"quote":    quote,
+		"squote": func(v interface{}) string {
+			// The single quote is escaped by doubling it in YAML
+			return "'" + strings.ReplaceAll(toString(v), "'", "''") + "'"
+		},
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":    func(old, repl, s string) string { return strings.ReplaceAll(s, old, repl) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"indent":     indent,
+		"nindent": func(spaces int, s string) string {
+			return "\n" + indent(spaces, s)
+		},
+		"join": join,
+
+		"toYaml": func(v interface{}) (string, error) {
+			d, err := yaml.Marshal(v)
+			return strings.TrimSuffix(string(d), "\n"), err
+		},
+		"toJson": toJSON,
+		"b64enc": func(s string) string {
+			return base64.StdEncoding.EncodeToString([]byte(s))
+		},
+		"b64dec": func(s string) (string, error) {
+			d, err := base64.StdEncoding.DecodeString(s)
+			return string(d), err
+		},
+		"sha256sum": func(s string) string {
+			sum := sha256.Sum256([]byte(s))
+			return hex.EncodeToString(sum[:])
+		},
+		"randAlphaNum": randAlphaNum,
+
+		"lookup": func(kind, name string) (map[string]interface{}, error) {
+			if lookup == nil {
+				return map[string]interface{}{}, nil
+			}
+			return lookup(kind, name)
+		},
+	}
+}
+
+// empty reports whether the value is nil, false, zero or an empty string, map
+// or slice.
+func empty(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return true
+	}
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return rv.IsNil()
+	}
+	return rv.IsZero()
+}
+
+func defaultValue(d interface{}, given ...interface{}) interface{} {
+	if len(given) == 0 || empty(given[0]) {
+		return d
+	}
+	return given[0]
+}
+
+func coalesce(v ...interface{}) interface{} {
+	for _, val := range v {
+		if !empty(val) {
+			return val
+		}
+	}
+	return nil
+}
+
+func required(msg string, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, errors.New(msg)
+	}
+	if s, ok := v.(string); ok && s == "" {
+		return nil, errors.New(msg)
+	}
+	return v, nil
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func quote(v ...interface{}) string {
+	ret := []string{}
+	for _, s := range v {
+		if s != nil {
+			ret = append(ret, fmt.Sprintf("%q", toString(s)))
+		}
+	}
+	return strings.Join(ret, " ")
+}
+
+func indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+func join(sep string, v interface{}) string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return toString(v)
+	}
+	ret := []string{}
+	for i := 0; i < rv.Len(); i++ {
+		if e := rv.Index(i).Interface(); e != nil {
+			ret = append(ret, toString(e))
+		}
+	}
+	return strings.Join(ret, sep)
+}
+
+func toJSON(v interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	// The values are kept as is like the other functions, e.g. "&" in the URLs
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+const alphaNum = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+func randAlphaNum(n int) (string, error) {
+	ret := make([]byte, n)
+	max := big.NewInt(int64(len(alphaNum)))
+	for i := range ret {
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		ret[i] = alphaNum[idx.Int64()]
+	}
+	return string(ret), nil
+}
+
+// missingValueFunc is piped at the end of the actions that print, the missing
+// values are printed empty instead of "<no value>".
+const missingValueFunc = "openappMissingValue"
+
+func missingValue(v interface{}) interface{} {
+	if v == nil {
+		return ""
+	}
+	return v
+}
+
+// printMissingValuesEmpty rewrites the parsed templates, so the printed values
+// pass through missingValueFunc. The literal text is never changed.
+func printMissingValuesEmpty(tmpl *template.Template) {
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			pipeMissingValue(t.Tree, t.Tree.Root)
+		}
+	}
+}
+
+func pipeMissingValue(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			pipeMissingValue(tree, child)
+		}
+	case *parse.ActionNode:
+		// The assignments print nothing
+		if len(n.Pipe.Decl) != 0 {
+			return
+		}
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pos,
+			Args:     []parse.Node{parse.NewIdentifier(missingValueFunc).SetTree(tree).SetPos(n.Pos)},
+		})
+	case *parse.IfNode:
+		pipeMissingValue(tree, n.List)
+		pipeMissingValue(tree, n.ElseList)
+	case *parse.RangeNode:
+		pipeMissingValue(tree, n.List)
+		pipeMissingValue(tree, n.ElseList)
+	case *parse.WithNode:
+		pipeMissingValue(tree, n.List)
+		pipeMissingValue(tree, n.ElseList)
+	}
+}
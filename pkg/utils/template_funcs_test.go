@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func renderTestTemplate(t *testing.T, manifest string, lookup InstanceLookupFunc) (string, error) {
+	manifestFile := path.Join(t.TempDir(), "deployment.yaml")
+	assert.NoError(t, os.WriteFile(manifestFile, []byte(manifest), 0644))
+	values := `{"openapp": {"instance_name": "test"}, "inputs": {"password": "a&b<c>", "port": 8080, "tags": ["a", "b"]}}`
+	content, err := ConstructTemplateWithValues(manifestFile, values, lookup)
+	return string(content), err
+}
+
+func TestConstructTemplateWithValues(t *testing.T) {
+	lookup := func(kind, name string) (map[string]interface{}, error) {
+		if kind == "AppInstance" && name == "db" {
+			return map[string]interface{}{"localServiceURL": "http://db:5432"}, nil
+		}
+		return map[string]interface{}{}, nil
+	}
+	cases := []struct {
+		manifest string
+		expected string
+	}{
+		{`{{ .inputs.password }}`, `a&b<c>`},
+		{`{{ .inputs.password | quote }}`, `"a&b<c>"`},
+		{`{{ "it's" | squote }}`, `'it''s'`},
+		{`{{ .inputs.missing }}`, ``},
+		{`{{ .inputs.missing.key }}`, ``},
+		{`{{ if .inputs.missing }}x{{ else }}[{{ .inputs.missing }}]{{ end }}`, `[]`},
+		{`{{ $port := .inputs.port }}{{ $port }}`, `8080`},
+		{`<no value> {{ .inputs.port }}`, `<no value> 8080`},
+		{`{{ .inputs.missing | default "nginx" }}`, `nginx`},
+		{`{{ .inputs.port | default 80 }}`, `8080`},
+		{`{{ coalesce .inputs.missing "" "x" }}`, `x`},
+		{`{{ .inputs.password | b64enc }}`, `YSZiPGM+`},
+		{`{{ "YSZiPGM+" | b64dec }}`, `a&b<c>`},
+		{`{{ "openapp" | sha256sum }}`, `0166f0cd3c69ba0bd97a2cf09fabd7034fbaf03d46d395f46d8a100bbaf022e3`},
+		{`{{ join "," .inputs.tags }}`, `a,b`},
+		{"tags:{{ .inputs.tags | toYaml | nindent 2 }}", "tags:\n  - a\n  - b"},
+		{`{{ .inputs | toJson }}`, `{"password":"a&b<c>","port":8080,"tags":["a","b"]}`},
+		{`{{ .openapp.instance_name | upper | trimPrefix "T" }}`, `EST`},
+		{`{{ ternary "on" "off" (empty .inputs.missing) }}`, `on`},
+		{`{{ (lookup "AppInstance" "db").localServiceURL }}`, `http://db:5432`},
+		{`{{ (lookup "AppInstance" "other").localServiceURL | default "none" }}`, `none`},
+		{`{{ randAlphaNum 16 | len }}`, `16`},
+	}
+	for _, c := range cases {
+		content, err := renderTestTemplate(t, c.manifest, lookup)
+		assert.NoError(t, err, c.manifest)
+		assert.Equal(t, c.expected, content, c.manifest)
+	}
+
+	// The lookup of the template rendered without a cluster is empty
+	content, err := renderTestTemplate(t, `{{ (lookup "AppInstance" "db").localServiceURL | default "none" }}`, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "none", content)
+
+	_, err = renderTestTemplate(t, "a: 1\nb: {{ required \"password2 is required\" .inputs.password2 }}", lookup)
+	assert.ErrorContains(t, err, "deployment.yaml:2:")
+	assert.ErrorContains(t, err, "password2 is required")
+
+	_, err = renderTestTemplate(t, "a: 1\nb: {{ .inputs.password ", lookup)
+	assert.ErrorContains(t, err, "deployment.yaml:2:")
+
+	// The manifests in the subdirectories are reported by the relative path
+	templateDir := t.TempDir()
+	manifestFile := path.Join(templateDir, TemplateManifestsDirName, "db", "deployment.yaml")
+	assert.NoError(t, os.MkdirAll(path.Dir(manifestFile), 0755))
+	assert.NoError(t, os.WriteFile(path.Join(templateDir, TemplateFileName), nil, 0644))
+	assert.NoError(t, os.WriteFile(manifestFile, []byte("{{ required \"missing\" .inputs.missing }}"), 0644))
+	_, err = ConstructTemplateWithValues(manifestFile, `{"inputs": {}}`, nil)
+	assert.ErrorContains(t, err, "db/deployment.yaml:1:")
+}
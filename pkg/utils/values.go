@@ -4,7 +4,11 @@ const (
 	OpenAPPHelperKey = "openappHelper"
 
 	RegistryKey                   = "registry"
+	RegistryCredentialsKey        = "registryCredentials"
 	RegistryCachePath             = "/root/openapp/registry"
+	RegistryIndexFileName         = ".index.json"
+	TemplateVersionCachePath      = "/root/openapp/template-versions"
+	HelmChartCachePath            = "/root/openapp/helm-charts"
 	AppTemplatePath               = "app-template"
 	AppTemplateBasePath           = "app-template"
 	PublicServiceTemplatePath     = "publicservice-template"
@@ -13,27 +17,37 @@ const (
 	TemplateResourceDirName       = "resource"
 	TemplateManifestsDirName      = "manifests"
 
-	RegistryUpdateTimeAnnotationKey = "registry.openapp.dev/update-time"
-	ServiceExposeClassLabelKey      = "service.openapp.dev/expose-class"
-	AppInstanceLabelKey             = "app.openapp.dev/app-instance"
-	PublicServiceInstanceLabelKey   = "service.openapp.dev/publicservice-instance"
-	InstanceGenerationLabelKey      = "instance.openapp.dev/instance-generation"
+	RegistryFromConfigLabelKey    = "registry.openapp.dev/from-config"
+	RegistryLabelKey              = "registry.openapp.dev/registry"
+	TemplateNameLabelKey          = "registry.openapp.dev/template"
+	ServiceExposeClassLabelKey    = "service.openapp.dev/expose-class"
+	AppInstanceLabelKey           = "app.openapp.dev/app-instance"
+	PublicServiceInstanceLabelKey = "service.openapp.dev/publicservice-instance"
+	InstanceGenerationLabelKey    = "instance.openapp.dev/instance-generation"
+
+	TemplateDeprecatedAnnotationKey    = "registry.openapp.dev/deprecated"
+	RegistrySyncRequestedAnnotationKey = "registry.openapp.dev/sync-requested-at"
 
 	InstanceNamespace = "openapp"
 	SystemNamespace   = "openapp-system"
 	SystemConfigMap   = "openapp-config"
 	VolumeConfigMap   = "volume-config"
 
-	TemplateManifestServiceFile     = "service.yaml"
-	TemplateManifestStatefulSetFile = "statefulset.yaml"
-	TemplateManifestConfigMapFile   = "configmap.yaml"
-
 	InstanceDerivedResourceServiceKind     = "Service"
 	InstanceDerivedResourceStatefulSetKind = "StatefulSet"
-	InstanceDerivedResourceConfigMapKind   = "ConfigMap"
 
 	OpenAPPDNSName = "openapp"
 
+	DefaultRevisionHistoryLimit = 10
+
+	DerivedResourceFieldManager = "openapp-controller"
+	EventSourceComponent        = "openapp-controller"
+
+	WebhookServiceName                 = "openapp-webhook"
+	WebhookCertSecretName              = "openapp-webhook-certs"
+	ValidatingWebhookConfigurationName = "openapp-validating-webhook"
+	MutatingWebhookConfigurationName   = "openapp-mutating-webhook"
+
 	PublicServiceInstanceControllerFinalizerKey = "publicservice-instance-controller"
 	AppInstanceControllerFinalizerKey           = "app-instance-controller"
 )
@@ -0,0 +1,279 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/ghodss/yaml"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/klog"
+
+	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
+	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
+)
+
+type templateVersionMeta struct {
+	Spec struct {
+		Version     string                      `json:"version"`
+		InputSchema *commonv1alpha1.InputSchema `json:"inputSchema,omitempty"`
+		Type        appv1alpha1.AppTemplateType `json:"type,omitempty"`
+		Chart       *appv1alpha1.HelmChart      `json:"chart,omitempty"`
+		Compose     string                      `json:"compose,omitempty"`
+	} `json:"spec"`
+}
+
+func readTemplateVersionMeta(templateDir string) (*templateVersionMeta, error) {
+	d, err := os.ReadFile(path.Join(templateDir, TemplateFileName))
+	if err != nil {
+		klog.Errorf("Failed to read template file: %v", err)
+		return nil, err
+	}
+	meta := &templateVersionMeta{}
+	if err := yaml.Unmarshal(d, meta); err != nil {
+		klog.Errorf("Failed to unmarshal template: %v", err)
+		return nil, err
+	}
+	return meta, nil
+}
+
+// SnapshotTemplateVersions copies the templates checked out in the registries
+// into the version cache, a version is never overwritten once it's cached, so
+// the instances pinned to it are not changed by the registry pull.
+func SnapshotTemplateVersions() error {
+	for _, registry := range GetRegistryPaths() {
+		for _, tempBasePath := range []string{AppTemplateBasePath, PublicServiceTemplateBasePath} {
+			for _, tempName := range getTemplates(registry, tempBasePath) {
+				if err := snapshotTemplateVersion(path.Join(registry, tempBasePath, tempName),
+					path.Base(registry), tempName, tempBasePath); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func snapshotTemplateVersion(templateDir, registry, tempName, tempBasePath string) error {
+	version, err := getTemplateVersion(templateDir)
+	if err != nil || version == "" {
+		// The templates without a valid version are always rendered from the registry
+		return nil
+	}
+	versionDir := getTemplateVersionDir(registry, tempName, version, tempBasePath)
+	if _, err := os.Stat(versionDir); err == nil {
+		return nil
+	}
+
+	klog.Infof("Caching template %s/%s/%s version %s...", registry, tempBasePath, tempName, version)
+	if err := os.MkdirAll(path.Dir(versionDir), 0755); err != nil {
+		klog.Errorf("Failed to create version cache of template %s: %v", tempName, err)
+		return err
+	}
+	tmpDir := versionDir + ".tmp"
+	_ = os.RemoveAll(tmpDir)
+	if err := CopyDir(templateDir, tmpDir); err != nil {
+		klog.Errorf("Failed to copy template %s to version cache: %v", tempName, err)
+		_ = os.RemoveAll(tmpDir)
+		return err
+	}
+	if err := os.Rename(tmpDir, versionDir); err != nil {
+		klog.Errorf("Failed to move template %s to version cache: %v", tempName, err)
+		return err
+	}
+	return nil
+}
+
+// getTemplateVersionDir returns the cache directory of the template version,
+// the cache is namespaced by registry as the registry cache.
+func getTemplateVersionDir(registry, tempName, version, tempBasePath string) string {
+	return path.Join(TemplateVersionCachePath, registry, tempBasePath, tempName, version)
+}
+
+func getTemplateVersion(templateDir string) (string, error) {
+	d, err := os.ReadFile(path.Join(templateDir, TemplateFileName))
+	if err != nil {
+		return "", err
+	}
+	meta := &templateVersionMeta{}
+	if err := yaml.Unmarshal(d, meta); err != nil {
+		return "", err
+	}
+	if meta.Spec.Version == "" {
+		return "", nil
+	}
+	if _, err := utilversion.ParseSemantic(meta.Spec.Version); err != nil {
+		klog.Warningf("Invalid version of template %s: %v", templateDir, err)
+		return "", err
+	}
+	return meta.Spec.Version, nil
+}
+
+// ListTemplateVersions returns the cached versions of the template in the
+// registry, the newest comes first.
+func ListTemplateVersions(registry, tempName, tempBasePath string) []string {
+	dirs, err := os.ReadDir(path.Join(TemplateVersionCachePath, registry, tempBasePath, tempName))
+	if err != nil {
+		return nil
+	}
+	ret := []string{}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		if _, err := utilversion.ParseSemantic(dir.Name()); err != nil {
+			continue
+		}
+		ret = append(ret, dir.Name())
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return utilversion.MustParseSemantic(ret[j]).LessThan(utilversion.MustParseSemantic(ret[i]))
+	})
+	return ret
+}
+
+// FindTemplateDir returns the directory of the template in the given version,
+// the template checked out in the registry is used if the version is empty.
+// The registries are searched by priority if the registry is empty.
+func FindTemplateDir(registry, tempName, version, tempBasePath string) string {
+	registries := []string{registry}
+	if registry == "" {
+		registries = []string{}
+		for _, registryPath := range GetRegistryPaths() {
+			registries = append(registries, path.Base(registryPath))
+		}
+	}
+
+	for _, r := range registries {
+		if version != "" {
+			versionDir := getTemplateVersionDir(r, tempName, version, tempBasePath)
+			if _, err := os.Stat(versionDir); err == nil {
+				return versionDir
+			}
+		}
+		templateDir := path.Join(RegistryCachePath, r, tempBasePath, tempName)
+		if _, err := os.Stat(templateDir); err != nil {
+			continue
+		}
+		if version == "" {
+			return templateDir
+		}
+		// The version may be checked out but not cached yet
+		if v, _ := getTemplateVersion(templateDir); v == version {
+			return templateDir
+		}
+	}
+
+	// The versions cached before the cache was namespaced by registry
+	if registry == "" && version != "" {
+		versionDir := path.Join(TemplateVersionCachePath, tempBasePath, tempName, version)
+		if _, err := os.Stat(versionDir); err == nil {
+			return versionDir
+		}
+	}
+	return ""
+}
+
+// LoadTemplateInputSchema returns the input schema declared by the template in
+// the given version, it may differ from the schema of the latest version.
+func LoadTemplateInputSchema(registry, tempName, version, tempBasePath string) (*commonv1alpha1.InputSchema, error) {
+	templateDir := FindTemplateDir(registry, tempName, version, tempBasePath)
+	if templateDir == "" {
+		return nil, fmt.Errorf("template %s(%s) not found", tempName, version)
+	}
+	meta, err := readTemplateVersionMeta(templateDir)
+	if err != nil {
+		return nil, err
+	}
+	if meta.Spec.InputSchema == nil && meta.Spec.Type == appv1alpha1.AppTemplateCompose {
+		composeFile, err := LoadTemplateCompose(templateDir)
+		if err != nil {
+			return nil, err
+		}
+		return LoadComposeInputSchema(composeFile)
+	}
+	return meta.Spec.InputSchema, nil
+}
+
+// CompareTemplateVersion returns -1, 0 or 1 if version a is older than, equal
+// to or newer than version b.
+func CompareTemplateVersion(a, b string) (int, error) {
+	va, err := utilversion.ParseSemantic(a)
+	if err != nil {
+		return 0, fmt.Errorf("invalid version %q: %v", a, err)
+	}
+	return va.Compare(b)
+}
+
+// ResolveUpgradeVersion returns the version the instance will be upgraded to,
+// the latest version is used if the target is not specified.
+func ResolveUpgradeVersion(currentVersion, targetVersion, latestVersion string, versions []string) (string, error) {
+	if targetVersion == "" {
+		targetVersion = latestVersion
+	}
+	if targetVersion == "" {
+		return "", fmt.Errorf("template has no version to upgrade to")
+	}
+	found := targetVersion == latestVersion
+	for _, v := range versions {
+		if v == targetVersion {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return "", fmt.Errorf("version %s is not available", targetVersion)
+	}
+	if currentVersion == "" {
+		return targetVersion, nil
+	}
+	ret, err := CompareTemplateVersion(targetVersion, currentVersion)
+	if err != nil {
+		return "", err
+	}
+	if ret <= 0 {
+		return "", fmt.Errorf("version %s is not newer than %s", targetVersion, currentVersion)
+	}
+	return targetVersion, nil
+}
+
+// CopyDir copies the directories and regular files of src into dst.
+func CopyDir(src, dst string) error {
+	return filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFile(p, target, info.Mode())
+	})
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
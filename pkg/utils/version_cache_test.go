@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveUpgradeVersion(t *testing.T) {
+	versions := []string{"1.2.0", "1.1.0", "1.0.0"}
+
+	version, err := ResolveUpgradeVersion("1.0.0", "", "1.2.0", versions)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.2.0", version)
+
+	version, err = ResolveUpgradeVersion("1.0.0", "1.1.0", "1.2.0", versions)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.1.0", version)
+
+	_, err = ResolveUpgradeVersion("1.1.0", "1.0.0", "1.2.0", versions)
+	assert.Error(t, err)
+
+	_, err = ResolveUpgradeVersion("1.0.0", "2.0.0", "1.2.0", versions)
+	assert.Error(t, err)
+}
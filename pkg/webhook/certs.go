@@ -0,0 +1,235 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/klog"
+
+	"github.com/openapp-dev/openapp/pkg/utils"
+)
+
+const (
+	// The serving certificate is regenerated if it expires within this duration.
+	certRenewBefore = time.Hour * 24 * 30
+	// certCheckInterval is how often the certificate secret is checked, so the
+	// certificate is renewed before it expires and the renewal by another
+	// replica is picked up.
+	certCheckInterval = time.Hour
+)
+
+// servingCertificate is the serving certificate of the webhook, it's
+// self-signed and stored in a secret so that all the replicas share it. The CA
+// bundle of the webhook configurations is updated with the certificate.
+type servingCertificate struct {
+	k8sClient kubernetes.Interface
+
+	lock    sync.RWMutex
+	certPEM []byte
+	cert    *tls.Certificate
+}
+
+func newServingCertificate(k8sClient kubernetes.Interface) (*servingCertificate, error) {
+	sc := &servingCertificate{k8sClient: k8sClient}
+	if err := sc.sync(); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// run renews the certificate periodically until the stop channel is closed.
+func (sc *servingCertificate) run(stopCh <-chan struct{}) {
+	wait.Until(func() {
+		if err := sc.sync(); err != nil {
+			klog.Errorf("Failed to sync webhook serving certificate: %v", err)
+		}
+	}, certCheckInterval, stopCh)
+}
+
+// GetCertificate returns the current certificate, it's used by tls.Config so
+// the renewed certificate is served without restart.
+func (sc *servingCertificate) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	sc.lock.RLock()
+	defer sc.lock.RUnlock()
+	return sc.cert, nil
+}
+
+func (sc *servingCertificate) sync() error {
+	secret, err := getOrCreateCertSecret(sc.k8sClient)
+	if err != nil {
+		return err
+	}
+
+	certPEM := secret.Data[corev1.TLSCertKey]
+	keyPEM := secret.Data[corev1.TLSPrivateKeyKey]
+	// The bundle also trusts the previous certificate, which is still served
+	// by the replicas that haven't picked up the renewal
+	caBundle := secret.Data[corev1.ServiceAccountRootCAKey]
+	if len(caBundle) == 0 {
+		caBundle = certPEM
+	}
+	if err := injectCABundle(sc.k8sClient, caBundle); err != nil {
+		return err
+	}
+
+	sc.lock.RLock()
+	unchanged := bytes.Equal(sc.certPEM, certPEM)
+	sc.lock.RUnlock()
+	if unchanged {
+		return nil
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		klog.Errorf("Failed to load serving certificate: %v", err)
+		return err
+	}
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+	sc.certPEM, sc.cert = certPEM, &cert
+	return nil
+}
+
+func getOrCreateCertSecret(k8sClient kubernetes.Interface) (*corev1.Secret, error) {
+	secretClient := k8sClient.CoreV1().Secrets(utils.SystemNamespace)
+	secret, err := secretClient.Get(context.Background(), utils.WebhookCertSecretName, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.Errorf("Failed to get webhook certificate secret: %v", err)
+		return nil, err
+	}
+	secretExists := err == nil
+	if secretExists && !certNeedsRenewal(secret.Data[corev1.TLSCertKey]) {
+		return secret, nil
+	}
+
+	klog.Infof("Generating webhook serving certificate...")
+	serviceName := utils.WebhookServiceName + "." + utils.SystemNamespace + ".svc"
+	certPEM, keyPEM, err := certutil.GenerateSelfSignedCertKey(serviceName, nil, []string{
+		utils.WebhookServiceName,
+		utils.WebhookServiceName + "." + utils.SystemNamespace,
+		serviceName + ".cluster.local",
+	})
+	if err != nil {
+		klog.Errorf("Failed to generate serving certificate: %v", err)
+		return nil, err
+	}
+
+	if !secretExists {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      utils.WebhookCertSecretName,
+				Namespace: utils.SystemNamespace,
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:              certPEM,
+				corev1.TLSPrivateKeyKey:        keyPEM,
+				corev1.ServiceAccountRootCAKey: certPEM,
+			},
+		}
+		created, err := secretClient.Create(context.Background(), secret, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			// Another replica created it first, use the one it generated
+			return secretClient.Get(context.Background(), utils.WebhookCertSecretName, metav1.GetOptions{})
+		}
+		if err != nil {
+			klog.Errorf("Failed to create webhook certificate secret: %v", err)
+			return nil, err
+		}
+		return created, nil
+	}
+
+	caBundle := certPEM
+	if oldCertPEM := secret.Data[corev1.TLSCertKey]; !certExpired(oldCertPEM) {
+		caBundle = append(append([]byte{}, certPEM...), oldCertPEM...)
+	}
+	secret.Data = map[string][]byte{
+		corev1.TLSCertKey:              certPEM,
+		corev1.TLSPrivateKeyKey:        keyPEM,
+		corev1.ServiceAccountRootCAKey: caBundle,
+	}
+	updated, err := secretClient.Update(context.Background(), secret, metav1.UpdateOptions{})
+	if apierrors.IsConflict(err) {
+		// Another replica renewed it first, use the one it generated
+		return secretClient.Get(context.Background(), utils.WebhookCertSecretName, metav1.GetOptions{})
+	}
+	if err != nil {
+		klog.Errorf("Failed to update webhook certificate secret: %v", err)
+		return nil, err
+	}
+	return updated, nil
+}
+
+func certNeedsRenewal(certPEM []byte) bool {
+	return certExpiresWithin(certPEM, certRenewBefore)
+}
+
+func certExpired(certPEM []byte) bool {
+	return certExpiresWithin(certPEM, 0)
+}
+
+func certExpiresWithin(certPEM []byte, d time.Duration) bool {
+	certs, err := certutil.ParseCertsPEM(certPEM)
+	if err != nil || len(certs) == 0 {
+		return true
+	}
+	return time.Now().Add(d).After(certs[0].NotAfter)
+}
+
+// injectCABundle sets the CA bundle of all the webhooks, the self-signed
+// certificate bundle contains its CA.
+func injectCABundle(k8sClient kubernetes.Interface, caBundle []byte) error {
+	validatingClient := k8sClient.AdmissionregistrationV1().ValidatingWebhookConfigurations()
+	validating, err := validatingClient.Get(context.Background(), utils.ValidatingWebhookConfigurationName, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("Failed to get validating webhook configuration: %v", err)
+		return err
+	}
+	changed := false
+	for i := range validating.Webhooks {
+		if !bytes.Equal(validating.Webhooks[i].ClientConfig.CABundle, caBundle) {
+			validating.Webhooks[i].ClientConfig.CABundle = caBundle
+			changed = true
+		}
+	}
+	if changed {
+		if _, err := validatingClient.Update(context.Background(), validating, metav1.UpdateOptions{}); err != nil {
+			klog.Errorf("Failed to update validating webhook configuration: %v", err)
+			return err
+		}
+	}
+
+	mutatingClient := k8sClient.AdmissionregistrationV1().MutatingWebhookConfigurations()
+	mutating, err := mutatingClient.Get(context.Background(), utils.MutatingWebhookConfigurationName, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("Failed to get mutating webhook configuration: %v", err)
+		return err
+	}
+	changed = false
+	for i := range mutating.Webhooks {
+		if !bytes.Equal(mutating.Webhooks[i].ClientConfig.CABundle, caBundle) {
+			mutating.Webhooks[i].ClientConfig.CABundle = caBundle
+			changed = true
+		}
+	}
+	if changed {
+		if _, err := mutatingClient.Update(context.Background(), mutating, metav1.UpdateOptions{}); err != nil {
+			klog.Errorf("Failed to update mutating webhook configuration: %v", err)
+			return err
+		}
+	}
+
+	if len(validating.Webhooks) == 0 && len(mutating.Webhooks) == 0 {
+		return fmt.Errorf("no webhook is registered in the webhook configurations")
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package webhook
+
+import (
+	"encoding/json"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/klog"
+
+	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
+	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
+	servicev1alpha1 "github.com/openapp-dev/openapp/pkg/apis/service/v1alpha1"
+)
+
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+func defaultAppTemplate(appTemp *appv1alpha1.AppTemplate) []patchOperation {
+	// The app is exposed as a http service if the expose type is not set
+	if appTemp.Spec.ExposeType == "" {
+		return []patchOperation{{
+			Op:    "add",
+			Path:  "/spec/exposeType",
+			Value: commonv1alpha1.ExposeLayer7,
+		}}
+	}
+	return nil
+}
+
+func defaultPublicServiceTemplate(temp *servicev1alpha1.PublicServiceTemplate) []patchOperation {
+	// The publicservice exposes the http services if the expose types are not set
+	if len(temp.Spec.ExposeTypes) == 0 {
+		return []patchOperation{{
+			Op:    "add",
+			Path:  "/spec/exposeTypes",
+			Value: []commonv1alpha1.ExposeType{commonv1alpha1.ExposeLayer7},
+		}}
+	}
+	return nil
+}
+
+func (ws *WebhookServer) defaultAppInstance(appIns *appv1alpha1.AppInstance) []patchOperation {
+	if appIns.Spec.AppTemplate == "" {
+		return nil
+	}
+	appTemp, err := ws.getAppTemplate(appIns.Spec.AppTemplate)
+	if err != nil {
+		// The validating webhook will reject it if the template is not found
+		klog.Warningf("Failed to get app template(%s): %v", appIns.Spec.AppTemplate, err)
+		return nil
+	}
+	if appIns.Spec.TemplateVersion != "" && appIns.Spec.TemplateVersion != appTemp.Spec.Version {
+		return nil
+	}
+	patches := defaultInputs(appIns.Spec.Inputs, appTemp.Spec.InputSchema)
+	return append(patches, defaultTemplateVersion(appIns.Spec.TemplateVersion, appTemp.Spec.Version)...)
+}
+
+func (ws *WebhookServer) defaultPublicServiceInstance(ins *servicev1alpha1.PublicServiceInstance) []patchOperation {
+	if ins.Spec.PublicServiceTemplate == "" {
+		return nil
+	}
+	temp, err := ws.getPublicServiceTemplate(ins.Spec.PublicServiceTemplate)
+	if err != nil {
+		klog.Warningf("Failed to get publicservice template(%s): %v", ins.Spec.PublicServiceTemplate, err)
+		return nil
+	}
+	if ins.Spec.TemplateVersion != "" && ins.Spec.TemplateVersion != temp.Spec.Version {
+		return nil
+	}
+	patches := defaultInputs(ins.Spec.Inputs, temp.Spec.InputSchema)
+	return append(patches, defaultTemplateVersion(ins.Spec.TemplateVersion, temp.Spec.Version)...)
+}
+
+// defaultTemplateVersion pins the new instance to the latest template version.
+func defaultTemplateVersion(templateVersion, latestVersion string) []patchOperation {
+	if templateVersion != "" || latestVersion == "" {
+		return nil
+	}
+	return []patchOperation{{
+		Op:    "add",
+		Path:  "/spec/templateVersion",
+		Value: latestVersion,
+	}}
+}
+
+// defaultInputs writes the schema defaults of the unset inputs into the spec,
+// so the instance keeps its values even if the template defaults change.
+func defaultInputs(inputs string, schema *commonv1alpha1.InputSchema) []patchOperation {
+	if schema == nil {
+		return nil
+	}
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(inputs), &values); err != nil {
+		return nil
+	}
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+
+	changed := false
+	for name, property := range schema.Properties {
+		if _, ok := values[name]; ok || property.Default == nil || len(property.Default.Raw) == 0 {
+			continue
+		}
+		var defaultValue interface{}
+		if err := json.Unmarshal(property.Default.Raw, &defaultValue); err != nil {
+			continue
+		}
+		values[name] = defaultValue
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	defaulted, err := yaml.Marshal(values)
+	if err != nil {
+		klog.Errorf("Failed to marshal inputs: %v", err)
+		return nil
+	}
+	return []patchOperation{{
+		Op:    "add",
+		Path:  "/spec/inputs",
+		Value: string(defaulted),
+	}}
+}
@@ -0,0 +1,180 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/klog"
+
+	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
+	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
+	servicev1alpha1 "github.com/openapp-dev/openapp/pkg/apis/service/v1alpha1"
+	"github.com/openapp-dev/openapp/pkg/utils"
+)
+
+var supportedExposeTypes = []string{
+	string(commonv1alpha1.ExposeLayer4),
+	string(commonv1alpha1.ExposeLayer7),
+}
+
+func validateExposeType(exposeType commonv1alpha1.ExposeType, fldPath *field.Path) field.ErrorList {
+	for _, t := range supportedExposeTypes {
+		if string(exposeType) == t {
+			return nil
+		}
+	}
+	return field.ErrorList{field.NotSupported(fldPath, exposeType, supportedExposeTypes)}
+}
+
+func validateAppTemplate(appTemp *appv1alpha1.AppTemplate) field.ErrorList {
+	specPath := field.NewPath("spec")
+	errs := field.ErrorList{}
+	if appTemp.Spec.Title == "" {
+		errs = append(errs, field.Required(specPath.Child("title"), ""))
+	}
+	errs = append(errs, validateExposeType(appTemp.Spec.ExposeType, specPath.Child("exposeType"))...)
+	errs = append(errs, validateVersion(appTemp.Spec.Version, specPath.Child("version"))...)
+	errs = append(errs, utils.ValidateInputSchema(appTemp.Spec.InputSchema, specPath.Child("inputSchema"))...)
+	return errs
+}
+
+func validatePublicServiceTemplate(temp *servicev1alpha1.PublicServiceTemplate) field.ErrorList {
+	specPath := field.NewPath("spec")
+	errs := field.ErrorList{}
+	if temp.Spec.Title == "" {
+		errs = append(errs, field.Required(specPath.Child("title"), ""))
+	}
+	if len(temp.Spec.ExposeTypes) == 0 {
+		errs = append(errs, field.Required(specPath.Child("exposeTypes"), ""))
+	}
+	for i, exposeType := range temp.Spec.ExposeTypes {
+		errs = append(errs, validateExposeType(exposeType, specPath.Child("exposeTypes").Index(i))...)
+	}
+	errs = append(errs, validateVersion(temp.Spec.Version, specPath.Child("version"))...)
+	errs = append(errs, utils.ValidateInputSchema(temp.Spec.InputSchema, specPath.Child("inputSchema"))...)
+	return errs
+}
+
+func validateVersion(version string, fldPath *field.Path) field.ErrorList {
+	if version == "" {
+		return nil
+	}
+	if _, err := utilversion.ParseSemantic(version); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, version, err.Error())}
+	}
+	return nil
+}
+
+// validateTemplateVersion checks the pinned version is kept in the template
+// cache, it returns whether the version is the latest one.
+func validateTemplateVersion(templateVersion, latestVersion string,
+	versions []string,
+	fldPath *field.Path) (bool, field.ErrorList) {
+	if templateVersion == "" || templateVersion == latestVersion {
+		return true, nil
+	}
+	for _, v := range versions {
+		if v == templateVersion {
+			return false, nil
+		}
+	}
+	return false, field.ErrorList{field.NotSupported(fldPath, templateVersion, versions)}
+}
+
+func (ws *WebhookServer) validateAppInstance(appIns *appv1alpha1.AppInstance) field.ErrorList {
+	specPath := field.NewPath("spec")
+	if appIns.Spec.AppTemplate == "" {
+		return field.ErrorList{field.Required(specPath.Child("appTemplate"), "")}
+	}
+	appTemp, err := ws.getAppTemplate(appIns.Spec.AppTemplate)
+	if err != nil {
+		return field.ErrorList{templateLookupError(specPath.Child("appTemplate"), appIns.Spec.AppTemplate, err)}
+	}
+
+	latest, errs := validateTemplateVersion(appIns.Spec.TemplateVersion, appTemp.Spec.Version,
+		appTemp.Spec.Versions, specPath.Child("templateVersion"))
+	// Only the schema of the latest version is known here, the controller will
+	// validate the inputs of the older versions.
+	if latest {
+		_, inputErrs := utils.ResolveInputs(appIns.Spec.Inputs, appTemp.Spec.InputSchema)
+		errs = append(errs, inputErrs...)
+	}
+	errs = append(errs, utils.ValidateInstancePatches(appIns.Spec.Patches, specPath.Child("patches"))...)
+	if appIns.Spec.PublicServiceClass != "" {
+		errs = append(errs, ws.validatePublicServiceClass(appIns.Spec.PublicServiceClass,
+			appTemp.Spec.ExposeType, specPath.Child("publicServiceClass"))...)
+	}
+	return errs
+}
+
+// validatePublicServiceClass checks the publicservice instance exists and its
+// template supports the expose type of the app.
+func (ws *WebhookServer) validatePublicServiceClass(publicServiceClass string,
+	exposeType commonv1alpha1.ExposeType,
+	fldPath *field.Path) field.ErrorList {
+	publicServiceIns, err := ws.openappClient.ServiceV1alpha1().PublicServiceInstances(utils.InstanceNamespace).
+		Get(context.Background(), publicServiceClass, metav1.GetOptions{})
+	if err != nil {
+		return field.ErrorList{templateLookupError(fldPath, publicServiceClass, err)}
+	}
+	publicServiceTemp, err := ws.getPublicServiceTemplate(publicServiceIns.Spec.PublicServiceTemplate)
+	if err != nil {
+		return field.ErrorList{templateLookupError(fldPath, publicServiceClass, err)}
+	}
+
+	if exposeType == "" {
+		exposeType = commonv1alpha1.ExposeLayer7
+	}
+	for _, t := range publicServiceTemp.Spec.ExposeTypes {
+		if t == exposeType {
+			return nil
+		}
+	}
+	return field.ErrorList{field.Invalid(fldPath, publicServiceClass,
+		fmt.Sprintf("publicservice template(%s) doesn't support expose type %s",
+			publicServiceTemp.Name, exposeType))}
+}
+
+func (ws *WebhookServer) validatePublicServiceInstance(ins *servicev1alpha1.PublicServiceInstance) field.ErrorList {
+	specPath := field.NewPath("spec")
+	if ins.Spec.PublicServiceTemplate == "" {
+		return field.ErrorList{field.Required(specPath.Child("publicServiceTemplate"), "")}
+	}
+	temp, err := ws.getPublicServiceTemplate(ins.Spec.PublicServiceTemplate)
+	if err != nil {
+		return field.ErrorList{templateLookupError(specPath.Child("publicServiceTemplate"),
+			ins.Spec.PublicServiceTemplate, err)}
+	}
+
+	latest, errs := validateTemplateVersion(ins.Spec.TemplateVersion, temp.Spec.Version,
+		temp.Spec.Versions, specPath.Child("templateVersion"))
+	if latest {
+		_, inputErrs := utils.ResolveInputs(ins.Spec.Inputs, temp.Spec.InputSchema)
+		errs = append(errs, inputErrs...)
+	}
+	return errs
+}
+
+func templateLookupError(fldPath *field.Path, name string, err error) *field.Error {
+	if apierrors.IsNotFound(err) {
+		return field.NotFound(fldPath, name)
+	}
+	klog.Errorf("Failed to get %s: %v", name, err)
+	return field.InternalError(fldPath, err)
+}
+
+func (ws *WebhookServer) getAppTemplate(ref string) (*appv1alpha1.AppTemplate, error) {
+	return utils.GetAppTemplate(func(name string) (*appv1alpha1.AppTemplate, error) {
+		return ws.openappClient.AppV1alpha1().AppTemplates().Get(context.Background(), name, metav1.GetOptions{})
+	}, ref)
+}
+
+func (ws *WebhookServer) getPublicServiceTemplate(ref string) (*servicev1alpha1.PublicServiceTemplate, error) {
+	return utils.GetPublicServiceTemplate(func(name string) (*servicev1alpha1.PublicServiceTemplate, error) {
+		return ws.openappClient.ServiceV1alpha1().PublicServiceTemplates().Get(context.Background(), name, metav1.GetOptions{})
+	}, ref)
+}
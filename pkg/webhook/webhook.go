@@ -0,0 +1,230 @@
+package webhook
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+
+	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
+	servicev1alpha1 "github.com/openapp-dev/openapp/pkg/apis/service/v1alpha1"
+	"github.com/openapp-dev/openapp/pkg/generated/clientset/versioned"
+)
+
+const (
+	ValidatePath = "/validate"
+	MutatePath   = "/mutate"
+)
+
+// WebhookServer validates and defaults the OpenAPP resources before they are
+// persisted, so the invalid objects are rejected at apply time.
+type WebhookServer struct {
+	k8sClient     kubernetes.Interface
+	openappClient versioned.Interface
+}
+
+func NewWebhookServer(k8sClient kubernetes.Interface, openappClient versioned.Interface) *WebhookServer {
+	return &WebhookServer{
+		k8sClient:     k8sClient,
+		openappClient: openappClient,
+	}
+}
+
+func (ws *WebhookServer) Run(ctx context.Context, addr string) error {
+	cert, err := newServingCertificate(ws.k8sClient)
+	if err != nil {
+		return err
+	}
+	go cert.run(ctx.Done())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(ValidatePath, func(w http.ResponseWriter, r *http.Request) {
+		serveAdmission(w, r, ws.validate)
+	})
+	mux.HandleFunc(MutatePath, func(w http.ResponseWriter, r *http.Request) {
+		serveAdmission(w, r, ws.mutate)
+	})
+	server := &http.Server{
+		Addr:    addr,
+		Handler: mux,
+		TLSConfig: &tls.Config{
+			GetCertificate: cert.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+		},
+	}
+	klog.Infof("Webhook server is listening on %s", addr)
+	return server.ListenAndServeTLS("", "")
+}
+
+func serveAdmission(w http.ResponseWriter,
+	r *http.Request,
+	admit func(*admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		klog.Errorf("Failed to read request body: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	review := &admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(body, review); err != nil || review.Request == nil {
+		klog.Errorf("Failed to unmarshal admission review: %v", err)
+		http.Error(w, "invalid admission review", http.StatusBadRequest)
+		return
+	}
+
+	response := admit(review.Request)
+	response.UID = review.Request.UID
+	review.Response = response
+	review.Request = nil
+	ret, err := json.Marshal(review)
+	if err != nil {
+		klog.Errorf("Failed to marshal admission review: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(ret); err != nil {
+		klog.Errorf("Failed to write admission response: %v", err)
+	}
+}
+
+func (ws *WebhookServer) validate(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	klog.V(4).Infof("Validating %s(%s)...", req.Kind.Kind, req.Name)
+	var errs field.ErrorList
+	switch req.Kind.Kind {
+	case "AppTemplate":
+		obj := &appv1alpha1.AppTemplate{}
+		if err := json.Unmarshal(req.Object.Raw, obj); err != nil {
+			return errorResponse(err)
+		}
+		errs = validateAppTemplate(obj)
+	case "PublicServiceTemplate":
+		obj := &servicev1alpha1.PublicServiceTemplate{}
+		if err := json.Unmarshal(req.Object.Raw, obj); err != nil {
+			return errorResponse(err)
+		}
+		errs = validatePublicServiceTemplate(obj)
+	case "AppInstance":
+		obj, oldObj := &appv1alpha1.AppInstance{}, &appv1alpha1.AppInstance{}
+		if err := unmarshalObjects(req, obj, oldObj); err != nil {
+			return errorResponse(err)
+		}
+		// Finalizers must be removable even if the template is gone
+		if !obj.DeletionTimestamp.IsZero() ||
+			(req.Operation == admissionv1.Update && reflect.DeepEqual(obj.Spec, oldObj.Spec)) {
+			return allowedResponse()
+		}
+		errs = ws.validateAppInstance(obj)
+	case "PublicServiceInstance":
+		obj, oldObj := &servicev1alpha1.PublicServiceInstance{}, &servicev1alpha1.PublicServiceInstance{}
+		if err := unmarshalObjects(req, obj, oldObj); err != nil {
+			return errorResponse(err)
+		}
+		if !obj.DeletionTimestamp.IsZero() ||
+			(req.Operation == admissionv1.Update && reflect.DeepEqual(obj.Spec, oldObj.Spec)) {
+			return allowedResponse()
+		}
+		errs = ws.validatePublicServiceInstance(obj)
+	default:
+		return allowedResponse()
+	}
+
+	if len(errs) != 0 {
+		status := apierrors.NewInvalid(schema.GroupKind{Group: req.Kind.Group, Kind: req.Kind.Kind}, req.Name, errs).ErrStatus
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result:  &status,
+		}
+	}
+	return allowedResponse()
+}
+
+func (ws *WebhookServer) mutate(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	klog.V(4).Infof("Defaulting %s(%s)...", req.Kind.Kind, req.Name)
+	var patches []patchOperation
+	switch req.Kind.Kind {
+	case "AppTemplate":
+		obj := &appv1alpha1.AppTemplate{}
+		if err := json.Unmarshal(req.Object.Raw, obj); err != nil {
+			return errorResponse(err)
+		}
+		patches = defaultAppTemplate(obj)
+	case "AppInstance":
+		obj := &appv1alpha1.AppInstance{}
+		if err := json.Unmarshal(req.Object.Raw, obj); err != nil {
+			return errorResponse(err)
+		}
+		if req.Operation == admissionv1.Create {
+			patches = ws.defaultAppInstance(obj)
+		}
+	case "PublicServiceTemplate":
+		obj := &servicev1alpha1.PublicServiceTemplate{}
+		if err := json.Unmarshal(req.Object.Raw, obj); err != nil {
+			return errorResponse(err)
+		}
+		patches = defaultPublicServiceTemplate(obj)
+	case "PublicServiceInstance":
+		obj := &servicev1alpha1.PublicServiceInstance{}
+		if err := json.Unmarshal(req.Object.Raw, obj); err != nil {
+			return errorResponse(err)
+		}
+		if req.Operation == admissionv1.Create {
+			patches = ws.defaultPublicServiceInstance(obj)
+		}
+	}
+	if len(patches) == 0 {
+		return allowedResponse()
+	}
+
+	patch, err := json.Marshal(patches)
+	if err != nil {
+		return errorResponse(err)
+	}
+	patchType := admissionv1.PatchTypeJSONPatch
+	return &admissionv1.AdmissionResponse{
+		Allowed:   true,
+		Patch:     patch,
+		PatchType: &patchType,
+	}
+}
+
+func unmarshalObjects(req *admissionv1.AdmissionRequest, obj, oldObj interface{}) error {
+	if err := json.Unmarshal(req.Object.Raw, obj); err != nil {
+		return err
+	}
+	if req.Operation != admissionv1.Update {
+		return nil
+	}
+	if err := json.Unmarshal(req.OldObject.Raw, oldObj); err != nil {
+		return fmt.Errorf("failed to unmarshal old object: %v", err)
+	}
+	return nil
+}
+
+func allowedResponse() *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{Allowed: true}
+}
+
+func errorResponse(err error) *admissionv1.AdmissionResponse {
+	klog.Errorf("Failed to handle admission request: %v", err)
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusBadRequest,
+			Reason:  metav1.StatusReasonBadRequest,
+			Message: err.Error(),
+		},
+	}
+}
@@ -0,0 +1,153 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	certutil "k8s.io/client-go/util/cert"
+
+	appv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/app/v1alpha1"
+	commonv1alpha1 "github.com/openapp-dev/openapp/pkg/apis/common/v1alpha1"
+	servicev1alpha1 "github.com/openapp-dev/openapp/pkg/apis/service/v1alpha1"
+	"github.com/openapp-dev/openapp/pkg/generated/clientset/versioned/fake"
+	"github.com/openapp-dev/openapp/pkg/utils"
+)
+
+func newAppInstanceRequest(t *testing.T, appIns *appv1alpha1.AppInstance) *admissionv1.AdmissionRequest {
+	raw, err := json.Marshal(appIns)
+	assert.NoError(t, err)
+	return &admissionv1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Group: "app.openapp.dev", Version: "v1alpha1", Kind: "AppInstance"},
+		Name:      appIns.Name,
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: raw},
+	}
+}
+
+func TestValidateAppInstance(t *testing.T) {
+	ws := NewWebhookServer(nil, fake.NewSimpleClientset(
+		&appv1alpha1.AppTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "ssh"},
+			Spec: appv1alpha1.AppTemplateSpec{
+				Title:      "ssh",
+				Registry:   "official",
+				ExposeType: commonv1alpha1.ExposeLayer4,
+				InputSchema: &commonv1alpha1.InputSchema{
+					Properties: map[string]commonv1alpha1.InputProperty{
+						"port": {
+							Type:    commonv1alpha1.InputTypeInteger,
+							Default: &runtime.RawExtension{Raw: []byte("22")},
+						},
+					},
+				},
+			},
+		},
+		&servicev1alpha1.PublicServiceTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "cloudflare"},
+			Spec: servicev1alpha1.PublicServiceTemplateSpec{
+				ExposeTypes: []commonv1alpha1.ExposeType{commonv1alpha1.ExposeLayer7},
+			},
+		},
+		&servicev1alpha1.PublicServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "cloudflare", Namespace: utils.InstanceNamespace},
+			Spec:       servicev1alpha1.PublicServiceInstanceSpec{PublicServiceTemplate: "cloudflare"},
+		},
+	))
+
+	appIns := &appv1alpha1.AppInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: "ssh", Namespace: utils.InstanceNamespace},
+		Spec:       appv1alpha1.AppInstanceSpec{AppTemplate: "ssh"},
+	}
+	assert.True(t, ws.validate(newAppInstanceRequest(t, appIns)).Allowed)
+
+	resp := ws.mutate(newAppInstanceRequest(t, appIns))
+	assert.True(t, resp.Allowed)
+	assert.JSONEq(t, `[{"op":"add","path":"/spec/inputs","value":"port: 22\n"}]`, string(resp.Patch))
+
+	appIns.Spec.Inputs = "port: ssh"
+	assert.False(t, ws.validate(newAppInstanceRequest(t, appIns)).Allowed)
+
+	appIns.Spec.Inputs = ""
+	appIns.Spec.PublicServiceClass = "cloudflare"
+	resp = ws.validate(newAppInstanceRequest(t, appIns))
+	assert.False(t, resp.Allowed)
+	assert.Contains(t, resp.Result.Message, "doesn't support expose type Layer4")
+
+	appIns.Spec.PublicServiceClass = ""
+	appIns.Spec.AppTemplate = "official/ssh"
+	assert.True(t, ws.validate(newAppInstanceRequest(t, appIns)).Allowed)
+
+	appIns.Spec.AppTemplate = "community/ssh"
+	assert.False(t, ws.validate(newAppInstanceRequest(t, appIns)).Allowed)
+
+	appIns.Spec.AppTemplate = "not-exist"
+	assert.False(t, ws.validate(newAppInstanceRequest(t, appIns)).Allowed)
+}
+
+func TestDefaultPublicServiceTemplate(t *testing.T) {
+	ws := NewWebhookServer(nil, fake.NewSimpleClientset())
+	raw, err := json.Marshal(&servicev1alpha1.PublicServiceTemplate{ObjectMeta: metav1.ObjectMeta{Name: "frp"}})
+	assert.NoError(t, err)
+	resp := ws.mutate(&admissionv1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Group: "service.openapp.dev", Version: "v1alpha1", Kind: "PublicServiceTemplate"},
+		Name:      "frp",
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: raw},
+	})
+	assert.True(t, resp.Allowed)
+	assert.JSONEq(t, `[{"op":"add","path":"/spec/exposeTypes","value":["Layer7"]}]`, string(resp.Patch))
+}
+
+func TestServingCertificate(t *testing.T) {
+	k8sClient := k8sfake.NewSimpleClientset(
+		&admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: utils.ValidatingWebhookConfigurationName},
+			Webhooks:   []admissionregistrationv1.ValidatingWebhook{{Name: "validate.app.openapp.dev"}},
+		},
+		&admissionregistrationv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: utils.MutatingWebhookConfigurationName},
+			Webhooks:   []admissionregistrationv1.MutatingWebhook{{Name: "default.app.openapp.dev"}},
+		},
+	)
+	sc, err := newServingCertificate(k8sClient)
+	assert.NoError(t, err)
+	cert, err := sc.GetCertificate(nil)
+	assert.NoError(t, err)
+
+	secretClient := k8sClient.CoreV1().Secrets(utils.SystemNamespace)
+	secret, err := secretClient.Get(context.Background(), utils.WebhookCertSecretName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	validating, err := k8sClient.AdmissionregistrationV1().ValidatingWebhookConfigurations().
+		Get(context.Background(), utils.ValidatingWebhookConfigurationName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, secret.Data[corev1.TLSCertKey], validating.Webhooks[0].ClientConfig.CABundle)
+
+	// The certificate renewed by another replica is served after the next sync
+	certPEM, keyPEM, err := certutil.GenerateSelfSignedCertKey("openapp-webhook", nil, nil)
+	assert.NoError(t, err)
+	caBundle := append(append([]byte{}, certPEM...), secret.Data[corev1.TLSCertKey]...)
+	secret.Data = map[string][]byte{
+		corev1.TLSCertKey:              certPEM,
+		corev1.TLSPrivateKeyKey:        keyPEM,
+		corev1.ServiceAccountRootCAKey: caBundle,
+	}
+	_, err = secretClient.Update(context.Background(), secret, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, sc.sync())
+
+	renewed, err := sc.GetCertificate(nil)
+	assert.NoError(t, err)
+	assert.NotEqual(t, cert.Certificate[0], renewed.Certificate[0])
+	mutating, err := k8sClient.AdmissionregistrationV1().MutatingWebhookConfigurations().
+		Get(context.Background(), utils.MutatingWebhookConfigurationName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, caBundle, mutating.Webhooks[0].ClientConfig.CABundle)
+}